@@ -0,0 +1,97 @@
+// LRU 与 LFU 的命中率、单次操作耗时，以及分片对并发访问的影响
+
+package boundedcache
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+shell:
+	go test -bench=. -run=^$ -benchmem -cpu=1,4
+*/
+
+/*
+10 万个键、容量 1 万，键服从 s=1.1 的 Zipf 分布（单核机器上运行，-4 只是 4 个 P 轮流执行）
+
+BenchmarkGetSet/LRU                     11493428                90.93 ns/op             84.66 hit%             7 B/op          0 allocs/op
+BenchmarkGetSet/LRU-4                   14121147               103.4 ns/op              84.66 hit%             7 B/op          0 allocs/op
+BenchmarkGetSet/LFU                      5766625               257.4 ns/op              86.23 hit%            56 B/op          0 allocs/op
+BenchmarkGetSet/LFU-4                    3168615               318.3 ns/op              86.18 hit%            56 B/op          0 allocs/op
+BenchmarkParallel/Mutex-LRU              8241790               136.9 ns/op              84.65 hit%             7 B/op          0 allocs/op
+BenchmarkParallel/Mutex-LRU-4            9731851               139.1 ns/op              90.49 hit%             4 B/op          0 allocs/op
+BenchmarkParallel/Sharded-LRU            8926764               140.3 ns/op              84.60 hit%             7 B/op          0 allocs/op
+BenchmarkParallel/Sharded-LRU-4         11023315               127.5 ns/op              90.77 hit%             4 B/op          0 allocs/op
+BenchmarkParallel/Mutex-LFU              3926571               330.7 ns/op              86.20 hit%            56 B/op          0 allocs/op
+BenchmarkParallel/Mutex-LFU-4            3330583               389.1 ns/op              95.10 hit%            53 B/op          0 allocs/op
+BenchmarkParallel/Sharded-LFU            2889338               408.6 ns/op              86.16 hit%            62 B/op          0 allocs/op
+BenchmarkParallel/Sharded-LFU-4          2485047               433.0 ns/op              95.27 hit%            59 B/op          0 allocs/op
+
+结论：
+	热点集中时 LFU 的命中率比 LRU 高 1~2 个百分点，代价是每次访问都可能新建或删除 bucket，耗时约为 LRU 的 2.5 倍。
+	单核上分片没有并行收益，只多了一次哈希；多核时分片才能让不同分片的访问互不阻塞。
+	-4 的命中率更高是因为 4 个 goroutine 从不同位置读同一段键序列，热点键被重复访问得更多，与实现无关。
+	B/op 不为 0 是未命中时 Set 新建条目的摊销开销，不到一次分配/op，所以 allocs/op 显示为 0。
+*/
+
+const (
+	benchKeys     = 100000
+	benchCapacity = 10000 // 键空间的 10%
+)
+
+// 读穿透：Get 未命中时 Set，键服从 Zipf 分布（少数热点键占大部分访问）
+func benchmarkGetSet(b *testing.B, c Cache[int, int]) {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.1, 1, benchKeys-1)
+	keys := make([]int, 1<<16)
+	for i := range keys {
+		keys[i] = int(z.Uint64())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i&(len(keys)-1)]
+		if _, ok := c.Get(k); !ok {
+			c.Set(k, k)
+		}
+	}
+	b.ReportMetric(c.Stats().HitRatio()*100, "hit%")
+}
+
+func BenchmarkGetSet(b *testing.B) {
+	opts := Options[int, int]{Capacity: benchCapacity}
+	b.Run("LRU", func(b *testing.B) { benchmarkGetSet(b, NewLRU(opts)) })
+	b.Run("LFU", func(b *testing.B) { benchmarkGetSet(b, NewLFU(opts)) })
+}
+
+func benchmarkParallel(b *testing.B, c Cache[int, int]) {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.1, 1, benchKeys-1)
+	keys := make([]int, 1<<16)
+	for i := range keys {
+		keys[i] = int(z.Uint64())
+	}
+	var offset atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(offset.Add(7919)) // 各 goroutine 从不同位置开始读键序列
+		for pb.Next() {
+			k := keys[i&(len(keys)-1)]
+			i++
+			if _, ok := c.Get(k); !ok {
+				c.Set(k, k)
+			}
+		}
+	})
+	b.ReportMetric(c.Stats().HitRatio()*100, "hit%")
+}
+
+// 1 个分片相当于一把 Mutex 保护整个 LRU
+func BenchmarkParallel(b *testing.B) {
+	opts := Options[int, int]{Capacity: benchCapacity}
+	b.Run("Mutex-LRU", func(b *testing.B) { benchmarkParallel(b, NewSharded(1, PolicyLRU, opts)) })
+	b.Run("Sharded-LRU", func(b *testing.B) { benchmarkParallel(b, NewSharded(16, PolicyLRU, opts)) })
+	b.Run("Mutex-LFU", func(b *testing.B) { benchmarkParallel(b, NewSharded(1, PolicyLFU, opts)) })
+	b.Run("Sharded-LFU", func(b *testing.B) { benchmarkParallel(b, NewSharded(16, PolicyLFU, opts)) })
+}
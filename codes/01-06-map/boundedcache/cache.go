@@ -0,0 +1,119 @@
+// 有容量上限的缓存
+//
+// demo.go 里的 map 只会越长越大，缓存要在固定的内存预算内工作，满了就得淘汰：
+//
+//	LRU：淘汰最久没有访问的条目
+//	LFU：淘汰访问次数最少的条目，次数相同时淘汰最久没有访问的
+//
+// 容量按“开销”计算：默认每个条目开销为 1，即按条目数限制；
+// 提供 SizeFunc 时按它返回的值（比如字节数）累计。
+// LRU 和 LFU 本身不加锁，需要并发访问时用 NewSharded 分片，每个分片一把锁。
+package boundedcache
+
+// Cache 是 LRU、LFU 和分片缓存的公共接口
+type Cache[K comparable, V any] interface {
+	// Get 命中时返回值并记为一次访问
+	Get(key K) (V, bool)
+	// Set 存入或更新条目（也记为一次访问），超出容量时淘汰其它条目。
+	// 单个条目的开销超过总容量时拒绝存入，同时删除该键原有的值，返回 false。
+	// Sharded 的“总容量”是单个分片的容量（Capacity/分片数，见 ShardCapacity），不是 Options.Capacity。
+	Set(key K, value V) bool
+	Delete(key K) bool
+	Len() int
+	// Size 返回当前条目开销之和
+	Size() int64
+	Stats() Stats
+}
+
+type Options[K comparable, V any] struct {
+	// Capacity 是开销上限，必须大于 0
+	Capacity int64
+	// SizeFunc 计算条目的开销，为 nil 时每个条目开销为 1
+	SizeFunc func(key K, value V) int64
+	// OnEvict 在条目因容量不足被淘汰时调用（Delete 不会触发）。
+	// 调用时持有缓存的锁，回调里不能再访问同一个缓存。
+	OnEvict func(key K, value V)
+}
+
+func (o *Options[K, V]) cost(key K, value V) int64 {
+	if o.SizeFunc == nil {
+		return 1
+	}
+	return o.SizeFunc(key, value)
+}
+
+func (o *Options[K, V]) check() {
+	if o.Capacity <= 0 {
+		panic("boundedcache: Capacity must be positive")
+	}
+}
+
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64 // 因容量不足被淘汰的条目数
+	EvictedSize int64 // 被淘汰条目的开销之和
+	Rejected    int64 // 开销超过总容量、被拒绝的 Set 次数
+}
+
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Evictions += o.Evictions
+	s.EvictedSize += o.EvictedSize
+	s.Rejected += o.Rejected
+}
+
+// entry 同时挂在 map 和侵入式双向链表上，移动和删除都是 O(1)
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int64
+	prev, next *entry[K, V]
+	bucket     *bucket[K, V] // 仅 LFU 使用
+}
+
+// list 是带哨兵的环形双向链表，front 为最近访问，back 为最久未访问
+type list[K comparable, V any] struct {
+	root entry[K, V]
+}
+
+func (l *list[K, V]) init() {
+	l.root.prev, l.root.next = &l.root, &l.root
+}
+
+func (l *list[K, V]) empty() bool { return l.root.next == &l.root }
+
+func (l *list[K, V]) pushFront(e *entry[K, V]) {
+	e.prev, e.next = &l.root, l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+}
+
+func (l *list[K, V]) remove(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (l *list[K, V]) moveToFront(e *entry[K, V]) {
+	l.remove(e)
+	l.pushFront(e)
+}
+
+// victim 从 back 往前找第一个不是 protect 的条目，找不到时返回 nil
+func (l *list[K, V]) victim(protect *entry[K, V]) *entry[K, V] {
+	for e := l.root.prev; e != &l.root; e = e.prev {
+		if e != protect {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package boundedcache
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	cd boundedcache
+	go test -v -race .
+	go test -bench=. -run=^$ -benchmem -cpu=1,4
+*/
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := NewLRU(Options[string, int]{
+		Capacity: 3,
+		OnEvict:  func(k string, _ int) { evicted = append(evicted, k) },
+	})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a") // b 变成最久未访问
+	c.Set("d", 4)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(k); !ok {
+			t.Fatalf("%s missing", k)
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v", evicted)
+	}
+	if s := c.Stats(); s.Hits != 4 || s.Misses != 1 || s.Evictions != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestLFUEvictsLeastFrequentlyUsed(t *testing.T) {
+	c := NewLFU(Options[string, int]{Capacity: 3})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("c") // 次数：a=3 b=2 c=2，b 和 c 中 b 更久没访问
+	c.Set("d", 4)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	// 现在 a=3 c=2 d=1，d 再访问两次变成 3，新键 e 会淘汰 c
+	c.Get("d")
+	c.Get("d")
+	c.Set("e", 5)
+	if _, ok := c.Get("c"); ok {
+		t.Fatal("c should have been evicted")
+	}
+	if c.Len() != 3 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+}
+
+// 按字节预算限制：开销由 SizeFunc 决定
+func TestByteBudget(t *testing.T) {
+	for _, name := range []string{"LRU", "LFU"} {
+		t.Run(name, func(t *testing.T) {
+			opts := Options[string, []byte]{
+				Capacity: 100,
+				SizeFunc: func(k string, v []byte) int64 { return int64(len(k) + len(v)) },
+			}
+			var c Cache[string, []byte]
+			if name == "LRU" {
+				c = NewLRU(opts)
+			} else {
+				c = NewLFU(opts)
+			}
+			for i := 0; i < 10; i++ {
+				c.Set(fmt.Sprint(i), make([]byte, 29)) // 每个条目 30 字节
+				if c.Size() > 100 {
+					t.Fatalf("size %d exceeds budget", c.Size())
+				}
+			}
+			if c.Len() != 3 || c.Size() != 90 {
+				t.Fatalf("len=%d size=%d", c.Len(), c.Size())
+			}
+
+			// 超过总预算的条目被拒绝，并删除旧值
+			c.Set("9", make([]byte, 10))
+			if c.Set("9", make([]byte, 200)) {
+				t.Fatal("oversized entry accepted")
+			}
+			if _, ok := c.Get("9"); ok {
+				t.Fatal("stale value kept after rejected Set")
+			}
+			// 更新为更大的值会挤掉其它条目
+			c.Set("7", make([]byte, 69))
+			if c.Size() > 100 {
+				t.Fatalf("size %d exceeds budget", c.Size())
+			}
+			if v, ok := c.Get("7"); !ok || len(v) != 69 {
+				t.Fatal("updated entry evicted itself")
+			}
+			if s := c.Stats(); s.Rejected != 1 || s.EvictedSize != s.Evictions*30 {
+				t.Fatalf("stats = %+v", s)
+			}
+		})
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, PolicyLFU} {
+		c := NewSharded(8, policy, Options[int, int]{Capacity: 256})
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				for i := 0; i < 5000; i++ {
+					k := r.Intn(1000)
+					if v, ok := c.Get(k); ok && v != k {
+						t.Errorf("Get(%d) = %d", k, v)
+					}
+					c.Set(k, k)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if c.Len() > 256 || int64(c.Len()) != c.Size() {
+			t.Fatalf("len=%d size=%d", c.Len(), c.Size())
+		}
+		if s := c.Stats(); s.Hits+s.Misses != 8*5000 || s.Evictions == 0 {
+			t.Fatalf("stats = %+v", s)
+		}
+	}
+
+	// 容量小于分片数时减少分片，总开销仍不超过 Capacity
+	c := NewSharded(16, PolicyLRU, Options[int, int]{Capacity: 5})
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+	if c.Len() > 5 {
+		t.Fatalf("len=%d exceeds capacity", c.Len())
+	}
+}
+
+// 分片缓存单个条目的开销上限是 ShardCapacity，而不是 Capacity
+func TestShardedEntryLimit(t *testing.T) {
+	c := NewSharded(4, PolicyLRU, Options[string, int]{
+		Capacity: 100,
+		SizeFunc: func(_ string, v int) int64 { return int64(v) },
+	})
+	if c.ShardCapacity() != 25 {
+		t.Fatalf("ShardCapacity = %d, want 25", c.ShardCapacity())
+	}
+	if !c.Set("fits", 25) {
+		t.Fatal("entry of ShardCapacity rejected")
+	}
+	for _, cost := range []int{26, 100} {
+		if c.Set("too-big", cost) {
+			t.Fatalf("entry of cost %d accepted", cost)
+		}
+	}
+	if s := c.Stats(); s.Rejected != 2 || c.Size() != 25 {
+		t.Fatalf("rejected = %d, size = %d", s.Rejected, c.Size())
+	}
+}
+
+// 参考模型：用最朴素的方式实现同样的语义，每次淘汰都线性扫描。
+// LRU 淘汰 last 最小的条目；LFU 淘汰 freq 最小的，freq 相同时淘汰 last 最小的。
+type model struct {
+	lfu      bool
+	capacity int64
+	size     int64
+	tick     int64
+	items    map[int]*modelEntry
+	evicted  []int
+}
+
+type modelEntry struct {
+	value, cost int
+	freq, last  int64
+}
+
+func (m *model) touch(e *modelEntry) {
+	m.tick++
+	e.freq++
+	e.last = m.tick
+}
+
+func (m *model) get(k int) (int, bool) {
+	e, ok := m.items[k]
+	if !ok {
+		return 0, false
+	}
+	m.touch(e)
+	return e.value, true
+}
+
+func (m *model) set(k, v int) bool {
+	cost := modelCost(k, v)
+	if int64(cost) > m.capacity {
+		m.del(k)
+		return false
+	}
+	e, ok := m.items[k]
+	if ok {
+		m.size += int64(cost - e.cost)
+		e.value, e.cost = v, cost
+	} else {
+		e = &modelEntry{value: v, cost: cost}
+		m.items[k] = e
+		m.size += int64(cost)
+	}
+	m.touch(e)
+	for m.size > m.capacity {
+		victim := -1
+		for vk, ve := range m.items {
+			if vk == k {
+				continue
+			}
+			if victim == -1 || m.less(ve, m.items[victim]) {
+				victim = vk
+			}
+		}
+		m.del(victim)
+		m.evicted = append(m.evicted, victim)
+	}
+	return true
+}
+
+func (m *model) less(a, b *modelEntry) bool {
+	if m.lfu && a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.last < b.last
+}
+
+func (m *model) del(k int) bool {
+	e, ok := m.items[k]
+	if ok {
+		m.size -= int64(e.cost)
+		delete(m.items, k)
+	}
+	return ok
+}
+
+func modelCost(k, v int) int { return v%7 + 1 }
+
+// 随机操作序列下，LRU/LFU 的每一步结果、淘汰顺序都要与参考模型一致
+func TestAgainstModel(t *testing.T) {
+	for _, lfu := range []bool{false, true} {
+		for seed := int64(0); seed < 200; seed++ {
+			r := rand.New(rand.NewSource(seed))
+			capacity := int64(r.Intn(40) + 1)
+			m := &model{lfu: lfu, capacity: capacity, items: make(map[int]*modelEntry)}
+			var evicted []int
+			opts := Options[int, int]{
+				Capacity: capacity,
+				SizeFunc: func(k, v int) int64 { return int64(modelCost(k, v)) },
+				OnEvict:  func(k, _ int) { evicted = append(evicted, k) },
+			}
+			var c Cache[int, int]
+			if lfu {
+				c = NewLFU(opts)
+			} else {
+				c = NewLRU(opts)
+			}
+
+			for op := 0; op < 500; op++ {
+				k := r.Intn(20)
+				switch n := r.Intn(10); {
+				case n < 5:
+					v1, ok1 := c.Get(k)
+					v2, ok2 := m.get(k)
+					if v1 != v2 || ok1 != ok2 {
+						t.Fatalf("lfu=%v seed=%d op=%d: Get(%d) = %v, %v, model %v, %v", lfu, seed, op, k, v1, ok1, v2, ok2)
+					}
+				case n < 9:
+					v := r.Intn(100)
+					if got, want := c.Set(k, v), m.set(k, v); got != want {
+						t.Fatalf("lfu=%v seed=%d op=%d: Set(%d, %d) = %v, model %v", lfu, seed, op, k, v, got, want)
+					}
+				default:
+					if got, want := c.Delete(k), m.del(k); got != want {
+						t.Fatalf("lfu=%v seed=%d op=%d: Delete(%d) = %v, model %v", lfu, seed, op, k, got, want)
+					}
+				}
+				if c.Len() != len(m.items) || c.Size() != m.size {
+					t.Fatalf("lfu=%v seed=%d op=%d: len=%d size=%d, model len=%d size=%d",
+						lfu, seed, op, c.Len(), c.Size(), len(m.items), m.size)
+				}
+			}
+			if fmt.Sprint(evicted) != fmt.Sprint(m.evicted) {
+				t.Fatalf("lfu=%v seed=%d: evicted %v, model %v", lfu, seed, evicted, m.evicted)
+			}
+			if s := c.Stats(); s.Evictions != int64(len(m.evicted)) {
+				t.Fatalf("lfu=%v seed=%d: Evictions=%d, model %d", lfu, seed, s.Evictions, len(m.evicted))
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package boundedcache
+
+// bucket 保存访问次数相同的条目，按 LRU 顺序排列。
+// 所有非空 bucket 按 freq 升序串成链表，淘汰时从 head 开始找。
+type bucket[K comparable, V any] struct {
+	freq       int64
+	items      list[K, V]
+	prev, next *bucket[K, V]
+}
+
+// LFU 是 O(1) 的 LFU（"An O(1) algorithm for implementing the LFU cache eviction scheme"）：
+// 访问一次，条目从 freq 为 n 的 bucket 移到 n+1 的 bucket，后者不存在就紧挨着插入一个新 bucket，
+// 不需要排序或堆。不是并发安全的。
+type LFU[K comparable, V any] struct {
+	opts  Options[K, V]
+	items map[K]*entry[K, V]
+	head  *bucket[K, V] // freq 最小的 bucket
+	size  int64
+	stats Stats
+}
+
+func NewLFU[K comparable, V any](opts Options[K, V]) *LFU[K, V] {
+	opts.check()
+	return &LFU[K, V]{opts: opts, items: make(map[K]*entry[K, V])}
+}
+
+func (c *LFU[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return value, false
+	}
+	c.stats.Hits++
+	c.touch(e)
+	return e.value, true
+}
+
+func (c *LFU[K, V]) Set(key K, value V) bool {
+	size := c.opts.cost(key, value)
+	e, ok := c.items[key]
+	if size > c.opts.Capacity {
+		c.stats.Rejected++
+		if ok {
+			c.removeEntry(e)
+		}
+		return false
+	}
+	if ok {
+		c.size += size - e.size
+		e.value, e.size = value, size
+		c.touch(e)
+	} else {
+		e = &entry[K, V]{key: key, value: value, size: size}
+		c.items[key] = e
+		c.size += size
+		if c.head == nil || c.head.freq != 1 {
+			c.insertBucket(nil, c.head, 1)
+		}
+		e.bucket = c.head
+		c.head.items.pushFront(e)
+	}
+	for c.size > c.opts.Capacity {
+		v := c.victim(e)
+		c.removeEntry(v)
+		c.stats.Evictions++
+		c.stats.EvictedSize += v.size
+		if c.opts.OnEvict != nil {
+			c.opts.OnEvict(v.key, v.value)
+		}
+	}
+	return true
+}
+
+// victim 返回 freq 最小的 bucket 中最久未访问的条目，跳过刚写入的 protect
+func (c *LFU[K, V]) victim(protect *entry[K, V]) *entry[K, V] {
+	for b := c.head; b != nil; b = b.next {
+		if v := b.items.victim(protect); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// touch 把条目移到 freq+1 的 bucket
+func (c *LFU[K, V]) touch(e *entry[K, V]) {
+	cur := e.bucket
+	next := cur.next
+	if next == nil || next.freq != cur.freq+1 {
+		next = c.insertBucket(cur, next, cur.freq+1)
+	}
+	cur.items.remove(e)
+	e.bucket = next
+	next.items.pushFront(e)
+	if cur.items.empty() {
+		c.removeBucket(cur)
+	}
+}
+
+// insertBucket 在 prev 和 next 之间插入新 bucket，prev 为 nil 时插在最前面
+func (c *LFU[K, V]) insertBucket(prev, next *bucket[K, V], freq int64) *bucket[K, V] {
+	b := &bucket[K, V]{freq: freq, prev: prev, next: next}
+	b.items.init()
+	if prev != nil {
+		prev.next = b
+	} else {
+		c.head = b
+	}
+	if next != nil {
+		next.prev = b
+	}
+	return b
+}
+
+func (c *LFU[K, V]) removeBucket(b *bucket[K, V]) {
+	if b.prev != nil {
+		b.prev.next = b.next
+	} else {
+		c.head = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	}
+}
+
+func (c *LFU[K, V]) Delete(key K) bool {
+	e, ok := c.items[key]
+	if ok {
+		c.removeEntry(e)
+	}
+	return ok
+}
+
+func (c *LFU[K, V]) removeEntry(e *entry[K, V]) {
+	b := e.bucket
+	b.items.remove(e)
+	if b.items.empty() {
+		c.removeBucket(b)
+	}
+	e.bucket = nil
+	delete(c.items, e.key)
+	c.size -= e.size
+}
+
+func (c *LFU[K, V]) Len() int     { return len(c.items) }
+func (c *LFU[K, V]) Size() int64  { return c.size }
+func (c *LFU[K, V]) Stats() Stats { return c.stats }
@@ -0,0 +1,80 @@
+package boundedcache
+
+// LRU 用 map 定位条目，用双向链表维护访问顺序，Get 和 Set 都是 O(1)。
+// 不是并发安全的。
+type LRU[K comparable, V any] struct {
+	opts  Options[K, V]
+	items map[K]*entry[K, V]
+	ll    list[K, V]
+	size  int64
+	stats Stats
+}
+
+func NewLRU[K comparable, V any](opts Options[K, V]) *LRU[K, V] {
+	opts.check()
+	c := &LRU[K, V]{opts: opts, items: make(map[K]*entry[K, V])}
+	c.ll.init()
+	return c
+}
+
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return value, false
+	}
+	c.stats.Hits++
+	c.ll.moveToFront(e)
+	return e.value, true
+}
+
+func (c *LRU[K, V]) Set(key K, value V) bool {
+	size := c.opts.cost(key, value)
+	e, ok := c.items[key]
+	if size > c.opts.Capacity {
+		c.stats.Rejected++
+		if ok {
+			c.removeEntry(e)
+		}
+		return false
+	}
+	if ok {
+		c.size += size - e.size
+		e.value, e.size = value, size
+		c.ll.moveToFront(e)
+	} else {
+		e = &entry[K, V]{key: key, value: value, size: size}
+		c.items[key] = e
+		c.ll.pushFront(e)
+		c.size += size
+	}
+	// 刚写入的条目在 front，又不超过总容量，所以不会淘汰它自己
+	for c.size > c.opts.Capacity {
+		v := c.ll.victim(e)
+		c.removeEntry(v)
+		c.stats.Evictions++
+		c.stats.EvictedSize += v.size
+		if c.opts.OnEvict != nil {
+			c.opts.OnEvict(v.key, v.value)
+		}
+	}
+	return true
+}
+
+func (c *LRU[K, V]) Delete(key K) bool {
+	e, ok := c.items[key]
+	if ok {
+		c.removeEntry(e)
+	}
+	return ok
+}
+
+func (c *LRU[K, V]) removeEntry(e *entry[K, V]) {
+	c.ll.remove(e)
+	delete(c.items, e.key)
+	c.size -= e.size
+}
+
+func (c *LRU[K, V]) Len() int     { return len(c.items) }
+func (c *LRU[K, V]) Size() int64  { return c.size }
+func (c *LRU[K, V]) Stats() Stats { return c.stats }
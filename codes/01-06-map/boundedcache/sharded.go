@@ -0,0 +1,130 @@
+package boundedcache
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// Policy 选择分片内部使用的淘汰策略
+type Policy int
+
+const (
+	PolicyLRU Policy = iota
+	PolicyLFU
+)
+
+type cacheShard[K comparable, V any] struct {
+	mu sync.Mutex
+	c  Cache[K, V]
+	// Mutex 8 字节 + 接口 16 字节，补齐到 64 字节的缓存行
+	_ [40]byte
+}
+
+// Sharded 按键的哈希把条目分到多个分片，每个分片是一个独立加锁的 LRU 或 LFU。
+// 淘汰只在分片内进行，所以它是全局 LRU/LFU 的近似：键分布不均时，
+// 某个分片可能已经开始淘汰，而总开销还没有达到 Capacity。
+// 同样，单个条目能存入的最大开销是分片的容量 ShardCapacity，而不是 Capacity：
+// 开销在 ShardCapacity 和 Capacity 之间的条目会被拒绝。
+type Sharded[K comparable, V any] struct {
+	shards   []cacheShard[K, V]
+	mask     uint64
+	seed     maphash.Seed
+	perShard int64
+}
+
+// NewSharded 创建至少 shards 个分片（向上取 2 的幂）的并发安全缓存。
+// opts.Capacity 平均分给各分片并向下取整，总开销不会超过 Capacity；
+// Capacity 小于分片数时相应减少分片数。按字节计算开销、条目大小差别很大时，
+// 分片数要取得足够小，让最大的条目也不超过 ShardCapacity。
+func NewSharded[K comparable, V any](shards int, policy Policy, opts Options[K, V]) *Sharded[K, V] {
+	opts.check()
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	for n > 1 && int64(n) > opts.Capacity {
+		n >>= 1
+	}
+	per := opts
+	per.Capacity = opts.Capacity / int64(n)
+
+	s := &Sharded[K, V]{
+		shards:   make([]cacheShard[K, V], n),
+		mask:     uint64(n - 1),
+		seed:     maphash.MakeSeed(),
+		perShard: per.Capacity,
+	}
+	for i := range s.shards {
+		if policy == PolicyLFU {
+			s.shards[i].c = NewLFU(per)
+		} else {
+			s.shards[i].c = NewLRU(per)
+		}
+	}
+	return s
+}
+
+// ShardCapacity 返回每个分片的容量，也就是单个条目开销的上限
+func (s *Sharded[K, V]) ShardCapacity() int64 {
+	return s.perShard
+}
+
+func (s *Sharded[K, V]) shardFor(key K) *cacheShard[K, V] {
+	return &s.shards[maphash.Comparable(s.seed, key)&s.mask]
+}
+
+// Get 会修改访问顺序，所以读操作也要加互斥锁而不是读锁
+func (s *Sharded[K, V]) Get(key K) (V, bool) {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.c.Get(key)
+}
+
+func (s *Sharded[K, V]) Set(key K, value V) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.c.Set(key, value)
+}
+
+func (s *Sharded[K, V]) Delete(key K) bool {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.c.Delete(key)
+}
+
+func (s *Sharded[K, V]) Len() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += sh.c.Len()
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+func (s *Sharded[K, V]) Size() int64 {
+	var n int64
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += sh.c.Size()
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// Stats 汇总所有分片的统计
+func (s *Sharded[K, V]) Stats() Stats {
+	var st Stats
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		st.add(sh.c.Stats())
+		sh.mu.Unlock()
+	}
+	return st
+}
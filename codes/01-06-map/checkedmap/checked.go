@@ -0,0 +1,188 @@
+//go:build !nomapcheck
+
+package checkedmap
+
+import (
+	"iter"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const enabled = true
+
+const (
+	readerOne = 1       // 低 32 位：正在读的数量
+	writerOne = 1 << 32 // 高 32 位：正在写的数量
+)
+
+// 测试用：在访问区间内、持有内部锁时调用，用来让两次访问确定地重叠
+var testHookInside func(op string)
+
+type CheckedMap[K comparable, V any] struct {
+	handler Handler
+
+	// state 标记当前有几个读者和写者，进入时 Add，离开时减回去。
+	// 进入时发现计数里还有别人，就是一次重叠访问。
+	state  atomic.Int64
+	writer atomic.Pointer[site] // 当前写者的调用栈
+	reader atomic.Pointer[site] // 最近进入的读者的调用栈，读者可能有多个，只记一个
+
+	mu sync.RWMutex // 串行化真正的 map 操作，检测到冲突后程序还能继续跑
+	m  map[K]V
+}
+
+// site 记录一次访问的调用栈，只保存 PC，报告时才解析成文本
+type site struct {
+	op  string
+	pcs []uintptr
+}
+
+func New[K comparable, V any](h Handler) *CheckedMap[K, V] {
+	return &CheckedMap[K, V]{handler: h, m: make(map[K]V)}
+}
+
+func capture(op string) *site {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // 跳过 Callers、capture、begin
+	return &site{op: op, pcs: pcs[:n]}
+}
+
+func (s *site) String() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(s.pcs)
+	for {
+		f, more := frames.Next()
+		sb.WriteString(f.Function)
+		sb.WriteString("(...)\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+		if !more {
+			return sb.String()
+		}
+	}
+}
+
+// begin 进入访问区间，返回的 site 交给 end
+func (c *CheckedMap[K, V]) begin(op string) *site {
+	s := capture(op)
+	if op == "write" {
+		if old := c.state.Add(writerOne) - writerOne; old != 0 {
+			if old >= writerOne {
+				c.report(s, &c.writer, "write")
+			} else {
+				c.report(s, &c.reader, "read")
+			}
+		}
+		c.writer.Store(s)
+	} else {
+		if old := c.state.Add(readerOne) - readerOne; old >= writerOne {
+			c.report(s, &c.writer, "write")
+		}
+		c.reader.Store(s)
+	}
+	return s
+}
+
+func (c *CheckedMap[K, V]) end(s *site) {
+	if s.op == "write" {
+		c.writer.CompareAndSwap(s, nil)
+		c.state.Add(-writerOne)
+	} else {
+		c.reader.CompareAndSwap(s, nil)
+		c.state.Add(-readerOne)
+	}
+}
+
+// report 在发现冲突的 goroutine 中调用 handler。
+// 对方是先 Add 再保存 site 的，这里可能看到 nil，稍等一下对方就会写进去。
+func (c *CheckedMap[K, V]) report(s *site, other *atomic.Pointer[site], otherOp string) {
+	o := other.Load()
+	for i := 0; o == nil && i < 100; i++ {
+		runtime.Gosched()
+		o = other.Load()
+	}
+	r := &Race{Op: s.op, Stack: s.String(), Other: otherOp}
+	if o != nil {
+		r.OtherStack = o.String()
+	}
+	if c.handler == nil {
+		c.end(s) // panic 之后调用方可能 recover 并继续使用这个 map，计数要还回去
+		panic(r)
+	}
+	c.handler(r)
+}
+
+func (c *CheckedMap[K, V]) Get(key K) (V, bool) {
+	s := c.begin("read")
+	defer c.end(s)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if testHookInside != nil {
+		testHookInside("read")
+	}
+	v, ok := c.m[key]
+	return v, ok
+}
+
+func (c *CheckedMap[K, V]) Set(key K, value V) {
+	s := c.begin("write")
+	defer c.end(s)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if testHookInside != nil {
+		testHookInside("write")
+	}
+	c.m[key] = value
+}
+
+func (c *CheckedMap[K, V]) Delete(key K) {
+	s := c.begin("write")
+	defer c.end(s)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if testHookInside != nil {
+		testHookInside("write")
+	}
+	delete(c.m, key)
+}
+
+func (c *CheckedMap[K, V]) Len() int {
+	s := c.begin("read")
+	defer c.end(s)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.m)
+}
+
+// All 遍历 map 的快照：只有复制的过程算作一次读访问，yield 中可以读写这个 map，
+// 修改不影响本次遍历。不带检测时和 range 内置 map 的行为一样。
+func (c *CheckedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		type entry struct {
+			k K
+			v V
+		}
+		s := c.begin("read")
+		c.mu.RLock()
+		if testHookInside != nil {
+			testHookInside("read")
+		}
+		entries := make([]entry, 0, len(c.m))
+		for k, v := range c.m {
+			entries = append(entries, entry{k, v})
+		}
+		c.mu.RUnlock()
+		c.end(s)
+
+		for _, e := range entries {
+			if !yield(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
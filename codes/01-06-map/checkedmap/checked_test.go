@@ -0,0 +1,183 @@
+//go:build !nomapcheck
+
+package checkedmap
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// holdInside 让下一次进入访问区间的操作停在里面（持有内部锁），直到 release 被关闭，
+// 这样另一个 goroutine 的访问一定和它重叠
+func holdInside(t *testing.T) (inside <-chan struct{}, release chan struct{}) {
+	in := make(chan struct{})
+	release = make(chan struct{})
+	var once sync.Once
+	testHookInside = func(string) {
+		once.Do(func() {
+			close(in)
+			<-release
+		})
+	}
+	t.Cleanup(func() { testHookInside = nil })
+	return in, release
+}
+
+// 单独的函数，方便在调用栈里认出双方
+func writerA(m *CheckedMap[string, int]) { m.Set("a", 1) }
+func writerB(m *CheckedMap[string, int]) { m.Delete("b") }
+func readerA(m *CheckedMap[string, int]) { m.Get("a") }
+func readerB(m *CheckedMap[string, int]) { m.Len() }
+
+func TestOverlapReportsBothStacks(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		a, b         func(*CheckedMap[string, int])
+		nameA, nameB string
+		op, other    string
+		msg          string
+	}{
+		{"write/write", writerA, writerB, "writerA", "writerB", "write", "write", "concurrent map writes"},
+		{"read/write", readerA, writerB, "readerA", "writerB", "write", "read", "concurrent map read and map write"},
+		{"write/read", writerA, readerB, "writerA", "readerB", "read", "write", "concurrent map read and map write"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inside, release := holdInside(t)
+			var races []*Race
+			m := New[string, int](func(r *Race) {
+				races = append(races, r)
+				close(release)
+			})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				tc.a(m)
+			}()
+			<-inside
+			tc.b(m) // 发现冲突、报告，然后等 a 释放内部锁
+			<-done
+
+			if len(races) != 1 {
+				t.Fatalf("got %d reports", len(races))
+			}
+			r := races[0]
+			if r.Op != tc.op || r.Other != tc.other {
+				t.Fatalf("Op = %s, Other = %s", r.Op, r.Other)
+			}
+			nameA, nameB := "checkedmap."+tc.nameA, "checkedmap."+tc.nameB
+			if !strings.Contains(r.Stack, nameB) || strings.Contains(r.Stack, nameA) {
+				t.Errorf("Stack does not point at %s:\n%s", nameB, r.Stack)
+			}
+			if !strings.Contains(r.OtherStack, nameA) {
+				t.Errorf("OtherStack does not point at %s:\n%s", nameA, r.OtherStack)
+			}
+			if msg := r.Error(); !strings.HasPrefix(msg, "checkedmap: "+tc.msg) {
+				t.Errorf("Error() = %s", msg)
+			}
+			if m.state.Load() != 0 {
+				t.Fatalf("state = %#x after both left", m.state.Load())
+			}
+		})
+	}
+}
+
+// 默认 handler 是 panic：可以 recover，之后 map 照常使用
+func TestDefaultHandlerPanics(t *testing.T) {
+	inside, release := holdInside(t)
+	m := New[string, int](nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writerA(m)
+	}()
+	<-inside
+
+	var recovered any
+	func() {
+		defer func() { recovered = recover() }()
+		writerB(m)
+	}()
+	close(release)
+	<-done
+
+	r, ok := recovered.(*Race)
+	if !ok || r.Op != "write" || r.Other != "write" {
+		t.Fatalf("recovered %v", recovered)
+	}
+	if m.state.Load() != 0 {
+		t.Fatalf("state = %#x", m.state.Load())
+	}
+	m.Set("c", 3) // 不再 panic
+	if v, _ := m.Get("a"); v != 1 || m.Len() != 2 {
+		t.Fatalf("map after recover: a=%d len=%d", v, m.Len())
+	}
+}
+
+type fakeT struct {
+	errs chan string
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errs <- fmt.Sprintf(format, args...)
+}
+
+func TestReportToTesting(t *testing.T) {
+	inside, release := holdInside(t)
+	ft := &fakeT{errs: make(chan string, 1)}
+	m := New[string, int](Report(ft))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readerA(m)
+	}()
+	<-inside
+	doneB := make(chan struct{})
+	go func() {
+		// 报告之后 writerB 要等 readerA 释放锁，所以放到另一个 goroutine 里
+		defer close(doneB)
+		writerB(m)
+	}()
+	msg := <-ft.errs
+	close(release)
+	<-done
+	<-doneB
+	if !strings.Contains(msg, "concurrent map read and map write") || !strings.Contains(msg, "readerA") {
+		t.Fatalf("report:\n%s", msg)
+	}
+}
+
+// 不加锁的并发读写：在访问区间内让出 CPU 把窗口拉大，单核机器上也一定会重叠；
+// 报告之后继续运行，进程不会像内置 map 那样崩溃
+func TestUnguardedConcurrentUseIsDetected(t *testing.T) {
+	testHookInside = func(string) { runtime.Gosched() }
+	t.Cleanup(func() { testHookInside = nil })
+	var mu sync.Mutex
+	var races int
+	m := New[int, int](func(*Race) {
+		mu.Lock()
+		races++
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				m.Set(i, i)
+				m.Get(i)
+			}
+		}()
+	}
+	wg.Wait()
+	if m.Len() != 500 {
+		t.Fatalf("Len = %d", m.Len())
+	}
+	if races == 0 {
+		t.Fatal("no overlap detected")
+	}
+	t.Logf("%d overlapping accesses reported", races)
+}
@@ -0,0 +1,60 @@
+// 调试用的并发访问检测 map
+//
+// demo.go 的 f7 必须加锁，因为内置 map 被并发写时 runtime 直接报
+// "fatal error: concurrent map writes" 结束进程，recover 也救不回来，只能看到其中一方的调用栈。
+// CheckedMap 在每次访问前后用原子计数标记“正在读/正在写”，发现写和写、读和写重叠时：
+//
+//	报告双方的操作和调用栈（对方已经离开时只有自己的）
+//	通过 Handler 交给调用方处理：默认 panic（可以 recover），测试中用 Report(t) 转成 t.Errorf
+//	真正的 map 操作仍由内部的锁串行化，报告之后程序可以继续运行
+//
+// 和 runtime 的检测一样，只有访问在时间上真的重叠才能发现，不能代替 -race。
+//
+// 检测需要记录每次访问的调用栈，开销很大。用 -tags nomapcheck 编译时 CheckedMap
+// 只是内置 map 的一层包装，没有锁也没有原子操作，Handler 被忽略。
+package checkedmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enabled 表示当前编译是否带检测
+const Enabled = enabled
+
+// Race 描述一次重叠访问，Op 是发现冲突的一方，Other 是已经在访问中的一方
+type Race struct {
+	Op         string // "read" 或 "write"
+	Stack      string
+	Other      string
+	OtherStack string // 对方已经结束访问时为空
+}
+
+func (r *Race) Error() string {
+	var sb strings.Builder
+	if r.Op == "write" && r.Other == "write" {
+		sb.WriteString("checkedmap: concurrent map writes")
+	} else {
+		sb.WriteString("checkedmap: concurrent map read and map write")
+	}
+	fmt.Fprintf(&sb, "\n\n%s:\n%s", r.Op, r.Stack)
+	if r.OtherStack != "" {
+		fmt.Fprintf(&sb, "\nconcurrent %s:\n%s", r.Other, r.OtherStack)
+	} else {
+		fmt.Fprintf(&sb, "\nconcurrent %s: already finished, stack not available\n", r.Other)
+	}
+	return sb.String()
+}
+
+// Handler 在发现冲突的 goroutine 中调用，为 nil 时 panic(*Race)
+type Handler func(*Race)
+
+// TB 是 *testing.T、*testing.B 都满足的最小接口
+type TB interface {
+	Errorf(format string, args ...any)
+}
+
+// Report 把冲突报告为测试失败。t.Errorf 可以在其它 goroutine 中调用，测试会继续运行。
+func Report(t TB) Handler {
+	return func(r *Race) { t.Errorf("%v", r) }
+}
@@ -0,0 +1,57 @@
+package checkedmap
+
+import (
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	cd checkedmap
+	go test -v -race .
+	go test -v -tags nomapcheck .   # 生产构建：只是内置 map 的包装
+*/
+
+func TestBasicOperations(t *testing.T) {
+	m := New[string, int](Report(t))
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 3)
+	if v, ok := m.Get("a"); !ok || v != 3 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	m.Delete("b")
+	if _, ok := m.Get("b"); ok || m.Len() != 1 {
+		t.Fatalf("after Delete: Len = %d", m.Len())
+	}
+	// 和内置 map 一样，遍历中可以删除
+	for k := range m.All() {
+		m.Delete(k)
+	}
+	if m.Len() != 0 {
+		t.Fatalf("after All: Len = %d", m.Len())
+	}
+}
+
+// 串行使用、或者由调用方加锁的并发使用都不会误报
+func TestGuardedConcurrentUseIsQuiet(t *testing.T) {
+	m := New[int, int](Report(t))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				mu.Lock()
+				m.Set(i, i*2)
+				m.Get(i)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if m.Len() != 1000 {
+		t.Fatalf("Len = %d", m.Len())
+	}
+}
@@ -0,0 +1,3 @@
+module checkedmap
+
+go 1.23
@@ -0,0 +1,37 @@
+//go:build nomapcheck
+
+package checkedmap
+
+import "iter"
+
+const enabled = false
+
+// 不带检测的版本：一层内置 map 的包装，方法都会被内联
+type CheckedMap[K comparable, V any] struct {
+	m map[K]V
+}
+
+func New[K comparable, V any](Handler) *CheckedMap[K, V] {
+	return &CheckedMap[K, V]{m: make(map[K]V)}
+}
+
+func (c *CheckedMap[K, V]) Get(key K) (V, bool) {
+	v, ok := c.m[key]
+	return v, ok
+}
+
+func (c *CheckedMap[K, V]) Set(key K, value V) { c.m[key] = value }
+
+func (c *CheckedMap[K, V]) Delete(key K) { delete(c.m, key) }
+
+func (c *CheckedMap[K, V]) Len() int { return len(c.m) }
+
+func (c *CheckedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range c.m {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// 写时复制（copy-on-write）的 map，适合读多写极少的配置
+//
+// demo.go 的 f2 建议读多写少时用 sync.Map，f7 用一把 Mutex。配置类的 map 通常整份替换、
+// 每秒被读几百万次，这两种方式每次读都要加锁或做原子操作加类型断言。
+// COWMap 用 atomic.Pointer 指向一份不可变的快照：
+//
+//	读：一次原子 Load 加一次内置 map 查找，没有锁，读者之间没有任何写共享内存
+//	写：在 Mutex 下复制整个 map、修改副本，再原子地换上去，O(n)
+//	Update 把多次修改合并成一个事务，只复制一次；回调返回错误时什么都不改
+//	Subscribe 在每次发布新快照后收到通知
+//
+// 读者拿到的 Snapshot 永远不会变，可以放心在多个 goroutine 中长期持有。
+// 零值可以直接使用。
+package cowmap
+
+import (
+	"iter"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshot 是某个版本的只读视图
+type Snapshot[K comparable, V any] struct {
+	m       map[K]V
+	version uint64
+}
+
+// Version 是版本号：零值和 New 创建的初始内容是 0，每发布一次加 1
+func (s *Snapshot[K, V]) Version() uint64 { return s.version }
+
+func (s *Snapshot[K, V]) Get(key K) (V, bool) {
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (s *Snapshot[K, V]) Len() int { return len(s.m) }
+
+func (s *Snapshot[K, V]) All() iter.Seq2[K, V] { return maps.All(s.m) }
+
+// Clone 返回一份可以修改的副本
+func (s *Snapshot[K, V]) Clone() map[K]V {
+	m := make(map[K]V, len(s.m))
+	maps.Copy(m, s.m)
+	return m
+}
+
+type COWMap[K comparable, V any] struct {
+	current atomic.Pointer[Snapshot[K, V]] // nil 表示零值，等同于空的版本 0
+
+	mu   sync.Mutex // 串行化写者，保护 subs
+	subs map[*subscriber[K, V]]struct{}
+}
+
+type subscriber[K comparable, V any] struct {
+	ch chan *Snapshot[K, V]
+}
+
+// New 用 initial 的副本创建 map，initial 可以为 nil
+func New[K comparable, V any](initial map[K]V) *COWMap[K, V] {
+	c := &COWMap[K, V]{}
+	if len(initial) > 0 {
+		c.current.Store(&Snapshot[K, V]{m: maps.Clone(initial)})
+	}
+	return c
+}
+
+// Snapshot 返回当前版本
+func (c *COWMap[K, V]) Snapshot() *Snapshot[K, V] {
+	if s := c.current.Load(); s != nil {
+		return s
+	}
+	return &Snapshot[K, V]{}
+}
+
+func (c *COWMap[K, V]) Load(key K) (value V, ok bool) {
+	if s := c.current.Load(); s != nil {
+		value, ok = s.m[key]
+	}
+	return value, ok
+}
+
+func (c *COWMap[K, V]) Len() int {
+	if s := c.current.Load(); s != nil {
+		return len(s.m)
+	}
+	return 0
+}
+
+func (c *COWMap[K, V]) Store(key K, value V) {
+	c.Update(func(tx *Tx[K, V]) error {
+		tx.Set(key, value)
+		return nil
+	})
+}
+
+func (c *COWMap[K, V]) Delete(key K) {
+	c.Update(func(tx *Tx[K, V]) error {
+		tx.Delete(key)
+		return nil
+	})
+}
+
+// Replace 用 m 的副本整体替换当前内容
+func (c *COWMap[K, V]) Replace(m map[K]V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publish(maps.Clone(m))
+}
+
+// Tx 是 Update 回调中的事务，只能在回调内使用。
+// 读取看到的是事务内已经做过的修改；第一次修改时才复制 map。
+type Tx[K comparable, V any] struct {
+	base  map[K]V
+	m     map[K]V // 第一次修改前为 nil
+	dirty bool
+}
+
+func (tx *Tx[K, V]) view() map[K]V {
+	if tx.m != nil {
+		return tx.m
+	}
+	return tx.base
+}
+
+func (tx *Tx[K, V]) Get(key K) (V, bool) {
+	v, ok := tx.view()[key]
+	return v, ok
+}
+
+func (tx *Tx[K, V]) Len() int { return len(tx.view()) }
+
+func (tx *Tx[K, V]) Set(key K, value V) {
+	tx.copy()
+	tx.m[key] = value
+}
+
+func (tx *Tx[K, V]) Delete(key K) {
+	if _, ok := tx.view()[key]; !ok {
+		return
+	}
+	tx.copy()
+	delete(tx.m, key)
+}
+
+// Clear 删除所有键
+func (tx *Tx[K, V]) Clear() {
+	if len(tx.view()) == 0 {
+		return
+	}
+	tx.m = make(map[K]V)
+	tx.dirty = true
+}
+
+func (tx *Tx[K, V]) copy() {
+	if tx.m == nil {
+		tx.m = make(map[K]V, len(tx.base)+1)
+		maps.Copy(tx.m, tx.base)
+	}
+	tx.dirty = true
+}
+
+// Update 在写锁下执行 fn，fn 中的所有修改作为一个新版本原子地发布。
+// fn 返回错误或者没有做任何修改时不发布，也不通知订阅者。
+// fn 中不能调用同一个 COWMap 的写方法（会死锁），读方法看到的是事务开始前的版本。
+func (c *COWMap[K, V]) Update(fn func(tx *Tx[K, V]) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx := &Tx[K, V]{}
+	if s := c.current.Load(); s != nil {
+		tx.base = s.m
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.dirty {
+		c.publish(tx.m)
+	}
+	return nil
+}
+
+// publish 在 c.mu 下调用，m 之后不能再被修改
+func (c *COWMap[K, V]) publish(m map[K]V) {
+	var version uint64
+	if s := c.current.Load(); s != nil {
+		version = s.version
+	}
+	s := &Snapshot[K, V]{m: m, version: version + 1}
+	c.current.Store(s)
+	for sub := range c.subs {
+		sub.notify(s)
+	}
+}
+
+// notify 不阻塞写者：通道里还有没取走的旧版本时换成新版本，慢的订阅者只会看到最新的
+func (sub *subscriber[K, V]) notify(s *Snapshot[K, V]) {
+	for {
+		select {
+		case sub.ch <- s:
+			return
+		default:
+		}
+		select {
+		case <-sub.ch:
+		default:
+		}
+	}
+}
+
+// Subscribe 返回一个通道，之后每次发布新版本都会收到对应的快照。
+// 订阅者处理不过来时中间的版本会被合并，只保证最后收到的是最新版本。
+// cancel 取消订阅并关闭通道，可以多次调用。
+func (c *COWMap[K, V]) Subscribe() (updates <-chan *Snapshot[K, V], cancel func()) {
+	sub := &subscriber[K, V]{ch: make(chan *Snapshot[K, V], 1)}
+	c.mu.Lock()
+	if c.subs == nil {
+		c.subs = make(map[*subscriber[K, V]]struct{})
+	}
+	c.subs[sub] = struct{}{}
+	c.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.subs, sub)
+			c.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
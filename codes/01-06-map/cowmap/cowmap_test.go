@@ -0,0 +1,198 @@
+package cowmap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	cd cowmap
+	go test -v -race .
+*/
+
+func TestZeroValue(t *testing.T) {
+	var m COWMap[string, int]
+	if _, ok := m.Load("a"); ok || m.Len() != 0 || m.Snapshot().Version() != 0 {
+		t.Fatal("zero value is not empty")
+	}
+	m.Store("a", 1)
+	if v, ok := m.Load("a"); !ok || v != 1 || m.Snapshot().Version() != 1 {
+		t.Fatalf("Load(a) = %d, %v", v, ok)
+	}
+}
+
+func TestNewCopiesInitial(t *testing.T) {
+	initial := map[string]int{"a": 1}
+	m := New(initial)
+	initial["a"] = 2
+	if v, _ := m.Load("a"); v != 1 {
+		t.Fatalf("New did not copy: a = %d", v)
+	}
+	m.Replace(initial)
+	initial["a"] = 3
+	if v, _ := m.Load("a"); v != 2 {
+		t.Fatalf("Replace did not copy: a = %d", v)
+	}
+}
+
+// 拿到的快照不受之后写入的影响
+func TestSnapshotIsImmutable(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2})
+	s := m.Snapshot()
+	m.Store("a", 10)
+	m.Delete("b")
+	if v, _ := s.Get("a"); v != 1 || s.Len() != 2 {
+		t.Fatalf("old snapshot changed: a=%d len=%d", v, s.Len())
+	}
+	if v, _ := m.Load("a"); v != 10 || m.Len() != 1 {
+		t.Fatalf("current: a=%d len=%d", v, m.Len())
+	}
+	c := s.Clone()
+	c["z"] = 26
+	if _, ok := s.Get("z"); ok {
+		t.Fatal("Clone shares the snapshot map")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2})
+	v0 := m.Snapshot().Version()
+
+	err := m.Update(func(tx *Tx[string, int]) error {
+		tx.Set("a", 100)
+		tx.Delete("b")
+		tx.Set("c", 3)
+		if v, _ := tx.Get("a"); v != 100 || tx.Len() != 2 {
+			t.Errorf("tx does not see its own writes: a=%d len=%d", v, tx.Len())
+		}
+		if v, _ := m.Load("a"); v != 1 {
+			t.Errorf("uncommitted write visible to readers: a=%d", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := m.Snapshot()
+	if s.Version() != v0+1 || s.Len() != 2 {
+		t.Fatalf("version %d len %d, want one new version", s.Version(), s.Len())
+	}
+
+	// 回调出错时什么都不改
+	boom := errors.New("boom")
+	err = m.Update(func(tx *Tx[string, int]) error {
+		tx.Clear()
+		return boom
+	})
+	if !errors.Is(err, boom) || m.Snapshot() != s {
+		t.Fatalf("failed tx published: %v", err)
+	}
+
+	// 没有修改时不发布新版本
+	m.Update(func(tx *Tx[string, int]) error {
+		tx.Delete("missing")
+		tx.Get("a")
+		return nil
+	})
+	if m.Snapshot() != s {
+		t.Fatal("no-op tx published a new version")
+	}
+
+	m.Update(func(tx *Tx[string, int]) error {
+		tx.Clear()
+		tx.Set("only", 1)
+		return nil
+	})
+	if m.Len() != 1 {
+		t.Fatalf("after Clear: len %d", m.Len())
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m := New[string, int](nil)
+	updates, cancel := m.Subscribe()
+
+	m.Store("a", 1)
+	if s := <-updates; s.Version() != 1 {
+		t.Fatalf("version %d", s.Version())
+	}
+
+	// 订阅者没来得及取，中间的版本被合并，只剩最新的
+	m.Store("a", 2)
+	m.Store("a", 3)
+	m.Update(func(tx *Tx[string, int]) error { return nil }) // 不发布
+	s := <-updates
+	if v, _ := s.Get("a"); v != 3 || s.Version() != 3 {
+		t.Fatalf("got version %d a=%d, want the latest", s.Version(), v)
+	}
+	select {
+	case s := <-updates:
+		t.Fatalf("extra notification: version %d", s.Version())
+	default:
+	}
+
+	cancel()
+	cancel()
+	m.Store("a", 4) // 取消后不再通知，也不会往已关闭的通道发送
+	if _, ok := <-updates; ok {
+		t.Fatal("channel not closed")
+	}
+}
+
+// 读者和多个事务并发：每个事务把 1 从 a 挪到 b，任何快照里 a+b 都是 1000
+func TestConcurrentReadersSeeConsistentSnapshots(t *testing.T) {
+	m := New(map[string]int{"a": 1000, "b": 0})
+	updates, cancel := m.Subscribe()
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.Update(func(tx *Tx[string, int]) error {
+					a, _ := tx.Get("a")
+					b, _ := tx.Get("b")
+					tx.Set("a", a-1)
+					tx.Set("b", b+1)
+					return nil
+				})
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s := m.Snapshot()
+				a, _ := s.Get("a")
+				b, _ := s.Get("b")
+				if a+b != 1000 {
+					t.Errorf("version %d: a+b = %d", s.Version(), a+b)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	if a, _ := m.Load("a"); a != 600 || m.Snapshot().Version() != 400 {
+		t.Fatalf("a = %d version = %d", a, m.Snapshot().Version())
+	}
+	if s := <-updates; s.Version() != 400 {
+		t.Fatalf("last notification: version %d", s.Version())
+	}
+}
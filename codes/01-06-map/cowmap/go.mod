@@ -0,0 +1,3 @@
+module cowmap
+
+go 1.23
@@ -80,6 +80,7 @@ func f1() {
 // 并发：Go 的 map 在多个 goroutine 中并发读写时不是线程安全的。直接操作可能导致运行时崩溃。
 // 使用 sync.Mutex 保护 map。
 // 使用 sync.Map 提供线程安全的 map
+// 读极多、写极少（比如整份替换的配置）时见 cowmap/
 func f2() {
 	var m sync.Map
 	m.Store("Alice", 25)
@@ -103,6 +104,7 @@ func f4() {
 
 // 反序列化时，如果目标类型是 map[string]interface{}，注意数字类型可能会被解析为 float64
 // 可能会，我没测出来
+// 大整数、小数原文都要保留时见 jsonx/
 func f5() {
 	jsonStr := `{"Alice":25.0,"Bob":30}`
 	var m map[string]interface{}
@@ -120,6 +122,7 @@ func f5() {
 }
 
 // 遍历顺序不固定，每次遍历的顺序可能不同；因为 map 是无序的
+// 需要按插入顺序遍历、序列化时见 orderedmap/
 func f6() {
 	m := map[string]int{"00": 25, "01": 30, "02": 35, "03": 40}
 	for k, v := range m {
@@ -127,6 +130,7 @@ func f6() {
 	}
 }
 
+// 测试里想在不加锁时定位是哪两处调用并发访问了 map，见 checkedmap/
 func f7() {
 	fmt.Println("f7 -------------------- 并发：sync.Mutex --------------------")
 	m := make(map[int]int)
@@ -0,0 +1,3 @@
+module hmap
+
+go 1.24
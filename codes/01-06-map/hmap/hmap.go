@@ -0,0 +1,464 @@
+// 教学用的哈希表，照搬 Go 1.24 之前 runtime/map.go 的结构和算法
+//
+// 知识点/01-数据类型/06-map-02-底层.md 讲了 hmap、bmap、tophash、溢出桶和扩容，
+// 这里用泛型把它们写成可以运行、可以打印的代码：
+//
+//	每个 bucket 8 个槽位，tophash 保存哈希值的高 8 位，小于 minTopHash 的值用作槽位状态
+//	bucket 装满后挂溢出桶，形成链表
+//	count > 6.5 * 2^B 时翻倍扩容；溢出桶太多时等量扩容（整理碎片）
+//	扩容是渐进的：每次写操作最多搬迁两个旧 bucket，读操作在旧 bucket 没搬完时去旧 bucket 找
+//
+// Inspect 打印 bucket 布局、溢出链和搬迁进度，SetTrace 在每次写操作后自动打印。
+// 与 runtime 的区别：没有用 unsafe 做紧凑布局、不预分配溢出桶、不检测并发写，
+// 遍历期间修改 map 会 panic（runtime 允许遍历时修改）。
+package hmap
+
+import (
+	"hash/maphash"
+	"io"
+	"iter"
+	"math/rand/v2"
+)
+
+const (
+	bucketCntBits = 3
+	bucketCnt     = 1 << bucketCntBits // 每个 bucket 的槽位数
+
+	// 负载因子 6.5 = loadFactorNum / loadFactorDen，用整数运算
+	loadFactorNum = 13
+	loadFactorDen = 2
+)
+
+// tophash 中小于 minTopHash 的值表示槽位状态，真正的 tophash 会被抬到 minTopHash 以上
+const (
+	emptyRest      = 0 // 槽位为空，并且之后的槽位（包括溢出桶）也都为空，查找可以提前结束
+	emptyOne       = 1 // 槽位为空（被删除过）
+	evacuatedX     = 2 // 已搬迁到新数组的前半部分（下标不变）
+	evacuatedY     = 3 // 已搬迁到新数组的后半部分（下标 + 旧 bucket 数）
+	evacuatedEmpty = 4 // 槽位为空，所在 bucket 已搬迁
+	minTopHash     = 5
+)
+
+type bmap[K comparable, V any] struct {
+	tophash  [bucketCnt]uint8
+	keys     [bucketCnt]K
+	elems    [bucketCnt]V
+	overflow *bmap[K, V]
+}
+
+// Map 对应 runtime 的 hmap
+type Map[K comparable, V any] struct {
+	count      int
+	sameSize   bool  // 正在进行的是等量扩容
+	B          uint8 // bucket 数为 2^B
+	noverflow  int   // 溢出桶数量（runtime 在 B 较大时是近似值，这里精确计数）
+	hash       func(K) uint64
+	seed       maphash.Seed
+	buckets    []bmap[K, V]
+	oldbuckets []bmap[K, V] // 扩容期间的旧数组，非 nil 表示正在扩容
+	nevacuate  int          // 下标小于它的旧 bucket 都已搬迁
+
+	writes int       // 写操作计数，遍历时用来发现并发修改
+	trace  io.Writer // 非 nil 时每次写操作后打印布局
+}
+
+// New 创建 map，hint 是预计的元素个数，和 make(map[K]V, hint) 一样用来提前确定 B
+func New[K comparable, V any](hint int) *Map[K, V] {
+	return NewWithHash[K, V](hint, nil)
+}
+
+// NewWithHash 使用自定义哈希函数，测试中用它制造冲突。hash 为 nil 时使用 maphash.Comparable
+func NewWithHash[K comparable, V any](hint int, hash func(K) uint64) *Map[K, V] {
+	h := &Map[K, V]{hash: hash, seed: maphash.MakeSeed()}
+	for overLoadFactor(hint, h.B) {
+		h.B++
+	}
+	h.buckets = make([]bmap[K, V], 1<<h.B)
+	return h
+}
+
+func (h *Map[K, V]) hashOf(key K) uint64 {
+	if h.hash != nil {
+		return h.hash(key)
+	}
+	return maphash.Comparable(h.seed, key)
+}
+
+// SetTrace 设置 w 后，每次 Set/Delete 都会把操作和 Inspect 的结果写到 w；传 nil 关闭
+func (h *Map[K, V]) SetTrace(w io.Writer) {
+	h.trace = w
+}
+
+func (h *Map[K, V]) Len() int { return h.count }
+
+func bucketMask(b uint8) uint64 { return 1<<b - 1 }
+
+// tophash 取哈希值的高 8 位，避开表示状态的 0~4
+func tophash(hash uint64) uint8 {
+	top := uint8(hash >> 56)
+	if top < minTopHash {
+		top += minTopHash
+	}
+	return top
+}
+
+func isEmpty(x uint8) bool { return x <= emptyOne }
+
+func evacuated[K comparable, V any](b *bmap[K, V]) bool {
+	h := b.tophash[0]
+	return h > emptyOne && h < minTopHash
+}
+
+// overLoadFactor 判断 count 个元素放进 2^B 个 bucket 是否超过负载因子
+func overLoadFactor(count int, B uint8) bool {
+	return count > bucketCnt && uint64(count) > loadFactorNum*((uint64(1)<<B)/loadFactorDen)
+}
+
+// tooManyOverflowBuckets 溢出桶数量达到 bucket 数量（B 最多按 15 算）时认为太多，
+// 通常是大量删除后留下的稀疏溢出链，等量扩容可以把它们压实
+func tooManyOverflowBuckets(noverflow int, B uint8) bool {
+	if B > 15 {
+		B = 15
+	}
+	return noverflow >= 1<<(B&15)
+}
+
+func (h *Map[K, V]) growing() bool { return h.oldbuckets != nil }
+
+// noldbuckets 返回扩容前的 bucket 数
+func (h *Map[K, V]) noldbuckets() int {
+	if h.sameSize {
+		return 1 << h.B
+	}
+	return 1 << (h.B - 1)
+}
+
+func (h *Map[K, V]) oldbucketmask() uint64 { return uint64(h.noldbuckets() - 1) }
+
+// Get 对应 mapaccess2
+func (h *Map[K, V]) Get(key K) (value V, ok bool) {
+	if h.count == 0 {
+		return value, false
+	}
+	hash := h.hashOf(key)
+	m := bucketMask(h.B)
+	b := &h.buckets[hash&m]
+	if h.growing() {
+		if !h.sameSize {
+			m >>= 1 // 旧数组只有一半大
+		}
+		if oldb := &h.oldbuckets[hash&m]; !evacuated(oldb) {
+			b = oldb
+		}
+	}
+	top := tophash(hash)
+	for ; b != nil; b = b.overflow {
+		for i := 0; i < bucketCnt; i++ {
+			if b.tophash[i] != top {
+				if b.tophash[i] == emptyRest {
+					return value, false
+				}
+				continue
+			}
+			if b.keys[i] == key {
+				return b.elems[i], true
+			}
+		}
+	}
+	return value, false
+}
+
+// Set 对应 mapassign
+func (h *Map[K, V]) Set(key K, value V) {
+	h.writes++
+	hash := h.hashOf(key)
+
+again:
+	bucket := hash & bucketMask(h.B)
+	if h.growing() {
+		h.growWork(bucket)
+	}
+	b := &h.buckets[bucket]
+	top := tophash(hash)
+
+	var insertb *bmap[K, V]
+	var inserti int
+bucketloop:
+	for {
+		for i := 0; i < bucketCnt; i++ {
+			if b.tophash[i] != top {
+				if isEmpty(b.tophash[i]) && insertb == nil {
+					insertb, inserti = b, i
+				}
+				if b.tophash[i] == emptyRest {
+					break bucketloop
+				}
+				continue
+			}
+			if b.keys[i] != key {
+				continue
+			}
+			b.elems[i] = value
+			h.traceOp("Set", key)
+			return
+		}
+		if b.overflow == nil {
+			break
+		}
+		b = b.overflow
+	}
+
+	// 没找到键。如果需要扩容就先开始扩容，然后重新定位（扩容后 bucket 下标可能变了）
+	if !h.growing() && (overLoadFactor(h.count+1, h.B) || tooManyOverflowBuckets(h.noverflow, h.B)) {
+		h.hashGrow()
+		goto again
+	}
+
+	if insertb == nil {
+		// 整条链都满了，挂一个新的溢出桶
+		insertb, inserti = h.newoverflow(b), 0
+	}
+	insertb.tophash[inserti] = top
+	insertb.keys[inserti] = key
+	insertb.elems[inserti] = value
+	h.count++
+	h.traceOp("Set", key)
+}
+
+func (h *Map[K, V]) newoverflow(b *bmap[K, V]) *bmap[K, V] {
+	ovf := new(bmap[K, V])
+	h.noverflow++
+	b.overflow = ovf
+	return ovf
+}
+
+// Delete 对应 mapdelete
+func (h *Map[K, V]) Delete(key K) {
+	if h.count == 0 {
+		return
+	}
+	h.writes++
+	hash := h.hashOf(key)
+	bucket := hash & bucketMask(h.B)
+	if h.growing() {
+		h.growWork(bucket)
+	}
+	b := &h.buckets[bucket]
+	bOrig := b
+	top := tophash(hash)
+search:
+	for ; b != nil; b = b.overflow {
+		for i := 0; i < bucketCnt; i++ {
+			if b.tophash[i] != top {
+				if b.tophash[i] == emptyRest {
+					break search
+				}
+				continue
+			}
+			if b.keys[i] != key {
+				continue
+			}
+			var zk K
+			var zv V
+			b.keys[i], b.elems[i] = zk, zv // 清掉引用，方便 GC
+			b.tophash[i] = emptyOne
+
+			// 如果这个槽位之后全是空的，把它以及前面连续的 emptyOne 都改成 emptyRest，
+			// 这样查找可以更早结束
+			if i == bucketCnt-1 {
+				if b.overflow != nil && b.overflow.tophash[0] != emptyRest {
+					goto notLast
+				}
+			} else if b.tophash[i+1] != emptyRest {
+				goto notLast
+			}
+			for {
+				b.tophash[i] = emptyRest
+				if i == 0 {
+					if b == bOrig {
+						break // 已经到了链表头
+					}
+					// 找到前一个 bucket，从它的最后一个槽位继续
+					c := b
+					for b = bOrig; b.overflow != c; b = b.overflow {
+					}
+					i = bucketCnt - 1
+				} else {
+					i--
+				}
+				if b.tophash[i] != emptyOne {
+					break
+				}
+			}
+		notLast:
+			h.count--
+			if h.count == 0 {
+				// map 空了，换一个种子，让攻击者难以持续制造冲突
+				h.seed = maphash.MakeSeed()
+			}
+			break search
+		}
+	}
+	h.traceOp("Delete", key)
+}
+
+// Clear 删除所有元素，保留当前的 bucket 数组大小
+func (h *Map[K, V]) Clear() {
+	h.writes++
+	h.count = 0
+	h.noverflow = 0
+	h.oldbuckets = nil
+	h.nevacuate = 0
+	h.sameSize = false
+	h.seed = maphash.MakeSeed()
+	h.buckets = make([]bmap[K, V], 1<<h.B)
+	h.traceOp("Clear", nil)
+}
+
+// hashGrow 分配新数组，但不搬迁任何数据，搬迁由之后的 growWork 逐步完成
+func (h *Map[K, V]) hashGrow() {
+	bigger := uint8(1)
+	if !overLoadFactor(h.count+1, h.B) {
+		bigger = 0
+		h.sameSize = true
+	}
+	h.oldbuckets = h.buckets
+	h.buckets = make([]bmap[K, V], 1<<(h.B+bigger))
+	h.B += bigger
+	h.nevacuate = 0
+	h.noverflow = 0
+}
+
+// growWork 搬迁即将被写入的 bucket 对应的旧 bucket，再额外搬迁一个，保证扩容最终能完成
+func (h *Map[K, V]) growWork(bucket uint64) {
+	h.evacuate(int(bucket & h.oldbucketmask()))
+	if h.growing() {
+		h.evacuate(h.nevacuate)
+	}
+}
+
+// evacDst 是搬迁的目的地
+type evacDst[K comparable, V any] struct {
+	b *bmap[K, V]
+	i int
+}
+
+// evacuate 把旧 bucket oldbucket（含溢出链）中的元素搬到新数组。
+// 翻倍扩容时，旧 bucket i 的元素按哈希值的第 B-1 位分到新 bucket i（X）或 i+newbit（Y）。
+func (h *Map[K, V]) evacuate(oldbucket int) {
+	b := &h.oldbuckets[oldbucket]
+	newbit := h.noldbuckets()
+	if !evacuated(b) {
+		var xy [2]evacDst[K, V]
+		xy[0].b = &h.buckets[oldbucket]
+		if !h.sameSize {
+			xy[1].b = &h.buckets[oldbucket+newbit]
+		}
+
+		for ; b != nil; b = b.overflow {
+			for i := 0; i < bucketCnt; i++ {
+				top := b.tophash[i]
+				if isEmpty(top) {
+					b.tophash[i] = evacuatedEmpty
+					continue
+				}
+				useY := 0
+				if !h.sameSize && h.hashOf(b.keys[i])&uint64(newbit) != 0 {
+					useY = 1
+				}
+				b.tophash[i] = evacuatedX + uint8(useY)
+				dst := &xy[useY]
+				if dst.i == bucketCnt {
+					dst.b = h.newoverflow(dst.b)
+					dst.i = 0
+				}
+				dst.b.tophash[dst.i] = top
+				dst.b.keys[dst.i] = b.keys[i]
+				dst.b.elems[dst.i] = b.elems[i]
+				dst.i++
+			}
+		}
+		// 旧 bucket 只保留 tophash 中的搬迁标记，断开溢出链、清掉键值，方便 GC
+		b = &h.oldbuckets[oldbucket]
+		b.overflow = nil
+		b.keys = [bucketCnt]K{}
+		b.elems = [bucketCnt]V{}
+	}
+	if oldbucket == h.nevacuate {
+		h.advanceEvacuationMark(newbit)
+	}
+}
+
+func (h *Map[K, V]) advanceEvacuationMark(newbit int) {
+	h.nevacuate++
+	// 最多往前看 1024 个，避免一次写操作花太多时间
+	stop := h.nevacuate + 1024
+	if stop > newbit {
+		stop = newbit
+	}
+	for h.nevacuate != stop && evacuated(&h.oldbuckets[h.nevacuate]) {
+		h.nevacuate++
+	}
+	if h.nevacuate == newbit {
+		// 全部搬完，丢掉旧数组
+		h.oldbuckets = nil
+		h.sameSize = false
+	}
+}
+
+// All 对应 mapiterinit/mapiternext：从随机的 bucket 和随机的槽位偏移开始遍历，
+// 所以和内置 map 一样，每次遍历的顺序都可能不同。
+// 扩容期间，还没搬迁的旧 bucket 直接在旧 bucket 里遍历，只取会落到当前新 bucket 的那部分键。
+func (h *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if h.count == 0 {
+			return
+		}
+		writes := h.writes
+		r := rand.Uint64()
+		B := h.B
+		startBucket := r & bucketMask(B)
+		offset := int(r >> (64 - bucketCntBits))
+
+		bucket := startBucket
+		for wrapped := false; !(bucket == startBucket && wrapped); {
+			var b *bmap[K, V]
+			checkBucket := -1
+			if h.growing() {
+				oldb := &h.oldbuckets[bucket&h.oldbucketmask()]
+				if !evacuated(oldb) {
+					b = oldb
+					checkBucket = int(bucket)
+				}
+			}
+			if b == nil {
+				b = &h.buckets[bucket]
+			}
+			bucket++
+			if bucket == 1<<B {
+				bucket = 0
+				wrapped = true
+			}
+
+			for ; b != nil; b = b.overflow {
+				for i := 0; i < bucketCnt; i++ {
+					offi := (i + offset) & (bucketCnt - 1)
+					if isEmpty(b.tophash[offi]) || b.tophash[offi] == evacuatedEmpty {
+						continue
+					}
+					k := b.keys[offi]
+					// 翻倍扩容时一个旧 bucket 对应两个新 bucket，各自只报告属于自己的键
+					if checkBucket >= 0 && !h.sameSize && h.hashOf(k)&bucketMask(B) != uint64(checkBucket) {
+						continue
+					}
+					if !yield(k, b.elems[offi]) {
+						return
+					}
+					if h.writes != writes {
+						panic("hmap: map modified during iteration")
+					}
+				}
+			}
+		}
+	}
+}
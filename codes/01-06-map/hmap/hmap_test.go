@@ -0,0 +1,281 @@
+package hmap
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+/*
+shell:
+	cd hmap
+	go test -v .
+	go test -run=Example -v .   # 查看扩容过程中的布局
+*/
+
+// 与内置 map 做差分测试：随机执行 Set/Delete/Get，每一步结果都要一致，
+// 并定期在任意扩容阶段比对遍历结果
+func testAgainstBuiltin(t *testing.T, m *Map[int, int], keySpace, ops int, seed uint64) {
+	r := rand.New(rand.NewPCG(seed, seed))
+	ref := make(map[int]int)
+	for op := 0; op < ops; op++ {
+		k := r.IntN(keySpace)
+		switch n := r.IntN(10); {
+		case n < 5:
+			m.Set(k, op)
+			ref[k] = op
+		case n < 8:
+			m.Delete(k)
+			delete(ref, k)
+		default:
+			got, ok := m.Get(k)
+			want, wantOK := ref[k]
+			if got != want || ok != wantOK {
+				t.Fatalf("op %d: Get(%d) = %d, %v; want %d, %v\n%s", op, k, got, ok, want, wantOK, m.Inspect())
+			}
+		}
+		if m.Len() != len(ref) {
+			t.Fatalf("op %d: Len = %d, want %d", op, m.Len(), len(ref))
+		}
+		if op%97 == 0 {
+			checkAll(t, m, ref)
+		}
+	}
+	checkAll(t, m, ref)
+}
+
+func checkAll(t *testing.T, m *Map[int, int], ref map[int]int) {
+	t.Helper()
+	seen := make(map[int]bool, len(ref))
+	for k, v := range m.All() {
+		if seen[k] {
+			t.Fatalf("key %d yielded twice\n%s", k, m.Inspect())
+		}
+		seen[k] = true
+		if want, ok := ref[k]; !ok || v != want {
+			t.Fatalf("All yielded %d=%d, want %d (present %v)", k, v, want, ok)
+		}
+	}
+	if len(seen) != len(ref) {
+		t.Fatalf("All yielded %d keys, want %d\n%s", len(seen), len(ref), m.Inspect())
+	}
+	for k, v := range ref {
+		if got, ok := m.Get(k); !ok || got != v {
+			t.Fatalf("Get(%d) = %d, %v; want %d", k, got, ok, v)
+		}
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	for seed := uint64(0); seed < 20; seed++ {
+		testAgainstBuiltin(t, New[int, int](0), 2000, 20000, seed)
+	}
+}
+
+// 哈希函数很差时所有键只落在 5 个 bucket 里，溢出链很长，tophash 也大量重复
+func TestDifferentialWeakHash(t *testing.T) {
+	weak := func(k int) uint64 { return uint64(k%5) | uint64(k%3)<<60 }
+	for seed := uint64(0); seed < 10; seed++ {
+		testAgainstBuiltin(t, NewWithHash[int, int](0, weak), 300, 5000, seed)
+	}
+}
+
+func TestStringKeys(t *testing.T) {
+	m := New[string, []int](0)
+	ref := map[string][]int{}
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprint("key-", i%300)
+		m.Set(k, []int{i})
+		ref[k] = []int{i}
+	}
+	for k, v := range ref {
+		if got, ok := m.Get(k); !ok || got[0] != v[0] {
+			t.Fatalf("Get(%q) = %v, %v", k, got, ok)
+		}
+	}
+	if m.Len() != 300 {
+		t.Fatalf("Len = %d", m.Len())
+	}
+}
+
+func TestIncrementalDoubling(t *testing.T) {
+	m := New[int, int](13) // 13 <= 6.5*2，B=1
+	if m.B != 1 {
+		t.Fatalf("B = %d, want 1", m.B)
+	}
+	for i := 0; i < 13; i++ {
+		m.Set(i, i)
+	}
+	if m.growing() {
+		t.Fatal("grew before exceeding the load factor")
+	}
+	m.Set(13, 13) // 14 > 6.5*2，翻倍
+	if m.B != 2 || len(m.buckets) != 4 {
+		t.Fatalf("B = %d after growth", m.B)
+	}
+	// 搬迁是渐进的：写操作才推进进度，读操作只是去旧 bucket 查找
+	for m.growing() {
+		for i := 0; i <= 13; i++ {
+			if v, ok := m.Get(i); !ok || v != i {
+				t.Fatalf("Get(%d) during growth = %d, %v\n%s", i, v, ok, m.Inspect())
+			}
+		}
+		m.Set(0, 0)
+	}
+	if m.nevacuate != 2 || m.oldbuckets != nil {
+		t.Fatalf("nevacuate = %d", m.nevacuate)
+	}
+}
+
+// 键按 k%4 落到 4 个 bucket 中，依次把每个 bucket 填到需要溢出桶再删空，
+// 元素不多但溢出桶越积越多，最终触发等量扩容
+func TestSameSizeGrow(t *testing.T) {
+	hash := func(k int) uint64 { return uint64(k%4) | uint64(k)<<48 }
+	m := NewWithHash[int, int](20, hash) // B=2
+	if m.B != 2 {
+		t.Fatalf("B = %d, want 2", m.B)
+	}
+	for b := 0; b < 4; b++ {
+		for j := 0; j < 9; j++ {
+			m.Set(b+4*j, j)
+		}
+		if b == 3 {
+			break // 保留最后一个 bucket 中的元素
+		}
+		for j := 0; j < 9; j++ {
+			m.Delete(b + 4*j)
+		}
+	}
+	if m.noverflow != 4 || m.growing() {
+		t.Fatalf("noverflow = %d growing = %v", m.noverflow, m.growing())
+	}
+
+	m.Set(100, 100) // 新键，溢出桶数 >= 2^B，等量扩容
+	if !m.growing() || !m.sameSize || m.B != 2 {
+		t.Fatalf("expected a same-size grow\n%s", m.Inspect())
+	}
+	if !strings.Contains(m.Inspect(), "growing: same-size") {
+		t.Fatalf("inspector does not show the grow:\n%s", m.Inspect())
+	}
+	for i := 0; m.growing(); i++ {
+		m.Set(200+i*4, i) // 落在 bucket 0，推进搬迁
+	}
+	// 整理后只剩 bucket 3 的 9 个元素需要一个溢出桶
+	if m.noverflow != 1 {
+		t.Fatalf("noverflow after compaction = %d\n%s", m.noverflow, m.Inspect())
+	}
+	for j := 0; j < 9; j++ {
+		if v, ok := m.Get(3 + 4*j); !ok || v != j {
+			t.Fatalf("Get(%d) = %d, %v", 3+4*j, v, ok)
+		}
+	}
+}
+
+func TestEmptyRestMarks(t *testing.T) {
+	hash := func(k int) uint64 { return uint64(k) << 56 } // 全部落在 bucket 0
+	m := NewWithHash[int, int](0, hash)
+	for i := 10; i < 14; i++ {
+		m.Set(i, i)
+	}
+	m.Delete(11) // 后面还有元素，只能标记为 emptyOne
+	if got := m.buckets[0].tophash; got[1] != emptyOne {
+		t.Fatalf("tophash = %v", got)
+	}
+	m.Delete(13) // 末尾的元素：自己变成 emptyRest
+	m.Delete(12) // 12 变成 emptyRest，并把前面的 emptyOne（11）一起改掉
+	if got := m.buckets[0].tophash; got[1] != emptyRest || got[2] != emptyRest || got[0] == emptyRest {
+		t.Fatalf("tophash = %v", got)
+	}
+}
+
+func TestModifyDuringIterationPanics(t *testing.T) {
+	m := New[int, int](0)
+	for i := 0; i < 10; i++ {
+		m.Set(i, i)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	for k := range m.All() {
+		m.Delete(k)
+	}
+}
+
+func TestIterationOrderIsRandomized(t *testing.T) {
+	m := New[int, int](0)
+	for i := 0; i < 20; i++ {
+		m.Set(i, i)
+	}
+	first := func() int {
+		for k := range m.All() {
+			return k
+		}
+		return -1
+	}
+	f := first()
+	for i := 0; i < 100; i++ {
+		if first() != f {
+			return
+		}
+	}
+	t.Fatal("iteration always starts from the same key")
+}
+
+// tophash 等于键本身，bucket 下标等于键的低位，方便对照输出
+func identity(k int) uint64 { return uint64(k)<<56 | uint64(k) }
+
+func ExampleMap_SetTrace() {
+	m := NewWithHash[int, string](26, identity) // B=2
+	for k := 32; k < 58; k++ {
+		m.Set(k, "v")
+	}
+	var sb strings.Builder
+	m.SetTrace(&sb)
+	m.Set(58, "v") // 第 27 个元素，翻倍扩容；搬迁 58 所在的旧 bucket 2，以及 nevacuate 指向的旧 bucket 0
+	m.Set(59, "v") // 搬迁旧 bucket 3 和 1，扩容结束
+	m.Delete(35)
+	fmt.Print(sb.String())
+	// Output:
+	// == Set(58)
+	// count=27 B=3 buckets=8 noverflow=0 load=3.38
+	// growing: double, oldbuckets=4 nevacuate=1
+	// buckets:
+	//   [0] 20:32 28:40 30:48 38:56 - - - -
+	//   [1] - - - - - - - -
+	//   [2] 22:34 2a:42 32:50 3a:58 - - - -
+	//   [3] - - - - - - - -
+	//   [4] 24:36 2c:44 34:52 - - - - -
+	//   [5] - - - - - - - -
+	//   [6] 26:38 2e:46 36:54 - - - - -
+	//   [7] - - - - - - - -
+	// oldbuckets:
+	//   [0] X Y X Y X Y X E
+	//   [1] 21:33 25:37 29:41 2d:45 31:49 35:53 39:57 -
+	//   [2] X Y X Y X Y E E
+	//   [3] 23:35 27:39 2b:43 2f:47 33:51 37:55 - -
+	// == Set(59)
+	// count=28 B=3 buckets=8 noverflow=0 load=3.50
+	// buckets:
+	//   [0] 20:32 28:40 30:48 38:56 - - - -
+	//   [1] 21:33 29:41 31:49 39:57 - - - -
+	//   [2] 22:34 2a:42 32:50 3a:58 - - - -
+	//   [3] 23:35 2b:43 33:51 3b:59 - - - -
+	//   [4] 24:36 2c:44 34:52 - - - - -
+	//   [5] 25:37 2d:45 35:53 - - - - -
+	//   [6] 26:38 2e:46 36:54 - - - - -
+	//   [7] 27:39 2f:47 37:55 - - - - -
+	// == Delete(35)
+	// count=27 B=3 buckets=8 noverflow=0 load=3.38
+	// buckets:
+	//   [0] 20:32 28:40 30:48 38:56 - - - -
+	//   [1] 21:33 29:41 31:49 39:57 - - - -
+	//   [2] 22:34 2a:42 32:50 3a:58 - - - -
+	//   [3] x 2b:43 33:51 3b:59 - - - -
+	//   [4] 24:36 2c:44 34:52 - - - - -
+	//   [5] 25:37 2d:45 35:53 - - - - -
+	//   [6] 26:38 2e:46 36:54 - - - - -
+	//   [7] 27:39 2f:47 37:55 - - - - -
+}
@@ -0,0 +1,83 @@
+package hmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Inspect 返回当前的内部布局，例如：
+//
+//	count=9 B=1 buckets=2 noverflow=0 load=4.50
+//	growing: double, oldbuckets=1 nevacuate=0
+//	buckets:
+//	  [0] a3:k1 5f:k2 - - - - - -
+//	  [1] - - - - - - - -
+//	oldbuckets:
+//	  [0] 9c:k3 ... -> 7e:k9 - - - - - - -
+//
+// 每个槽位显示为 tophash:键，或者空槽位的状态：
+//
+//	'-' emptyRest     'x' emptyOne
+//	'X' evacuatedX    'Y' evacuatedY    'E' evacuatedEmpty
+//
+// "->" 后面是溢出桶。
+func (h *Map[K, V]) Inspect() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "count=%d B=%d buckets=%d noverflow=%d load=%.2f\n",
+		h.count, h.B, len(h.buckets), h.noverflow, float64(h.count)/float64(len(h.buckets)))
+	if h.growing() {
+		kind := "double"
+		if h.sameSize {
+			kind = "same-size"
+		}
+		fmt.Fprintf(&sb, "growing: %s, oldbuckets=%d nevacuate=%d\n", kind, len(h.oldbuckets), h.nevacuate)
+	}
+	sb.WriteString("buckets:\n")
+	writeBuckets(&sb, h.buckets)
+	if h.growing() {
+		sb.WriteString("oldbuckets:\n")
+		writeBuckets(&sb, h.oldbuckets)
+	}
+	return sb.String()
+}
+
+func writeBuckets[K comparable, V any](sb *strings.Builder, buckets []bmap[K, V]) {
+	for i := range buckets {
+		fmt.Fprintf(sb, "  [%d]", i)
+		for b := &buckets[i]; b != nil; b = b.overflow {
+			if b != &buckets[i] {
+				sb.WriteString(" ->")
+			}
+			for j := 0; j < bucketCnt; j++ {
+				sb.WriteByte(' ')
+				switch top := b.tophash[j]; top {
+				case emptyRest:
+					sb.WriteByte('-')
+				case emptyOne:
+					sb.WriteByte('x')
+				case evacuatedX:
+					sb.WriteByte('X')
+				case evacuatedY:
+					sb.WriteByte('Y')
+				case evacuatedEmpty:
+					sb.WriteByte('E')
+				default:
+					fmt.Fprintf(sb, "%02x:%v", top, b.keys[j])
+				}
+			}
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+func (h *Map[K, V]) traceOp(op string, key any) {
+	if h.trace == nil {
+		return
+	}
+	if key == nil {
+		fmt.Fprintf(h.trace, "== %s\n", op)
+	} else {
+		fmt.Fprintf(h.trace, "== %s(%v)\n", op, key)
+	}
+	fmt.Fprint(h.trace, h.Inspect())
+}
@@ -0,0 +1,80 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Bytes 输出 v。没有被修改过的节点直接写出原文（保留原来的空白、转义和数字写法），
+// 被修改过的节点以紧凑格式重新编码，其中未修改的子节点仍然写原文。
+func (v *Value) Bytes() []byte {
+	var buf bytes.Buffer
+	v.encode(&buf)
+	return buf.Bytes()
+}
+
+// MarshalJSON 与 Bytes 相同。注意 encoding/json 会对 MarshalJSON 的结果再做一次 compact，
+// 原文中的空白会被去掉，数字写法和转义仍然保留；需要逐字节一致时用 Bytes。
+func (v *Value) MarshalJSON() ([]byte, error) {
+	return v.Bytes(), nil
+}
+
+// String 返回 v 的 JSON 文本
+func (v *Value) String() string {
+	var buf bytes.Buffer
+	v.encode(&buf)
+	return buf.String()
+}
+
+func (v *Value) encode(buf *bytes.Buffer) {
+	if v.raw != nil {
+		buf.Write(v.raw)
+		return
+	}
+	switch v.kind {
+	case Null:
+		buf.WriteString("null")
+	case Bool:
+		if v.b {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case Number:
+		buf.WriteString(v.text)
+	case String:
+		writeString(buf, v.text)
+	case Array:
+		buf.WriteByte('[')
+		for i, e := range v.elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			e.encode(buf)
+		}
+		buf.WriteByte(']')
+	case Object:
+		buf.WriteByte('{')
+		for i, m := range v.members {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if m.rawKey != nil {
+				buf.Write(m.rawKey)
+			} else {
+				writeString(buf, m.key)
+			}
+			buf.WriteByte(':')
+			m.value.encode(buf)
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// writeString 写出带引号的字符串。不转义 <、>、&，与原文中常见的写法一致
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)               // string 的编码不会失败
+	buf.Truncate(buf.Len() - 1) // 去掉 Encode 追加的换行
+}
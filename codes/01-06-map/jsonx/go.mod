@@ -0,0 +1,3 @@
+module jsonx
+
+go 1.23
@@ -0,0 +1,293 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*
+shell:
+	cd jsonx
+	go test -v .
+*/
+
+const doc = `{
+  "id": 9007199254740993,
+  "big": 123456789012345678901234567890,
+  "max_u64": 18446744073709551615,
+  "price": 0.10,
+  "ratio": 2.5e1,
+  "name": "café \"x\"",
+  "a": {"b": [1, 2, {"c": true, "d": null}], "x.y": "dotted"},
+  "dup": 1, "dup": 2
+}`
+
+func mustParse(t *testing.T, s string) *Value {
+	t.Helper()
+	v, err := Parse([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// f5 的问题：经过 float64 之后，大整数和小数的原文都丢了
+func TestNumbersAreLossless(t *testing.T) {
+	var viaAny map[string]any
+	json.Unmarshal([]byte(doc), &viaAny)
+	if viaAny["id"].(float64) != 9007199254740992 {
+		t.Fatal("expected float64 to round 2^53+1 down")
+	}
+
+	v := mustParse(t, doc)
+	id, _ := v.Get("id")
+	if n, err := id.Int64(); err != nil || n != 9007199254740993 {
+		t.Fatalf("id = %v, %v", n, err)
+	}
+
+	maxU, _ := v.Get("max_u64")
+	if n, err := maxU.Uint64(); err != nil || n != math.MaxUint64 {
+		t.Fatalf("max_u64 = %v, %v", n, err)
+	}
+	if _, err := maxU.Int64(); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Fatalf("Int64 on max_u64: %v", err)
+	}
+
+	big, _ := v.Get("big")
+	if b, err := big.BigInt(); err != nil || b.String() != "123456789012345678901234567890" {
+		t.Fatalf("big = %v, %v", b, err)
+	}
+
+	price, _ := v.Get("price")
+	if s, _ := price.NumberText(); s != "0.10" {
+		t.Fatalf("price text = %q", s)
+	}
+	if r, _ := price.Rat(); r.String() != "1/10" {
+		t.Fatalf("price rat = %v", r)
+	}
+	if _, err := price.Int64(); err == nil {
+		t.Fatal("0.10 converted to int64")
+	}
+
+	// 值为整数的小数和指数写法也能取整数
+	ratio, _ := v.Get("ratio")
+	if n, err := ratio.Int64(); err != nil || n != 25 {
+		t.Fatalf("ratio = %v, %v", n, err)
+	}
+	if f, err := ratio.Float64(); err != nil || f != 25 {
+		t.Fatalf("ratio float = %v, %v", f, err)
+	}
+	huge, _ := NewNumber("1e400")
+	if _, err := huge.Float64(); err == nil {
+		t.Fatal("1e400 fits in float64?")
+	}
+}
+
+func TestPathQueries(t *testing.T) {
+	v := mustParse(t, doc)
+	c, err := v.Get("a.b[2].c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := c.Bool(); err != nil || !b {
+		t.Fatalf("a.b[2].c = %v, %v", b, err)
+	}
+	if d, _ := v.Get("a.b[2].d"); !d.IsNull() {
+		t.Fatal("a.b[2].d is not null")
+	}
+	if s, _ := v.Get(`a["x.y"]`); s == nil {
+		t.Fatal("quoted key not found")
+	} else if str, _ := s.Str(); str != "dotted" {
+		t.Fatalf("a[\"x.y\"] = %q", str)
+	}
+	name, _ := v.Get("name")
+	if s, _ := name.Str(); s != `café "x"` {
+		t.Fatalf("name = %q", s)
+	}
+	// 重复的键取最后一个
+	if dup, _ := v.Get("dup"); dup.String() != "2" {
+		t.Fatalf("dup = %s", dup)
+	}
+	if root, _ := v.Get(""); root != v {
+		t.Fatal("empty path is not the root")
+	}
+
+	_, err = v.Get("a.b[5]")
+	var pe *PathError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &pe) || pe.Path != "a.b[5]" {
+		t.Fatalf("missing index: %v", err)
+	}
+	_, err = v.Get("a.b.c")
+	var ke *KindError
+	if !errors.As(err, &ke) || ke.Want != Object || ke.Got != Array {
+		t.Fatalf("key on array: %v", err)
+	}
+	if _, err := name.Int64(); !errors.As(err, &ke) || ke.Got != String {
+		t.Fatalf("Int64 on string: %v", err)
+	}
+	for _, bad := range []string{"a..b", ".a", "a.", "a[x]", "a[-1]", "a[1", `a["x]`} {
+		if _, err := v.Get(bad); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %v, want a syntax error", bad, err)
+		}
+	}
+}
+
+// 未修改的文档原样输出；修改后只有被改动的路径重新编码
+func TestByteForByteReencoding(t *testing.T) {
+	v := mustParse(t, doc)
+	out := v.Bytes()
+	if string(out) != strings.TrimSpace(doc) {
+		t.Fatalf("untouched document changed:\n%s", out)
+	}
+
+	if err := v.Set("a.b[0]", NewInt(100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("a.b[3]", NewString("<new>")); err != nil { // 追加
+		t.Fatal(err)
+	}
+	if err := v.Set("a.added", NewArray(NewBool(false), NewNull())); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Delete("dup"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 根和 a 被修改，重新编码为紧凑格式；其它成员保留原文（缩进、é、0.10、2.5e1）
+	want := `{"id":9007199254740993,"big":123456789012345678901234567890,"max_u64":18446744073709551615,` +
+		`"price":0.10,"ratio":2.5e1,"name":"café \"x\"",` +
+		`"a":{"b":[100,2,{"c": true, "d": null},"<new>"],"x.y":"dotted","added":[false,null]}}`
+	if got := v.String(); got != want {
+		t.Fatalf("re-encoded:\n got %s\nwant %s", got, want)
+	}
+	// 输出仍然是合法 JSON
+	if !json.Valid([]byte(v.String())) {
+		t.Fatal("invalid JSON after mutation")
+	}
+
+	untouched, _ := v.Get("a.b[2]")
+	if untouched.String() != `{"c": true, "d": null}` {
+		t.Fatalf("untouched subtree = %s", untouched)
+	}
+}
+
+func TestMutationErrors(t *testing.T) {
+	v := mustParse(t, `{"arr":[1],"s":"x"}`)
+	if err := v.Set("arr[5]", NewInt(1)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Set past end: %v", err)
+	}
+	if err := v.Set("s.k", NewInt(1)); err == nil {
+		t.Fatal("Set key on string succeeded")
+	}
+	if err := v.Set("missing.k", NewInt(1)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Set under missing parent: %v", err)
+	}
+	if err := v.Delete("nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete missing: %v", err)
+	}
+	if err := v.Delete("arr[0]"); err != nil || v.String() != `{"arr":[],"s":"x"}` {
+		t.Fatalf("Delete arr[0]: %v, %s", err, v)
+	}
+	if err := v.Set("", NewNull()); err == nil {
+		t.Fatal("Set root succeeded")
+	}
+}
+
+// 把一棵树的节点挂到另一棵树上时会拷贝，修改其中一棵不影响另一棵
+func TestMoveBetweenTrees(t *testing.T) {
+	src := mustParse(t, `{"user":{"name":"alice","tags":["a"]}}`)
+	dst := mustParse(t, `{}`)
+	user, _ := src.Get("user")
+	if err := dst.Set("copy", user); err != nil {
+		t.Fatal(err)
+	}
+	dst.Set("copy.name", NewString("bob"))
+	if name, _ := src.Get("user.name"); name.String() != `"alice"` {
+		t.Fatalf("source modified: %s", src)
+	}
+	if dst.String() != `{"copy":{"name":"bob","tags":["a"]}}` {
+		t.Fatalf("dst = %s", dst)
+	}
+	if src.String() != `{"user":{"name":"alice","tags":["a"]}}` {
+		t.Fatalf("src = %s", src)
+	}
+}
+
+// 把节点挂到它自己或者它的子孙下面：挂上去的是拷贝，不会形成环
+func TestAttachToOwnSubtree(t *testing.T) {
+	root := mustParse(t, `{"a":{}}`)
+	if err := root.Set("a.b", root); err != nil {
+		t.Fatal(err)
+	}
+	if root.String() != `{"a":{"b":{"a":{}}}}` {
+		t.Fatalf("root = %s", root)
+	}
+
+	a, _ := root.Get("a")
+	if err := a.SetKey("self", a); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != `{"b":{"a":{}},"self":{"b":{"a":{}}}}` {
+		t.Fatalf("a = %s", a)
+	}
+
+	arr := mustParse(t, `[[]]`)
+	if err := arr.Set("[0][0]", arr); err != nil {
+		t.Fatal(err)
+	}
+	if arr.String() != `[[[[]]]]` {
+		t.Fatalf("arr = %s", arr)
+	}
+}
+
+func TestStructField(t *testing.T) {
+	var msg struct {
+		Type    string `json:"type"`
+		Payload *Value `json:"payload"`
+	}
+	in := `{"type":"order","payload":{ "amount": 12.50, "id": 18446744073709551615 }}`
+	if err := json.Unmarshal([]byte(in), &msg); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := msg.Payload.Get("id")
+	if n, err := id.Uint64(); err != nil || n != math.MaxUint64 {
+		t.Fatalf("id = %v, %v", n, err)
+	}
+	if msg.Payload.String() != `{ "amount": 12.50, "id": 18446744073709551615 }` {
+		t.Fatalf("payload = %s", msg.Payload)
+	}
+	// encoding/json 会去掉空白，但数字原文不变
+	out, _ := json.Marshal(msg)
+	if in := `{"type":"order","payload":{"amount":12.50,"id":18446744073709551615}}`; string(out) != in {
+		t.Fatalf("round trip:\n got %s\nwant %s", out, in)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for _, bad := range []string{
+		``, `{`, `[1,]`, `{"a" 1}`, `{"a":1,}`, `01`, `1.`, `-`, `1e`, `tru`, `"abc`, "\"a\x01\"",
+		`{"a":1} x`, `[1 2]`, `"\x"`, `nul`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+		if json.Valid([]byte(bad)) {
+			t.Errorf("%q is valid JSON, test case is wrong", bad)
+		}
+	}
+	var se *SyntaxError
+	if _, err := Parse([]byte(`[1, x]`)); !errors.As(err, &se) || se.Offset != 4 {
+		t.Fatalf("err = %v", err)
+	}
+	for _, good := range []string{`0`, `-0.0e+0`, ` [ ] `, `{}`, `"😀"`, `[[[]]]`, `1E-2`} {
+		if _, err := Parse([]byte(good)); err != nil {
+			t.Errorf("Parse(%q): %v", good, err)
+		}
+	}
+	if _, err := Parse([]byte(strings.Repeat("[", maxDepth+2))); err == nil {
+		t.Error("deep nesting accepted")
+	}
+}
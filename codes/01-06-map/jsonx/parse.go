@@ -0,0 +1,277 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Parse 解析一个完整的 JSON 文档。data 会被复制，调用方之后可以修改它
+func Parse(data []byte) (*Value, error) {
+	p := &parser{data: append([]byte(nil), data...)}
+	p.skipSpace()
+	v, err := p.value(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return nil, p.errorf("unexpected %q after top-level value", p.data[p.pos])
+	}
+	return v, nil
+}
+
+// UnmarshalJSON 让 Value 可以作为结构体字段，由 encoding/json 调用
+func (v *Value) UnmarshalJSON(data []byte) error {
+	pv, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	*v = *pv
+	for _, e := range v.elems {
+		e.parent = v
+	}
+	for _, m := range v.members {
+		m.value.parent = v
+	}
+	return nil
+}
+
+// 嵌套层数上限，防止恶意输入耗尽栈
+const maxDepth = 10000
+
+type parser struct {
+	data []byte
+	pos  int
+}
+
+type SyntaxError struct {
+	Offset int
+	msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jsonx: syntax error at offset %d: %s", e.Offset, e.msg)
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: p.pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) value(depth int) (*Value, error) {
+	if depth > maxDepth {
+		return nil, p.errorf("exceeded max depth %d", maxDepth)
+	}
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	start := p.pos
+	var v *Value
+	var err error
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		v, err = p.object(depth)
+	case c == '[':
+		v, err = p.array(depth)
+	case c == '"':
+		var s string
+		s, err = p.str()
+		v = &Value{kind: String, text: s}
+	case c == '-' || c >= '0' && c <= '9':
+		v, err = p.number()
+	default:
+		v, err = p.literal()
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.raw = p.data[start:p.pos:p.pos]
+	return v, nil
+}
+
+func (p *parser) literal() (*Value, error) {
+	for _, lit := range []struct {
+		text string
+		v    Value
+	}{
+		{"null", Value{kind: Null}},
+		{"true", Value{kind: Bool, b: true}},
+		{"false", Value{kind: Bool}},
+	} {
+		if len(p.data)-p.pos >= len(lit.text) && string(p.data[p.pos:p.pos+len(lit.text)]) == lit.text {
+			p.pos += len(lit.text)
+			v := lit.v
+			return &v, nil
+		}
+	}
+	return nil, p.errorf("invalid character %q", p.data[p.pos])
+}
+
+func (p *parser) number() (*Value, error) {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	text := string(p.data[start:p.pos])
+	if !validNumber(text) {
+		p.pos = start
+		return nil, p.errorf("invalid number %q", text)
+	}
+	return &Value{kind: Number, text: text}, nil
+}
+
+// str 读取一个字符串字面量。先扫描出结束引号，再交给 encoding/json 处理转义
+func (p *parser) str() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == '\\':
+			p.pos += 2
+		case c == '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal(p.data[start:p.pos], &s); err != nil {
+				p.pos = start
+				return "", p.errorf("invalid string: %v", err)
+			}
+			return s, nil
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		default:
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) array(depth int) (*Value, error) {
+	v := &Value{kind: Array}
+	p.pos++ // '['
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == ']' {
+		p.pos++
+		return v, nil
+	}
+	for {
+		p.skipSpace()
+		e, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		e.parent = v
+		v.elems = append(v.elems, e)
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input in array")
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return v, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in array, got %q", p.data[p.pos])
+		}
+	}
+}
+
+func (p *parser) object(depth int) (*Value, error) {
+	v := &Value{kind: Object}
+	p.pos++ // '{'
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+		return v, nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected object key")
+		}
+		keyStart := p.pos
+		key, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		rawKey := p.data[keyStart:p.pos:p.pos]
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.pos++
+		p.skipSpace()
+		e, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		e.parent = v
+		v.members = append(v.members, member{rawKey: rawKey, key: key, value: e})
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input in object")
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return v, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in object, got %q", p.data[p.pos])
+		}
+	}
+}
+
+// validNumber 按 JSON 语法检查数字：-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+func validNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
@@ -0,0 +1,178 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 路径语法：
+//
+//	a.b.c          对象成员
+//	a[2]           数组下标
+//	a["x.y"]       键里有 '.' 或 '[' 时用带引号的写法，引号内按 JSON 字符串转义
+//	""             空路径表示当前节点
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parsePath(path string) ([]step, error) {
+	var steps []step
+	i := 0
+	for i < len(path) {
+		switch {
+		case path[i] == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonx: unclosed '[' in path %q", path)
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// 带引号的键里可能有 ']'，要按字符串规则找结束引号
+				n, key, err := quotedKey(path[i+1:])
+				if err != nil || i+1+n >= len(path) || path[i+1+n] != ']' {
+					return nil, fmt.Errorf("jsonx: bad quoted key in path %q", path)
+				}
+				steps = append(steps, step{key: key})
+				i += n + 2
+				break
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("jsonx: bad index %q in path %q", inner, path)
+			}
+			steps = append(steps, step{index: idx, isIndex: true})
+			i += end + 1
+		case path[i] == '.' && i > 0:
+			i++
+			fallthrough
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("jsonx: empty key in path %q", path)
+			}
+			steps = append(steps, step{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+// quotedKey 解析 s 开头的 JSON 字符串，返回它占用的字节数和解码后的内容
+func quotedKey(s string) (int, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			var key string
+			err := json.Unmarshal([]byte(s[:i+1]), &key)
+			return i + 1, key, err
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated string")
+}
+
+func (s step) String() string {
+	if s.isIndex {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	if strings.ContainsAny(s.key, ".[]\"") || s.key == "" {
+		b, _ := json.Marshal(s.key)
+		return "[" + string(b) + "]"
+	}
+	return "." + s.key
+}
+
+func pathString(steps []step) string {
+	var b strings.Builder
+	for _, s := range steps {
+		b.WriteString(s.String())
+	}
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+func (v *Value) walk(steps []step) (*Value, error) {
+	cur := v
+	for i, s := range steps {
+		var next *Value
+		var err error
+		if s.isIndex {
+			next, err = cur.Index(s.index)
+		} else {
+			next, err = cur.Key(s.key)
+		}
+		if err != nil {
+			return nil, &PathError{Path: pathString(steps[:i+1]), Err: err}
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// Get 按路径查找节点，比如 Get("a.b[2].c")。
+// 找不到时返回的错误满足 errors.Is(err, ErrNotFound)；中间节点类型不对时可以用 errors.As 取出 *KindError。
+func (v *Value) Get(path string) (*Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return v.walk(steps)
+}
+
+// Set 按路径设置节点：最后一步是键时设置或新增对象成员，是下标时替换数组元素，下标等于长度时追加。
+// 中间的节点必须已经存在。
+func (v *Value) Set(path string, value *Value) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("jsonx: cannot Set the root")
+	}
+	parent, err := v.walk(steps[:len(steps)-1])
+	if err != nil {
+		return err
+	}
+	last := steps[len(steps)-1]
+	if last.isIndex {
+		err = parent.SetIndex(last.index, value)
+	} else {
+		err = parent.SetKey(last.key, value)
+	}
+	if err != nil {
+		return &PathError{Path: pathString(steps), Err: err}
+	}
+	return nil
+}
+
+// Delete 按路径删除对象成员或数组元素
+func (v *Value) Delete(path string) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("jsonx: cannot Delete the root")
+	}
+	parent, err := v.walk(steps[:len(steps)-1])
+	if err != nil {
+		return err
+	}
+	last := steps[len(steps)-1]
+	if last.isIndex {
+		err = parent.RemoveIndex(last.index)
+	} else {
+		err = parent.DeleteKey(last.key)
+	}
+	if err != nil {
+		return &PathError{Path: pathString(steps), Err: err}
+	}
+	return nil
+}
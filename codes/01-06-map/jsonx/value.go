@@ -0,0 +1,395 @@
+// 无损的动态 JSON
+//
+// demo.go 的 f5 里，JSON 解析到 map[string]interface{} 后数字都成了 float64：
+// 超过 2^53 的整数会丢精度，"25.0" 和 "25" 也分不清；改用 UseNumber 后又要到处写类型断言。
+// jsonx.Value 是一棵保留原文的语法树：
+//
+//	数字保存原始文本，按需转换成 int64、uint64、*big.Int、*big.Rat 或 float64，溢出时返回错误
+//	对象保留键的顺序（包括重复的键）
+//	Get("a.b[2].c") 按路径查询，Set、Delete 按路径修改
+//	每个节点记住自己在输入中的原文，没有被修改的子树原样输出，和输入逐字节相同
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+)
+
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Null:
+		return "null"
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+var ErrNotFound = errors.New("jsonx: not found")
+
+// KindError 表示值的类型与访问方法要求的不符
+type KindError struct {
+	Want, Got Kind
+}
+
+func (e *KindError) Error() string {
+	return fmt.Sprintf("jsonx: value is %s, not %s", e.Got, e.Want)
+}
+
+// PathError 记录出错的路径
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string { return fmt.Sprintf("jsonx: path %q: %v", e.Path, e.Err) }
+func (e *PathError) Unwrap() error { return e.Err }
+
+type member struct {
+	rawKey []byte // 键在输入中的原文（含引号），新加的键为 nil
+	key    string
+	value  *Value
+}
+
+// Value 是 JSON 树中的一个节点
+type Value struct {
+	kind   Kind
+	raw    []byte // 节点在输入中的原文；节点或其子孙被修改后置为 nil
+	parent *Value
+
+	b       bool
+	text    string // Number 的原始文本，或 String 解码后的内容
+	elems   []*Value
+	members []member
+}
+
+func (v *Value) Kind() Kind { return v.kind }
+
+func NewNull() *Value           { return &Value{kind: Null} }
+func NewBool(b bool) *Value     { return &Value{kind: Bool, b: b} }
+func NewString(s string) *Value { return &Value{kind: String, text: s} }
+func NewInt(n int64) *Value     { return &Value{kind: Number, text: strconv.FormatInt(n, 10)} }
+func NewUint(n uint64) *Value   { return &Value{kind: Number, text: strconv.FormatUint(n, 10)} }
+
+// NewFloat 按 encoding/json 的格式输出 f，NaN 和 Inf 不是合法的 JSON 数字，会 panic
+func NewFloat(f float64) *Value {
+	b, err := json.Marshal(f)
+	if err != nil {
+		panic("jsonx: " + err.Error())
+	}
+	return &Value{kind: Number, text: string(b)}
+}
+
+// NewNumber 用原始文本创建数字，比如 "3.14159265358979323846" 或 "1e400"，文本必须符合 JSON 数字语法
+func NewNumber(text string) (*Value, error) {
+	if !validNumber(text) {
+		return nil, fmt.Errorf("jsonx: invalid number %q", text)
+	}
+	return &Value{kind: Number, text: text}, nil
+}
+
+func NewArray(elems ...*Value) *Value {
+	v := &Value{kind: Array}
+	for _, e := range elems {
+		v.elems = append(v.elems, v.adopt(e))
+	}
+	return v
+}
+
+func NewObject() *Value { return &Value{kind: Object} }
+
+// Clone 深拷贝 v，结果没有父节点
+func (v *Value) Clone() *Value {
+	c := *v
+	c.parent = nil
+	c.elems = nil
+	c.members = nil
+	for _, e := range v.elems {
+		ce := e.Clone()
+		ce.parent = &c
+		c.elems = append(c.elems, ce)
+	}
+	for _, m := range v.members {
+		cm := m.value.Clone()
+		cm.parent = &c
+		c.members = append(c.members, member{m.rawKey, m.key, cm})
+	}
+	return &c
+}
+
+// adopt 把 child 挂到 v 下。child 已经属于别的树时先拷贝一份，避免两棵树共享节点；
+// child 是 v 自己或者 v 的祖先时也拷贝一份，否则树里会出现环，输出时无限递归
+func (v *Value) adopt(child *Value) *Value {
+	if child.parent != nil || child.isAncestorOf(v) {
+		child = child.Clone()
+	}
+	child.parent = v
+	return child
+}
+
+// isAncestorOf 报告 v 是否是 n 自己或者 n 的祖先
+func (v *Value) isAncestorOf(n *Value) bool {
+	for ; n != nil; n = n.parent {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
+
+// touch 标记 v 及其所有祖先已被修改，输出时不能再使用原文
+func (v *Value) touch() {
+	for n := v; n != nil && n.raw != nil; n = n.parent {
+		n.raw = nil
+	}
+}
+
+func (v *Value) IsNull() bool { return v.kind == Null }
+
+func (v *Value) Bool() (bool, error) {
+	if v.kind != Bool {
+		return false, &KindError{Bool, v.kind}
+	}
+	return v.b, nil
+}
+
+func (v *Value) Str() (string, error) {
+	if v.kind != String {
+		return "", &KindError{String, v.kind}
+	}
+	return v.text, nil
+}
+
+// NumberText 返回数字的原始文本，与 json.Number 相同
+func (v *Value) NumberText() (string, error) {
+	if v.kind != Number {
+		return "", &KindError{Number, v.kind}
+	}
+	return v.text, nil
+}
+
+// Int64 把数字转换为 int64。"25.0"、"2.5e1" 这类值为整数的写法也可以转换；
+// 有小数部分或超出范围时返回错误，不会像 float64 那样悄悄截断。
+func (v *Value) Int64() (int64, error) {
+	if v.kind != Number {
+		return 0, &KindError{Number, v.kind}
+	}
+	if n, err := strconv.ParseInt(v.text, 10, 64); err == nil {
+		return n, nil
+	}
+	i, err := v.integer()
+	if err != nil {
+		return 0, err
+	}
+	if !i.IsInt64() {
+		return 0, fmt.Errorf("jsonx: %s overflows int64", v.text)
+	}
+	return i.Int64(), nil
+}
+
+func (v *Value) Uint64() (uint64, error) {
+	if v.kind != Number {
+		return 0, &KindError{Number, v.kind}
+	}
+	if n, err := strconv.ParseUint(v.text, 10, 64); err == nil {
+		return n, nil
+	}
+	i, err := v.integer()
+	if err != nil {
+		return 0, err
+	}
+	if !i.IsUint64() {
+		return 0, fmt.Errorf("jsonx: %s overflows uint64", v.text)
+	}
+	return i.Uint64(), nil
+}
+
+// BigInt 返回任意精度的整数，数字有小数部分时返回错误
+func (v *Value) BigInt() (*big.Int, error) {
+	if v.kind != Number {
+		return nil, &KindError{Number, v.kind}
+	}
+	return v.integer()
+}
+
+func (v *Value) integer() (*big.Int, error) {
+	if i, ok := new(big.Int).SetString(v.text, 10); ok {
+		return i, nil
+	}
+	r, err := v.Rat()
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("jsonx: %s is not an integer", v.text)
+	}
+	return r.Num(), nil
+}
+
+// Rat 返回数字的精确值，十进制小数不会有二进制浮点误差
+func (v *Value) Rat() (*big.Rat, error) {
+	if v.kind != Number {
+		return nil, &KindError{Number, v.kind}
+	}
+	r, ok := new(big.Rat).SetString(v.text)
+	if !ok {
+		return nil, fmt.Errorf("jsonx: cannot convert %s to a rational", v.text)
+	}
+	return r, nil
+}
+
+// Float64 转换为最接近的 float64，超出范围时返回错误
+func (v *Value) Float64() (float64, error) {
+	if v.kind != Number {
+		return 0, &KindError{Number, v.kind}
+	}
+	f, err := strconv.ParseFloat(v.text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("jsonx: %s: %w", v.text, errors.Unwrap(err))
+	}
+	return f, nil
+}
+
+// Len 返回数组的元素个数或对象的成员个数，其它类型返回 0
+func (v *Value) Len() int {
+	switch v.kind {
+	case Array:
+		return len(v.elems)
+	case Object:
+		return len(v.members)
+	}
+	return 0
+}
+
+// Index 返回数组的第 i 个元素
+func (v *Value) Index(i int) (*Value, error) {
+	if v.kind != Array {
+		return nil, &KindError{Array, v.kind}
+	}
+	if i < 0 || i >= len(v.elems) {
+		return nil, ErrNotFound
+	}
+	return v.elems[i], nil
+}
+
+// Key 返回对象成员的值。有重复的键时返回最后一个，与 encoding/json 一致
+func (v *Value) Key(key string) (*Value, error) {
+	if v.kind != Object {
+		return nil, &KindError{Object, v.kind}
+	}
+	if i := v.lookup(key); i >= 0 {
+		return v.members[i].value, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (v *Value) lookup(key string) int {
+	for i := len(v.members) - 1; i >= 0; i-- {
+		if v.members[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Keys 按原文顺序返回对象的键
+func (v *Value) Keys() []string {
+	keys := make([]string, len(v.members))
+	for i, m := range v.members {
+		keys[i] = m.key
+	}
+	return keys
+}
+
+// SetKey 设置对象成员，键已存在时原地替换（有重复时替换最后一个），否则追加到末尾
+func (v *Value) SetKey(key string, value *Value) error {
+	if v.kind != Object {
+		return &KindError{Object, v.kind}
+	}
+	value = v.adopt(value)
+	if i := v.lookup(key); i >= 0 {
+		v.members[i].value = value
+	} else {
+		v.members = append(v.members, member{key: key, value: value})
+	}
+	v.touch()
+	return nil
+}
+
+// DeleteKey 删除对象中所有名为 key 的成员
+func (v *Value) DeleteKey(key string) error {
+	if v.kind != Object {
+		return &KindError{Object, v.kind}
+	}
+	n := len(v.members)
+	kept := v.members[:0]
+	for _, m := range v.members {
+		if m.key != key {
+			kept = append(kept, m)
+		}
+	}
+	clear(v.members[len(kept):])
+	v.members = kept
+	if len(kept) == n {
+		return ErrNotFound
+	}
+	v.touch()
+	return nil
+}
+
+// SetIndex 替换数组第 i 个元素；i 等于长度时追加
+func (v *Value) SetIndex(i int, value *Value) error {
+	if v.kind != Array {
+		return &KindError{Array, v.kind}
+	}
+	switch {
+	case i >= 0 && i < len(v.elems):
+		v.elems[i] = v.adopt(value)
+	case i == len(v.elems):
+		v.elems = append(v.elems, v.adopt(value))
+	default:
+		return ErrNotFound
+	}
+	v.touch()
+	return nil
+}
+
+func (v *Value) Append(value *Value) error {
+	return v.SetIndex(v.Len(), value)
+}
+
+// RemoveIndex 删除数组第 i 个元素，后面的元素前移
+func (v *Value) RemoveIndex(i int) error {
+	if v.kind != Array {
+		return &KindError{Array, v.kind}
+	}
+	if i < 0 || i >= len(v.elems) {
+		return ErrNotFound
+	}
+	v.elems = slices.Delete(v.elems, i, i+1)
+	v.touch()
+	return nil
+}
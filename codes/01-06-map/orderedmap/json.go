@@ -0,0 +1,138 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// MarshalJSON 按插入顺序输出 JSON 对象。
+// 键的编码规则与 encoding/json 处理 map 时相同：字符串、整数，或实现了 encoding.TextMarshaler 的类型。
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for k, v := range m.All() {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		ks, err := encodeKey(k)
+		if err != nil {
+			return nil, err
+		}
+		kb, err := json.Marshal(ks)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+
+		vb, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 清空 m，再按原文中键的顺序插入。
+// 原文中有重复的键时保留第一次出现的位置，值取最后一次的（与 encoding/json 解析到 map 时一致）。
+// JSON null 会把 m 清空。
+//
+// 值用 encoding/json 解析，V 为 any 时数字仍会变成 float64（见 demo.go 的 f5），
+// 嵌套对象也会变成无序的 map[string]any；需要保序的嵌套对象时，把 V 声明为 *OrderedMap[string, ...]。
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	m.items = nil
+	m.lazyInit()
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("orderedmap: cannot unmarshal %v into an object", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, err := decodeKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("orderedmap: key %q: %w", tok, err)
+		}
+		m.Set(key, value)
+	}
+	// 读掉结尾的 '}'
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// encodeKey 的判断顺序和 encoding/json 文档一样：底层类型是 string 的键直接用，即使实现了 TextMarshaler；
+// 然后才是 TextMarshaler（nil 指针得到空字符串）、整数。解码时反过来，先看 TextUnmarshaler，见 decodeKey。
+// 注意 GOEXPERIMENT=jsonv2 下 encoding/json 对 string 键也会调用 MarshalText，这里不跟。
+func encodeKey[K comparable](k K) (string, error) {
+	rv := reflect.ValueOf(k)
+	if rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	if tm, ok := any(k).(encoding.TextMarshaler); ok {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("orderedmap: unsupported key type %T", k)
+}
+
+func decodeKey[K comparable](s string) (K, error) {
+	var k K
+	if tu, ok := any(&k).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(s))
+		return k, err
+	}
+	rv := reflect.ValueOf(&k).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+		return k, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("orderedmap: key %q: %w", s, err)
+		}
+		rv.SetInt(n)
+		return k, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("orderedmap: key %q: %w", s, err)
+		}
+		rv.SetUint(n)
+		return k, nil
+	}
+	return k, fmt.Errorf("orderedmap: unsupported key type %T", k)
+}
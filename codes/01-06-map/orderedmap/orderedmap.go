@@ -0,0 +1,165 @@
+// 按插入顺序遍历的 map
+//
+// demo.go 的 f6 说明 map 的遍历顺序是随机的；map_json 把 JSON 解析进 map 后，
+// 原文中键的顺序也就丢了，再序列化时 encoding/json 会按键排序输出。
+// OrderedMap 用 map 加双向链表保存插入顺序：
+//
+//	Get、Set、Delete 都是 O(1)
+//	All、Keys、Values、Backward 按插入顺序返回 Go 1.23 的迭代器，可以直接 for range
+//	MarshalJSON 按插入顺序输出，UnmarshalJSON 按原文顺序插入
+//
+// 零值可以直接使用。和内置 map 一样，不是并发安全的。
+package orderedmap
+
+import (
+	"iter"
+)
+
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *node[K, V]
+	deleted    bool
+}
+
+type OrderedMap[K comparable, V any] struct {
+	items map[K]*node[K, V]
+	root  node[K, V] // 哨兵，root.next 是最早插入的节点，root.prev 是最晚插入的
+}
+
+func New[K comparable, V any]() *OrderedMap[K, V] {
+	m := &OrderedMap[K, V]{}
+	m.lazyInit()
+	return m
+}
+
+func (m *OrderedMap[K, V]) lazyInit() {
+	if m.items == nil {
+		m.items = make(map[K]*node[K, V])
+		m.root.prev, m.root.next = &m.root, &m.root
+	}
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	n, ok := m.items[key]
+	if !ok {
+		return value, false
+	}
+	return n.value, true
+}
+
+// Set 存入键值对。键已存在时只更新值，位置不变；否则追加到末尾
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	m.lazyInit()
+	if n, ok := m.items[key]; ok {
+		n.value = value
+		return
+	}
+	n := &node[K, V]{key: key, value: value, prev: m.root.prev, next: &m.root}
+	m.root.prev.next = n
+	m.root.prev = n
+	m.items[key] = n
+}
+
+// Delete 删除键，返回键是否存在
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	n, ok := m.items[key]
+	if !ok {
+		return false
+	}
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	// 保留 n 自己的 prev/next：迭代器可能正停在 n 上，沿着它们能走回链表
+	n.deleted = true
+	delete(m.items, key)
+	return true
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.items)
+}
+
+// All 按插入顺序遍历键值对。
+// 和内置 map 的 for range 一样，遍历期间可以删除任意键（包括当前键），
+// 已删除但还没遍历到的键不会再出现；遍历期间新增的键可能会被遍历到。
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.items == nil {
+			return
+		}
+		for n := m.root.next; n != &m.root; {
+			if !yield(n.key, n.value) {
+				return
+			}
+			n = m.advance(n, true)
+		}
+	}
+}
+
+// Backward 按插入顺序的逆序遍历
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.items == nil {
+			return
+		}
+		for n := m.root.prev; n != &m.root; {
+			if !yield(n.key, n.value) {
+				return
+			}
+			n = m.advance(n, false)
+		}
+	}
+}
+
+// advance 返回 n 之后（forward 为 false 时是之前）第一个没被删除的节点。
+// 被删除节点的 prev/next 仍指向删除时的邻居，节点也不会被复用，所以沿着它们一定能回到链表上。
+func (m *OrderedMap[K, V]) advance(n *node[K, V], forward bool) *node[K, V] {
+	for {
+		if forward {
+			n = n.next
+		} else {
+			n = n.prev
+		}
+		if !n.deleted {
+			return n
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Oldest 返回最早插入的键值对
+func (m *OrderedMap[K, V]) Oldest() (key K, value V, ok bool) {
+	if m.Len() == 0 {
+		return key, value, false
+	}
+	n := m.root.next
+	return n.key, n.value, true
+}
+
+// Newest 返回最晚插入的键值对
+func (m *OrderedMap[K, V]) Newest() (key K, value V, ok bool) {
+	if m.Len() == 0 {
+		return key, value, false
+	}
+	n := m.root.prev
+	return n.key, n.value, true
+}
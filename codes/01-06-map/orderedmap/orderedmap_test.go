@@ -0,0 +1,316 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/*
+shell:
+	cd orderedmap
+	go test -v .
+*/
+
+func collect[K comparable, V any](m *OrderedMap[K, V]) []K {
+	return slices.Collect(m.Keys())
+}
+
+// 对应 demo.go 的 f6：多次遍历顺序都和插入顺序一致
+func TestInsertionOrder(t *testing.T) {
+	var m OrderedMap[string, int] // 零值可用
+	for _, k := range []string{"03", "01", "02", "00"} {
+		m.Set(k, len(k))
+	}
+	want := []string{"03", "01", "02", "00"}
+	for i := 0; i < 10; i++ {
+		if got := collect(&m); !slices.Equal(got, want) {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+
+	m.Set("01", 100) // 更新不改变位置
+	m.Delete("02")
+	m.Set("02", 2) // 删除后重新插入移到末尾
+	if got := collect(&m); !slices.Equal(got, []string{"03", "01", "00", "02"}) {
+		t.Fatalf("order = %v", got)
+	}
+	if v, ok := m.Get("01"); !ok || v != 100 {
+		t.Fatalf("Get(01) = %v, %v", v, ok)
+	}
+
+	var back []string
+	for k := range m.Backward() {
+		back = append(back, k)
+	}
+	if !slices.Equal(back, []string{"02", "00", "01", "03"}) {
+		t.Fatalf("Backward = %v", back)
+	}
+	if k, _, _ := m.Oldest(); k != "03" {
+		t.Fatalf("Oldest = %v", k)
+	}
+	if k, _, _ := m.Newest(); k != "02" {
+		t.Fatalf("Newest = %v", k)
+	}
+	if vals := slices.Collect(m.Values()); !slices.Equal(vals, []int{2, 100, 2, 2}) {
+		t.Fatalf("Values = %v", vals)
+	}
+}
+
+func TestEmptyMap(t *testing.T) {
+	var m OrderedMap[int, int]
+	for range m.All() {
+		t.Fatal("empty map yielded")
+	}
+	if _, _, ok := m.Oldest(); ok {
+		t.Fatal("Oldest on empty map")
+	}
+	if m.Delete(1) {
+		t.Fatal("Delete on empty map")
+	}
+}
+
+func TestDeleteDuringIteration(t *testing.T) {
+	m := New[int, int]()
+	for i := 0; i < 10; i++ {
+		m.Set(i, i)
+	}
+	var seen []int
+	for k := range m.All() {
+		seen = append(seen, k)
+		m.Delete(k) // 删除当前键
+		if k == 2 {
+			m.Delete(3) // 删除还没遍历到的键
+			m.Delete(4)
+		}
+		if k == 7 {
+			break
+		}
+	}
+	if !slices.Equal(seen, []int{0, 1, 2, 5, 6, 7}) {
+		t.Fatalf("seen %v", seen)
+	}
+	if got := collect(m); !slices.Equal(got, []int{8, 9}) {
+		t.Fatalf("remaining %v", got)
+	}
+}
+
+// 随机操作下与“map + 键切片”的朴素实现对比
+func TestAgainstSliceModel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := New[int, int]()
+	ref := map[int]int{}
+	var order []int
+	for op := 0; op < 10000; op++ {
+		k := r.Intn(50)
+		if r.Intn(3) == 0 {
+			_, ok := ref[k]
+			if got := m.Delete(k); got != ok {
+				t.Fatalf("op %d: Delete(%d) = %v", op, k, got)
+			}
+			delete(ref, k)
+			if i := slices.Index(order, k); i >= 0 {
+				order = slices.Delete(order, i, i+1)
+			}
+		} else {
+			if _, ok := ref[k]; !ok {
+				order = append(order, k)
+			}
+			ref[k] = op
+			m.Set(k, op)
+		}
+		if op%100 == 0 {
+			if got := collect(m); !slices.Equal(got, order) {
+				t.Fatalf("op %d: keys %v, want %v", op, got, order)
+			}
+		}
+	}
+	for k, v := range m.All() {
+		if ref[k] != v {
+			t.Fatalf("m[%d] = %d, want %d", k, v, ref[k])
+		}
+	}
+}
+
+// 对应 demo.go 的 map_json：原文的键序在往返后保持不变
+func TestJSONRoundTrip(t *testing.T) {
+	src := `{"zeta":1,"Bob":30,"alpha":{"y":1,"x":2},"Alice":25.5}`
+	var m OrderedMap[string, json.RawMessage]
+	if err := json.Unmarshal([]byte(src), &m); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(&m); !slices.Equal(got, []string{"zeta", "Bob", "alpha", "Alice"}) {
+		t.Fatalf("keys = %v", got)
+	}
+	out, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Fatalf("round trip:\n got %s\nwant %s", out, src)
+	}
+
+	// 内置 map 会按键排序
+	var plain map[string]json.RawMessage
+	json.Unmarshal([]byte(src), &plain)
+	sorted, _ := json.Marshal(plain)
+	if string(sorted) == src {
+		t.Fatal("expected encoding/json to reorder keys of a plain map")
+	}
+}
+
+// 嵌套对象用 *OrderedMap 声明时同样保序；作为结构体字段时也能正常编解码
+func TestNestedAndStructField(t *testing.T) {
+	type doc struct {
+		Name   string                                        `json:"name"`
+		Fields *OrderedMap[string, *OrderedMap[string, int]] `json:"fields"`
+	}
+	src := `{"name":"cfg","fields":{"b":{"z":1,"a":2},"a":{}}}`
+	var d doc
+	if err := json.Unmarshal([]byte(src), &d); err != nil {
+		t.Fatal(err)
+	}
+	inner, _ := d.Fields.Get("b")
+	if got := collect(inner); !slices.Equal(got, []string{"z", "a"}) {
+		t.Fatalf("inner keys = %v", got)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Fatalf("round trip:\n got %s\nwant %s", out, src)
+	}
+}
+
+func TestJSONKeysAndErrors(t *testing.T) {
+	// 整数键
+	var ints OrderedMap[int, string]
+	if err := json.Unmarshal([]byte(`{"3":"c","-1":"a","2":"b"}`), &ints); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(&ints); !slices.Equal(got, []int{3, -1, 2}) {
+		t.Fatalf("keys = %v", got)
+	}
+	if _, err := json.Marshal(&ints); err != nil {
+		t.Fatal(err)
+	}
+
+	// encoding.TextMarshaler 键
+	addrs := New[netip.Addr, int]()
+	addrs.Set(netip.MustParseAddr("10.0.0.2"), 2)
+	addrs.Set(netip.MustParseAddr("10.0.0.1"), 1)
+	out, _ := json.Marshal(addrs)
+	if string(out) != `{"10.0.0.2":2,"10.0.0.1":1}` {
+		t.Fatalf("marshal = %s", out)
+	}
+	back := New[netip.Addr, int]()
+	if err := json.Unmarshal(out, back); err != nil || back.Len() != 2 {
+		t.Fatalf("unmarshal = %v, len %d", err, back.Len())
+	}
+
+	// 重复的键：位置取第一次，值取最后一次
+	var dup OrderedMap[string, int]
+	json.Unmarshal([]byte(`{"a":1,"b":2,"a":3}`), &dup)
+	if out, _ := json.Marshal(&dup); string(out) != `{"a":3,"b":2}` {
+		t.Fatalf("duplicate keys = %s", out)
+	}
+
+	// 再次解析会先清空；null 得到空 map
+	if err := json.Unmarshal([]byte(`null`), &dup); err != nil || dup.Len() != 0 {
+		t.Fatalf("null: %v, len %d", err, dup.Len())
+	}
+
+	for _, bad := range []string{`[1,2]`, `{"a":"x"}`, `{"a":1`} {
+		if err := json.Unmarshal([]byte(bad), &dup); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", bad)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"x":"a"}`), &ints); err == nil {
+		t.Error("non-integer key accepted")
+	}
+	if _, err := json.Marshal(New[float64, int]()); err != nil {
+		t.Errorf("empty map with float keys: %v", err)
+	}
+	floats := New[float64, int]()
+	floats.Set(1.5, 1)
+	if _, err := json.Marshal(floats); err == nil {
+		t.Error("float key accepted")
+	}
+}
+
+// upperKey 的底层类型是 string，又实现了 TextMarshaler：按 encoding/json 的文档，编码 map 键时不调用 MarshalText
+type upperKey string
+
+func (k upperKey) MarshalText() ([]byte, error) { return []byte(strings.ToUpper(string(k))), nil }
+
+// levelKey 是整数，实现了 TextMarshaler：encoding/json 用 MarshalText
+type levelKey int
+
+func (k levelKey) MarshalText() ([]byte, error) { return []byte("L" + strconv.Itoa(int(k))), nil }
+
+// ptrKey 是指针，nil 时 encoding/json 编码成空字符串
+type ptrKey struct{ name string }
+
+func (k *ptrKey) MarshalText() ([]byte, error) { return []byte(k.name), nil }
+
+// 和 json.Marshal(map[K]V) 对比：只有一个键时两者的输出应该完全相同
+func sameAsStdlib[K comparable](t *testing.T, keys ...K) {
+	t.Helper()
+	for _, k := range keys {
+		m := New[K, int]()
+		m.Set(k, 1)
+		got, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("%T %v: %v", k, k, err)
+		}
+		want, _ := json.Marshal(map[K]int{k: 1})
+		if string(got) != string(want) {
+			t.Errorf("%T %v: got %s, encoding/json %s", k, k, got, want)
+		}
+	}
+}
+
+func TestKeyEncodingMatchesStdlib(t *testing.T) {
+	sameAsStdlib(t, levelKey(3), levelKey(-1))
+	sameAsStdlib(t, &ptrKey{"p"}, nil)
+	sameAsStdlib(t, "plain", `quote"`)
+	sameAsStdlib(t, -7, 0)
+	sameAsStdlib(t, uint8(255))
+	sameAsStdlib(t, netip.MustParseAddr("::1"))
+}
+
+// 底层类型是 string 的键按 encoding/json 文档的规则直接用，不调用 MarshalText。
+// GOEXPERIMENT=jsonv2（Go 1.27 默认打开）时 encoding/json 的 v1 兼容层会调用 MarshalText，和文档不一致，这时不对比。
+func TestStringKindKeyIgnoresMarshalText(t *testing.T) {
+	m := New[upperKey, int]()
+	m.Set("abc", 1)
+	if got, _ := json.Marshal(m); string(got) != `{"abc":1}` {
+		t.Fatalf("got %s", got)
+	}
+	if want, _ := json.Marshal(map[upperKey]int{"abc": 1}); string(want) != `{"abc":1}` {
+		t.Skipf("encoding/json called MarshalText on a string key (%s), run with GOEXPERIMENT=nojsonv2 to compare", want)
+	}
+	sameAsStdlib(t, upperKey("abc"), upperKey(""))
+}
+
+func ExampleOrderedMap() {
+	var m OrderedMap[string, int]
+	json.Unmarshal([]byte(`{"Bob":30,"Alice":25}`), &m)
+	m.Set("Tom", 26)
+	for k, v := range m.All() {
+		fmt.Printf("%s=%d\n", k, v)
+	}
+	out, _ := json.Marshal(&m)
+	fmt.Println(string(out))
+	// Output:
+	// Bob=30
+	// Alice=25
+	// Tom=26
+	// {"Bob":30,"Alice":25,"Tom":26}
+}
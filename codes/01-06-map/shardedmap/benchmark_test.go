@@ -0,0 +1,165 @@
+// ShardedMap 与 f7（Mutex + map）、f8（sync.Map）在不同读写比例下的对比
+
+package shardedmap
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+shell:
+	go test -bench=. -run=^$ -benchmem -cpu=1,4
+*/
+
+/*
+1000 个键，RunParallel 中每个操作按比例随机为读或写（单核机器上运行，-4 只是 4 个 P 轮流执行）
+
+BenchmarkMix/read50/Mutex               34379653                39.32 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read50/Mutex-4             26188556                53.90 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read50/SyncMap              9933786               126.8 ns/op            29 B/op          1 allocs/op
+BenchmarkMix/read50/SyncMap-4            6953739               186.3 ns/op            29 B/op          1 allocs/op
+BenchmarkMix/read50/Sharded             15918235                75.93 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read50/Sharded-4           15345469                66.88 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read90/Mutex               32122441                36.51 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read90/Mutex-4             22379179                48.41 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read90/SyncMap             15785436                73.24 ns/op            5 B/op          0 allocs/op
+BenchmarkMix/read90/SyncMap-4           16793722                81.07 ns/op            5 B/op          0 allocs/op
+BenchmarkMix/read90/Sharded             19544660                56.42 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read90/Sharded-4           20518968                53.78 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/Mutex               32096437                37.22 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/Mutex-4             23421069                47.22 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/SyncMap             21069576                57.76 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/SyncMap-4           20665429                57.53 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/Sharded             22211280                50.43 ns/op            0 B/op          0 allocs/op
+BenchmarkMix/read99/Sharded-4           23346319                49.46 ns/op            0 B/op          0 allocs/op
+
+BenchmarkWriteHeavyNewKeys/Mutex         2767357               496.7 ns/op            54 B/op          0 allocs/op
+BenchmarkWriteHeavyNewKeys/Mutex-4       2467771               544.0 ns/op            61 B/op          0 allocs/op
+BenchmarkWriteHeavyNewKeys/SyncMap       1342369              1189 ns/op             111 B/op          3 allocs/op
+BenchmarkWriteHeavyNewKeys/SyncMap-4     1000000              1093 ns/op             108 B/op          3 allocs/op
+BenchmarkWriteHeavyNewKeys/Sharded       2492308               532.9 ns/op            60 B/op          0 allocs/op
+BenchmarkWriteHeavyNewKeys/Sharded-4     2444816               570.6 ns/op            61 B/op          0 allocs/op
+
+1. 只有一个核时锁基本没有竞争，一把 Mutex 最快，ShardedMap 多出的是哈希计算的开销；
+   Mutex 从 1 个 P 到 4 个 P 变慢了约 30%，Sharded 基本不变，多核下差距会更明显。
+2. sync.Map 在写比例高时最慢：值以 any 保存，每次 Store 都要把 int 装箱（1 allocs/op 来自这里），
+   再对 entry 做 CAS；读 99% 时才接近另外两种实现。
+3. 写多读少、不断加入新键时 sync.Map 比其它两种慢一倍以上，这就是文档中“不适合频繁写”的含义。
+*/
+
+const benchKeys = 1000
+
+// 和三种实现共用的最小接口
+type benchMap interface {
+	load(k int) (int, bool)
+	store(k, v int)
+}
+
+// f7 的做法：一把 Mutex 保护内置 map
+type mutexMap struct {
+	mu sync.Mutex
+	m  map[int]int
+}
+
+func (m *mutexMap) load(k int) (int, bool) {
+	m.mu.Lock()
+	v, ok := m.m[k]
+	m.mu.Unlock()
+	return v, ok
+}
+
+func (m *mutexMap) store(k, v int) {
+	m.mu.Lock()
+	m.m[k] = v
+	m.mu.Unlock()
+}
+
+// f8 的做法：sync.Map
+type syncMap struct{ m sync.Map }
+
+func (m *syncMap) load(k int) (int, bool) {
+	v, ok := m.m.Load(k)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *syncMap) store(k, v int) { m.m.Store(k, v) }
+
+type sharded struct{ m *ShardedMap[int, int] }
+
+func (m sharded) load(k int) (int, bool) { return m.m.Load(k) }
+func (m sharded) store(k, v int)         { m.m.Store(k, v) }
+
+func benchmarkMix(b *testing.B, m benchMap, readPercent int) {
+	for i := 0; i < benchKeys; i++ {
+		m.store(i, i)
+	}
+	var seed atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		// 每个 goroutine 一个简单的 xorshift，避免 math/rand 的全局锁影响结果
+		x := seed.Add(0x9e3779b97f4a7c15)
+		for pb.Next() {
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+			k := int(x % benchKeys)
+			if int((x>>32)%100) < readPercent {
+				m.load(k)
+			} else {
+				m.store(k, k)
+			}
+		}
+	})
+}
+
+func BenchmarkMix(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func() benchMap
+	}{
+		{"Mutex", func() benchMap { return &mutexMap{m: make(map[int]int)} }},
+		{"SyncMap", func() benchMap { return &syncMap{} }},
+		{"Sharded", func() benchMap { return sharded{New[int, int](32, nil)} }},
+	}
+	for _, read := range []int{50, 90, 99} {
+		for _, impl := range impls {
+			b.Run("read"+strconv.Itoa(read)+"/"+impl.name, func(b *testing.B) {
+				benchmarkMix(b, impl.new(), read)
+			})
+		}
+	}
+}
+
+// 写多读少（10% 读），新键不断加入：sync.Map 最不擅长的场景
+func BenchmarkWriteHeavyNewKeys(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func() benchMap
+	}{
+		{"Mutex", func() benchMap { return &mutexMap{m: make(map[int]int)} }},
+		{"SyncMap", func() benchMap { return &syncMap{} }},
+		{"Sharded", func() benchMap { return sharded{New[int, int](32, nil)} }},
+	}
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			m := impl.new()
+			var next atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					k := int(next.Add(1))
+					if k%10 == 0 {
+						m.load(k - 5)
+					} else {
+						m.store(k, k)
+					}
+				}
+			})
+		})
+	}
+}
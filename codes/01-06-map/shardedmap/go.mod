@@ -0,0 +1,3 @@
+module shardedmap
+
+go 1.24
@@ -0,0 +1,162 @@
+package shardedmap
+
+import (
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	cd shardedmap
+	go test -v -race .
+	go test -bench=. -run=^$ -benchmem -cpu=1,4
+*/
+
+func TestBasicOperations(t *testing.T) {
+	m := New[string, int](4, nil)
+	m.Store("Alice", 25)
+
+	if v, ok := m.Load("Alice"); !ok || v != 25 {
+		t.Fatalf("Load(Alice) = %v, %v", v, ok)
+	}
+	if _, ok := m.Load("Bob"); ok {
+		t.Fatal("Load(Bob) found a missing key")
+	}
+
+	if v, loaded := m.LoadOrStore("Alice", 30); !loaded || v != 25 {
+		t.Fatalf("LoadOrStore(Alice) = %v, %v", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("Bob", 30); loaded || v != 30 {
+		t.Fatalf("LoadOrStore(Bob) = %v, %v", v, loaded)
+	}
+
+	if v, loaded := m.LoadAndDelete("Bob"); !loaded || v != 30 {
+		t.Fatalf("LoadAndDelete(Bob) = %v, %v", v, loaded)
+	}
+	m.Delete("Alice")
+	m.Delete("Nobody") // 和内置 map 一样，删除不存在的键不报错
+	if m.Len() != 0 {
+		t.Fatalf("Len = %d after deletes", m.Len())
+	}
+}
+
+func TestCompute(t *testing.T) {
+	m := New[string, int](1, nil)
+	inc := func(old int, loaded bool) (int, bool) { return old + 1, false }
+
+	if v, ok := m.Compute("n", inc); !ok || v != 1 {
+		t.Fatalf("first Compute = %v, %v", v, ok)
+	}
+	m.Compute("n", inc)
+	if v, _ := m.Load("n"); v != 2 {
+		t.Fatalf("n = %d, want 2", v)
+	}
+
+	// 返回 delete=true 删除键
+	if _, ok := m.Compute("n", func(old int, loaded bool) (int, bool) { return 0, true }); ok {
+		t.Fatal("Compute with delete reported the key present")
+	}
+	if _, ok := m.Load("n"); ok {
+		t.Fatal("key still present after Compute delete")
+	}
+}
+
+// 与 f7 相同的场景：一个 goroutine 写 10000 个键，另一个同时遍历
+func TestConcurrentWriteAndRange(t *testing.T) {
+	m := New[int, int](16, nil)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10000; i++ {
+			m.Store(i, i*2)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		m.Range(func(k, v int) bool {
+			if k >= 0 && v != k*2 {
+				t.Errorf("m[%d] = %d", k, v)
+			}
+			return true
+		})
+	}()
+	// 并发自增同一个键，Compute 保证不丢失更新
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.Compute(-1, func(old int, _ bool) (int, bool) { return old + 1, false })
+		}
+	}()
+	wg.Wait()
+
+	if m.Len() != 10001 {
+		t.Fatalf("Len = %d, want 10001", m.Len())
+	}
+	if v, _ := m.Load(-1); v != 1000 {
+		t.Fatalf("counter = %d, want 1000", v)
+	}
+}
+
+func TestLoadOrStoreAtomic(t *testing.T) {
+	m := New[string, int](4, nil)
+	var wg sync.WaitGroup
+	winners := make(chan int, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if _, loaded := m.LoadOrStore("key", id); !loaded {
+				winners <- id
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(winners)
+	if len(winners) != 1 {
+		t.Fatalf("%d goroutines stored the key, want exactly 1", len(winners))
+	}
+}
+
+func TestRangeStopAndMutate(t *testing.T) {
+	m := New[int, int](4, nil)
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+	visited := 0
+	m.Range(func(k, v int) bool {
+		visited++
+		return visited < 10
+	})
+	if visited != 10 {
+		t.Fatalf("visited %d entries, want 10", visited)
+	}
+
+	// 回调中删除不会死锁
+	m.Range(func(k, v int) bool {
+		m.Delete(k)
+		return true
+	})
+	if m.Len() != 0 {
+		t.Fatalf("Len = %d after deleting in Range", m.Len())
+	}
+}
+
+func TestCustomHasher(t *testing.T) {
+	// 所有键都落在同一个分片
+	m := New[int, string](8, func(int) uint64 { return 3 })
+	for i := 0; i < 10; i++ {
+		m.Store(i, "v")
+	}
+	for i := range m.shards {
+		if n := len(m.shards[i].m); (i == 3) != (n == 10) {
+			t.Fatalf("shard %d has %d keys", i, n)
+		}
+	}
+	if len(m.shards) != 8 {
+		t.Fatalf("%d shards, want 8", len(m.shards))
+	}
+	if n := len(New[int, int](5, nil).shards); n != 8 {
+		t.Fatalf("5 shards rounded to %d, want 8", n)
+	}
+}
@@ -0,0 +1,166 @@
+// Swiss table、1.24 之前的 bucket 实现（../hmap）与内置 map 的基准测试
+
+package swissmap
+
+import (
+	"hmap"
+	"iter"
+	"maps"
+	"math/rand/v2"
+	"testing"
+)
+
+/*
+shell:
+	cd swissmap
+	go test -bench=. -run=^$ -benchmem
+*/
+
+/*
+int 键，每个用例 65536 个元素（单核机器上运行；go1.27 的内置 map 本身就是 Swiss table）
+
+BenchmarkInsert/Builtin                      146           8661182 ns/op         4729528 B/op        532 allocs/op
+BenchmarkInsert/HMap                          66          15525938 ns/op         5791751 B/op      69288 allocs/op
+BenchmarkInsert/Swiss                        190           7295867 ns/op         4469184 B/op         16 allocs/op
+BenchmarkLookupHit/Builtin              43617488                25.69 ns/op            0 B/op          0 allocs/op
+BenchmarkLookupHit/HMap                 23330955                47.95 ns/op            0 B/op          0 allocs/op
+BenchmarkLookupHit/Swiss                38107042                26.54 ns/op            0 B/op          0 allocs/op
+BenchmarkLookupMiss/Builtin             80878822                19.37 ns/op            0 B/op          0 allocs/op
+BenchmarkLookupMiss/HMap                21798690                56.68 ns/op            0 B/op          0 allocs/op
+BenchmarkLookupMiss/Swiss               67005625                25.22 ns/op            0 B/op          0 allocs/op
+BenchmarkDelete/Builtin                  9515022               126.7 ns/op             0 B/op          0 allocs/op
+BenchmarkDelete/HMap                     7955528               198.4 ns/op            15 B/op          1 allocs/op
+BenchmarkDelete/Swiss                   11851188                89.93 ns/op            0 B/op          0 allocs/op
+BenchmarkIterate/Builtin                     840           1491981 ns/op              48 B/op          3 allocs/op
+BenchmarkIterate/HMap                        854           1394467 ns/op              48 B/op          3 allocs/op
+BenchmarkIterate/Swiss                       996           1197141 ns/op              48 B/op          3 allocs/op
+
+结论：
+	查找比 bucket 实现快约 2 倍：一次 uint64 运算就筛掉整个 group 中 H2 不同的槽位，
+	未命中时遇到有 empty 槽位的 group 就停下，不用沿着溢出链逐个比较 tophash。
+	纯 Go 的 Swiss table 查找已经接近内置 map，未命中还差约 30%：runtime 在 amd64 上用 SSE 指令匹配控制字节，
+	对 int/string 键有专门的快速路径，这里每次还要经过 maphash.Comparable。
+	Insert 包含从空表开始的多次扩容：bucket 实现每个溢出桶单独分配，所以有 6 万多次分配；
+	Swiss table 没有溢出桶，装载率 7/8 也比 6.5/8 高，内存最少。内置 map 的几百次分配来自把大表拆成多个 table。
+	Delete 用例删除后再插回同一个键。Swiss table 的 group 大多有空位，删除直接置空，不留墓碑；
+	bucket 实现偶尔要重新挂溢出桶，有少量分配。
+	遍历的差别不大，主要耗时在 iter.Seq2 的 yield 回调上。
+*/
+
+const benchSize = 1 << 16
+
+// 三种 map 的公共操作，内置 map 也包一层，让每种实现都经过一次方法调用
+type benchMap interface {
+	Get(int) (int, bool)
+	Set(int, int)
+	Delete(int)
+	All() iter.Seq2[int, int]
+}
+
+type builtinMap map[int]int
+
+func (m builtinMap) Get(k int) (int, bool) { v, ok := m[k]; return v, ok }
+func (m builtinMap) Set(k, v int)          { m[k] = v }
+func (m builtinMap) Delete(k int)          { delete(m, k) }
+func (m builtinMap) All() iter.Seq2[int, int] {
+	return maps.All(m)
+}
+
+var impls = []struct {
+	name string
+	new  func() benchMap
+}{
+	{"Builtin", func() benchMap { return builtinMap{} }},
+	{"HMap", func() benchMap { return hmap.New[int, int](0) }},
+	{"Swiss", func() benchMap { return New[int, int](0) }},
+}
+
+// 打乱顺序的键，避免连续的键恰好落在连续的 bucket 里
+func benchKeys() []int {
+	keys := rand.New(rand.NewPCG(1, 1)).Perm(benchSize)
+	for i := range keys {
+		keys[i] = keys[i]*7 + 1
+	}
+	return keys
+}
+
+func filled(newMap func() benchMap, keys []int) benchMap {
+	m := newMap()
+	for _, k := range keys {
+		m.Set(k, k)
+	}
+	return m
+}
+
+var sink int
+
+func BenchmarkInsert(b *testing.B) {
+	keys := benchKeys()
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				filled(impl.new, keys)
+			}
+		})
+	}
+}
+
+func BenchmarkLookupHit(b *testing.B) {
+	keys := benchKeys()
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			m := filled(impl.new, keys)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v, _ := m.Get(keys[i&(benchSize-1)])
+				sink += v
+			}
+		})
+	}
+}
+
+func BenchmarkLookupMiss(b *testing.B) {
+	keys := benchKeys()
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			m := filled(impl.new, keys)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v, _ := m.Get(keys[i&(benchSize-1)] + 1) // 表里的键都是 7n+1
+				sink += v
+			}
+		})
+	}
+}
+
+func BenchmarkDelete(b *testing.B) {
+	keys := benchKeys()
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			m := filled(impl.new, keys)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				k := keys[i&(benchSize-1)]
+				m.Delete(k)
+				m.Set(k, k)
+			}
+		})
+	}
+}
+
+func BenchmarkIterate(b *testing.B) {
+	keys := benchKeys()
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			m := filled(impl.new, keys)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for k, v := range m.All() {
+					sink += k + v
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+module swissmap
+
+go 1.24
+
+require hmap v0.0.0
+
+replace hmap => ../hmap
@@ -0,0 +1,87 @@
+package swissmap
+
+import "math/bits"
+
+// 控制字节：每个槽位一个字节，8 个槽位的控制字节打包成一个 uint64，
+// 这样一次比较就能检查整个 group（SWAR：SIMD within a register），不需要 SIMD 指令。
+//
+//	empty   1000_0000  槽位为空
+//	deleted 1111_1110  墓碑：槽位被删除，但探测序列不能在这里停下
+//	full    0hhh_hhhh  槽位有值，低 7 位是哈希值的 H2 部分
+const (
+	ctrlEmpty   = 0b1000_0000
+	ctrlDeleted = 0b1111_1110
+
+	groupSlots = 8
+	bitsetLSB  = 0x0101010101010101
+	bitsetMSB  = 0x8080808080808080
+)
+
+type ctrlGroup uint64
+
+// bitset 中每个字节的最高位表示对应的槽位是否匹配
+type bitset uint64
+
+func (b bitset) first() int { return bits.TrailingZeros64(uint64(b)) >> 3 }
+
+func (b bitset) removeFirst() bitset { return b & (b - 1) }
+
+func (b bitset) count() int { return bits.OnesCount64(uint64(b)) }
+
+func (g ctrlGroup) get(i int) uint8 { return uint8(g >> (8 * i)) }
+
+func (g *ctrlGroup) set(i int, c uint8) {
+	*g = *g&^(0xff<<(8*i)) | ctrlGroup(c)<<(8*i)
+}
+
+// matchH2 找出控制字节等于 h2 的槽位。
+// 经典的“查找零字节”技巧：v 中等于 0 的字节就是匹配的槽位。
+// 它可能有假阳性（紧挨在真正匹配之后的字节），调用方本来就要再比较键，所以无妨。
+func (g ctrlGroup) matchH2(h2 uint8) bitset {
+	v := uint64(g) ^ (bitsetLSB * uint64(h2))
+	return bitset(((v - bitsetLSB) &^ v) & bitsetMSB)
+}
+
+// matchEmpty：最高位为 1 且第 1 位为 0 的只有 empty
+func (g ctrlGroup) matchEmpty() bitset {
+	v := uint64(g)
+	return bitset((v &^ (v << 6)) & bitsetMSB)
+}
+
+// matchEmptyOrDeleted：最高位为 1 的是 empty 或 deleted
+func (g ctrlGroup) matchEmptyOrDeleted() bitset {
+	return bitset(uint64(g) & bitsetMSB)
+}
+
+// matchFull：最高位为 0 的是有值的槽位
+func (g ctrlGroup) matchFull() bitset {
+	return bitset(^uint64(g) & bitsetMSB)
+}
+
+type slot[K comparable, V any] struct {
+	key  K
+	elem V
+}
+
+type group[K comparable, V any] struct {
+	ctrl  ctrlGroup
+	slots [groupSlots]slot[K, V]
+}
+
+// probeSeq 是按 group 的二次探测（三角数序列）：offset 依次加 1、2、3……，
+// group 数是 2 的幂时，这个序列会恰好访问每个 group 一次
+type probeSeq struct {
+	mask   uint64
+	offset uint64
+	index  uint64
+}
+
+func makeProbeSeq(h1, mask uint64) probeSeq {
+	return probeSeq{mask: mask, offset: h1 & mask}
+}
+
+func (s probeSeq) next() probeSeq {
+	s.index++
+	s.offset = (s.offset + s.index) & s.mask
+	return s
+}
@@ -0,0 +1,240 @@
+// Swiss table：Go 1.24 起 runtime map 使用的开放寻址哈希表
+//
+// 知识点/01-数据类型/06-map-02-底层.md 和 ../hmap 讲的是 1.24 之前的拉链式 bucket。
+// Swiss table 的思路不同：
+//
+//	没有溢出桶，冲突时按探测序列去下一个 group 找（开放寻址）
+//	每个 group 8 个槽位，外加 8 个控制字节；哈希值拆成 H1（高 57 位，选 group）和 H2（低 7 位，存进控制字节）
+//	查找时先用一次 64 位运算比较整个 group 的控制字节，只有 H2 相同的槽位才比较键
+//	遇到有 empty 槽位的 group 就说明键不存在，可以停止探测
+//	删除时如果所在 group 已满，只能留下墓碑（deleted），否则直接标记为 empty
+//	装载率上限 7/8，超过时整表重建（runtime 把大表拆成多个 table，每个 table 单独扩容，这里省略）
+//
+// 和 hmap 一样不是并发安全的，遍历期间修改会 panic。
+package swissmap
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/rand/v2"
+)
+
+const (
+	maxLoadNum = 7
+	maxLoadDen = 8
+)
+
+type Map[K comparable, V any] struct {
+	groups     []group[K, V]
+	mask       uint64 // len(groups) - 1
+	count      int
+	tombstones int
+	growthLeft int // 还能占用多少个 empty 槽位，用完就要重建
+	hash       func(K) uint64
+	seed       maphash.Seed
+	writes     int
+}
+
+// New 创建 map，hint 是预计的元素个数
+func New[K comparable, V any](hint int) *Map[K, V] {
+	return NewWithHash[K, V](hint, nil)
+}
+
+// NewWithHash 使用自定义哈希函数，hash 为 nil 时使用 maphash.Comparable
+func NewWithHash[K comparable, V any](hint int, hash func(K) uint64) *Map[K, V] {
+	m := &Map[K, V]{hash: hash, seed: maphash.MakeSeed()}
+	m.init(groupsFor(hint))
+	return m
+}
+
+// groupsFor 返回放下 n 个元素且装载率不超过 7/8 所需的 group 数（2 的幂）
+func groupsFor(n int) int {
+	g := 1
+	for g*groupSlots*maxLoadNum/maxLoadDen < n {
+		g <<= 1
+	}
+	return g
+}
+
+func (m *Map[K, V]) init(ngroups int) {
+	m.groups = make([]group[K, V], ngroups)
+	for i := range m.groups {
+		m.groups[i].ctrl = ctrlGroup(bitsetLSB * ctrlEmpty)
+	}
+	m.mask = uint64(ngroups - 1)
+	m.count = 0
+	m.tombstones = 0
+	m.growthLeft = ngroups * groupSlots * maxLoadNum / maxLoadDen
+}
+
+func (m *Map[K, V]) hashOf(key K) uint64 {
+	if m.hash != nil {
+		return m.hash(key)
+	}
+	return maphash.Comparable(m.seed, key)
+}
+
+func h1(hash uint64) uint64 { return hash >> 7 }
+func h2(hash uint64) uint8  { return uint8(hash & 0x7f) }
+
+func (m *Map[K, V]) Len() int { return m.count }
+
+func (m *Map[K, V]) Get(key K) (value V, ok bool) {
+	hash := m.hashOf(key)
+	for seq := makeProbeSeq(h1(hash), m.mask); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		for match := g.ctrl.matchH2(h2(hash)); match != 0; match = match.removeFirst() {
+			if s := &g.slots[match.first()]; s.key == key {
+				return s.elem, true
+			}
+		}
+		if g.ctrl.matchEmpty() != 0 {
+			return value, false
+		}
+	}
+}
+
+func (m *Map[K, V]) Set(key K, value V) {
+	m.writes++
+	hash := m.hashOf(key)
+
+	// 探测序列上遇到的第一个墓碑，键不存在时优先复用它
+	var tombGroup *group[K, V]
+	var tombSlot int
+	for seq := makeProbeSeq(h1(hash), m.mask); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		for match := g.ctrl.matchH2(h2(hash)); match != 0; match = match.removeFirst() {
+			if s := &g.slots[match.first()]; s.key == key {
+				s.elem = value
+				return
+			}
+		}
+		if tombGroup == nil {
+			if del := g.ctrl.matchEmptyOrDeleted() &^ g.ctrl.matchEmpty(); del != 0 {
+				tombGroup, tombSlot = g, del.first()
+			}
+		}
+
+		empty := g.ctrl.matchEmpty()
+		if empty == 0 {
+			continue
+		}
+		// 键不存在
+		if tombGroup != nil {
+			tombGroup.ctrl.set(tombSlot, h2(hash))
+			tombGroup.slots[tombSlot] = slot[K, V]{key, value}
+			m.tombstones--
+			m.count++
+			return
+		}
+		if m.growthLeft == 0 {
+			m.rehash()
+			m.Set(key, value)
+			return
+		}
+		i := empty.first()
+		g.ctrl.set(i, h2(hash))
+		g.slots[i] = slot[K, V]{key, value}
+		m.growthLeft--
+		m.count++
+		return
+	}
+}
+
+func (m *Map[K, V]) Delete(key K) {
+	m.writes++
+	hash := m.hashOf(key)
+	for seq := makeProbeSeq(h1(hash), m.mask); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		for match := g.ctrl.matchH2(h2(hash)); match != 0; match = match.removeFirst() {
+			i := match.first()
+			if g.slots[i].key != key {
+				continue
+			}
+			g.slots[i] = slot[K, V]{} // 清掉引用，方便 GC
+			m.count--
+			// group 里还有 empty 槽位，说明从来没有探测序列越过这个 group，可以直接置空；
+			// 否则可能有别的键是探测到这里后继续往后放的，必须留下墓碑
+			if g.ctrl.matchEmpty() != 0 {
+				g.ctrl.set(i, ctrlEmpty)
+				m.growthLeft++
+			} else {
+				g.ctrl.set(i, ctrlDeleted)
+				m.tombstones++
+			}
+			return
+		}
+		if g.ctrl.matchEmpty() != 0 {
+			return
+		}
+	}
+}
+
+// Clear 删除所有元素，保留容量
+func (m *Map[K, V]) Clear() {
+	m.writes++
+	m.init(len(m.groups))
+	m.seed = maphash.MakeSeed()
+}
+
+// rehash 在 empty 槽位用完时重建整张表。
+// 墓碑较多时（元素不到容量上限的一半）按原大小重建，只是把墓碑清掉；否则容量翻倍。
+func (m *Map[K, V]) rehash() {
+	old := m.groups
+	n := len(old)
+	if m.count >= n*groupSlots*maxLoadNum/maxLoadDen/2 {
+		n *= 2
+	}
+	m.init(n)
+	for gi := range old {
+		g := &old[gi]
+		for full := g.ctrl.matchFull(); full != 0; full = full.removeFirst() {
+			s := &g.slots[full.first()]
+			m.insertNew(s.key, s.elem)
+		}
+	}
+}
+
+// insertNew 把确定不存在的键放到探测序列上第一个 empty 槽位，重建时使用
+func (m *Map[K, V]) insertNew(key K, value V) {
+	hash := m.hashOf(key)
+	for seq := makeProbeSeq(h1(hash), m.mask); ; seq = seq.next() {
+		g := &m.groups[seq.offset]
+		if empty := g.ctrl.matchEmpty(); empty != 0 {
+			i := empty.first()
+			g.ctrl.set(i, h2(hash))
+			g.slots[i] = slot[K, V]{key, value}
+			m.growthLeft--
+			m.count++
+			return
+		}
+	}
+}
+
+// All 从随机的 group 和随机的槽位偏移开始遍历，顺序和内置 map 一样不固定
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.count == 0 {
+			return
+		}
+		writes := m.writes
+		r := rand.Uint64()
+		start := r & m.mask
+		offset := int(r >> 61)
+		for gi := uint64(0); gi <= m.mask; gi++ {
+			g := &m.groups[(gi+start)&m.mask]
+			for i := 0; i < groupSlots; i++ {
+				si := (i + offset) & (groupSlots - 1)
+				if g.ctrl.get(si)&ctrlEmpty != 0 {
+					continue
+				}
+				if !yield(g.slots[si].key, g.slots[si].elem) {
+					return
+				}
+				if m.writes != writes {
+					panic("swissmap: map modified during iteration")
+				}
+			}
+		}
+	}
+}
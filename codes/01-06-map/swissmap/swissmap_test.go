@@ -0,0 +1,249 @@
+package swissmap
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+/*
+shell:
+	cd swissmap
+	go test -v .
+*/
+
+// checkCtrl 检查控制字节和计数字段对得上：FULL 的个数是 count，DELETED 的个数是 tombstones，
+// 用掉的槽位（FULL 和 DELETED）加上 growthLeft 正好是 7/8 的容量
+func checkCtrl(t *testing.T, m *Map[int, int]) {
+	t.Helper()
+	var full, empty int
+	for i := range m.groups {
+		full += m.groups[i].ctrl.matchFull().count()
+		empty += m.groups[i].ctrl.matchEmpty().count()
+	}
+	deleted := len(m.groups)*groupSlots - full - empty
+	if full != m.count || deleted != m.tombstones {
+		t.Fatalf("ctrl bytes: full=%d deleted=%d, fields: count=%d tombstones=%d", full, deleted, m.count, m.tombstones)
+	}
+	if capacity := len(m.groups) * groupSlots * maxLoadNum / maxLoadDen; full+deleted+m.growthLeft != capacity {
+		t.Fatalf("full=%d deleted=%d growthLeft=%d, capacity %d", full, deleted, m.growthLeft, capacity)
+	}
+}
+
+// 和 hmap 不同的地方在删除和重建：删除可能留下墓碑，墓碑占着 growthLeft，
+// empty 槽位用完时按原大小重建（清掉墓碑）或者翻倍。不断插入新键、随机删掉旧键，
+// 活着的键先涨到 200 个（翻倍），再长时间保持不变（墓碑越积越多，按原大小重建）；
+// 每一步检查控制字节，每次重建后和内置 map 比对全部内容，重建会把所有键重新放一遍
+func TestTombstonesAndRehashAgainstBuiltin(t *testing.T) {
+	// 固定的混合函数，不用每次换种子的 maphash，重建发生在哪一步每次都一样
+	mix := func(k int) uint64 {
+		x := uint64(k) * 0x9e3779b97f4a7c15
+		return x ^ x>>29
+	}
+	// H1 只有 5 种取值、H2 只有 3 种取值：探测序列很长，删除几乎都留下墓碑，
+	// 但新键的探测序列也都经过这些墓碑，马上就会复用，不会按原大小重建
+	weak := func(k int) uint64 { return uint64(k%3) | uint64(k%5)<<7 }
+	for _, tc := range []struct {
+		name     string
+		hash     func(int) uint64
+		sameSize bool // 是否一定会按原大小重建
+	}{{"mix", mix, true}, {"weak", weak, false}} {
+		m := NewWithHash[int, int](0, tc.hash)
+		ref := make(map[int]int)
+		var live []int
+		r := rand.New(rand.NewPCG(1, 2))
+		var sameSize, doubled int
+		for op := 0; op < 50000; op++ {
+			groups, growthLeft := len(m.groups), m.growthLeft
+			m.Set(op, op)
+			ref[op] = op
+			live = append(live, op)
+			rehashed := len(m.groups) != groups || m.growthLeft > growthLeft // Set 本身只会让 growthLeft 变小
+			switch {
+			case len(m.groups) > groups:
+				doubled++
+			case rehashed:
+				sameSize++
+			}
+			if len(live) > min(50+op/20, 200) {
+				i := r.IntN(len(live))
+				k := live[i]
+				live[i] = live[len(live)-1]
+				live = live[:len(live)-1]
+				m.Delete(k)
+				delete(ref, k)
+				if _, ok := m.Get(k); ok {
+					t.Fatalf("%s op %d: Get(%d) after Delete", tc.name, op, k)
+				}
+			}
+			checkCtrl(t, m)
+			if m.Len() != len(ref) {
+				t.Fatalf("%s op %d: Len = %d, want %d", tc.name, op, m.Len(), len(ref))
+			}
+			if !rehashed {
+				continue
+			}
+			for k, v := range ref {
+				if got, ok := m.Get(k); !ok || got != v {
+					t.Fatalf("%s op %d: after rehash Get(%d) = %d, %v; want %d", tc.name, op, k, got, ok, v)
+				}
+			}
+		}
+		if (tc.sameSize && sameSize == 0) || doubled == 0 {
+			t.Fatalf("%s: %d same-size rehashes, %d doublings", tc.name, sameSize, doubled)
+		}
+		t.Logf("%s: %d groups, %d same-size rehashes, %d doublings", tc.name, len(m.groups), sameSize, doubled)
+	}
+}
+
+func TestGroupMatch(t *testing.T) {
+	var g ctrlGroup
+	for i := 0; i < groupSlots; i++ {
+		g.set(i, ctrlEmpty)
+	}
+	g.set(1, 0x12)
+	g.set(3, ctrlDeleted)
+	g.set(4, 0x12)
+	g.set(6, 0x7f)
+
+	slots := func(b bitset) []int {
+		var s []int
+		for ; b != 0; b = b.removeFirst() {
+			s = append(s, b.first())
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		name string
+		got  bitset
+		want string
+	}{
+		{"matchH2(0x12)", g.matchH2(0x12), "[1 4]"},
+		{"matchH2(0x7f)", g.matchH2(0x7f), "[6]"},
+		{"matchH2(0x00)", g.matchH2(0x00), "[]"},
+		{"matchEmpty", g.matchEmpty(), "[0 2 5 7]"},
+		{"matchEmptyOrDeleted", g.matchEmptyOrDeleted(), "[0 2 3 5 7]"},
+		{"matchFull", g.matchFull(), "[1 4 6]"},
+	} {
+		if got := fmt.Sprint(slots(tc.got)); got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+	if g.get(3) != ctrlDeleted || g.get(6) != 0x7f {
+		t.Fatalf("get: %#x %#x", g.get(3), g.get(6))
+	}
+}
+
+// matchH2 可能有假阳性，但绝不能漏掉真正的匹配
+func TestMatchH2NoFalseNegatives(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	ctrls := []uint8{ctrlEmpty, ctrlDeleted}
+	for i := 0; i < 10000; i++ {
+		var g ctrlGroup
+		for s := 0; s < groupSlots; s++ {
+			if r.IntN(3) == 0 {
+				g.set(s, ctrls[r.IntN(2)])
+			} else {
+				g.set(s, uint8(r.IntN(4))) // 取值范围小，制造相邻的相同字节
+			}
+		}
+		h2 := uint8(r.IntN(4))
+		match := g.matchH2(h2)
+		for s := 0; s < groupSlots; s++ {
+			if g.get(s) == h2 && match&(0x80<<(8*s)) == 0 {
+				t.Fatalf("ctrl %016x: slot %d not matched for h2 %#x", uint64(g), s, h2)
+			}
+		}
+	}
+}
+
+func TestProbeSeqVisitsEveryGroup(t *testing.T) {
+	for _, n := range []uint64{1, 2, 8, 64} {
+		seen := make(map[uint64]bool)
+		seq := makeProbeSeq(12345, n-1)
+		for i := uint64(0); i < n; i++ {
+			seen[seq.offset] = true
+			seq = seq.next()
+		}
+		if uint64(len(seen)) != n {
+			t.Fatalf("%d groups: visited %d", n, len(seen))
+		}
+	}
+}
+
+// 满的 group 里删除只能留墓碑；有空位的 group 里删除直接置空
+func TestDeleteTombstones(t *testing.T) {
+	one := func(k int) uint64 { return uint64(k) & 0x7f } // H1 全为 0，都从 group 0 开始探测
+	m := NewWithHash[int, int](0, one)
+	for i := 0; i < 7; i++ {
+		m.Set(i, i)
+	}
+	m.Delete(3) // group 0 还有一个 empty 槽位
+	if m.tombstones != 0 || m.groups[0].ctrl.get(3) != ctrlEmpty {
+		t.Fatalf("tombstones = %d ctrl = %016x", m.tombstones, uint64(m.groups[0].ctrl))
+	}
+
+	m.Set(3, 3)
+	m.Set(7, 7) // 第 8 个元素，超过 7/8，扩容到 2 个 group
+	if len(m.groups) != 2 {
+		t.Fatalf("groups = %d", len(m.groups))
+	}
+	for i := 8; i < 10; i++ {
+		m.Set(i, i) // group 0 放满后溢出到 group 1
+	}
+	if m.groups[0].ctrl.matchEmpty() != 0 {
+		t.Fatalf("group 0 not full: %016x", uint64(m.groups[0].ctrl))
+	}
+	m.Delete(2)
+	if m.tombstones != 1 {
+		t.Fatalf("tombstones = %d", m.tombstones)
+	}
+	// 墓碑后面的键仍然能找到，新键优先复用墓碑
+	for _, k := range []int{8, 9} {
+		if _, ok := m.Get(k); !ok {
+			t.Fatalf("Get(%d) after delete failed", k)
+		}
+	}
+	m.Set(100, 100)
+	if m.tombstones != 0 || m.groups[0].ctrl.get(2) != h2(one(100)) {
+		t.Fatalf("tombstone not reused: %016x", uint64(m.groups[0].ctrl))
+	}
+}
+
+// 反复插入删除不同的键，元素个数不变，表不应该无限变大
+func TestChurnDoesNotGrow(t *testing.T) {
+	m := New[int, int](100)
+	n := len(m.groups)
+	for i := 0; i < 100000; i++ {
+		m.Set(i, i)
+		if i >= 50 {
+			m.Delete(i - 50)
+		}
+	}
+	if m.Len() != 50 || len(m.groups) != n {
+		t.Fatalf("len = %d groups = %d, want %d", m.Len(), len(m.groups), n)
+	}
+}
+
+func TestClear(t *testing.T) {
+	m := New[int, int](0)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+	n := len(m.groups)
+	m.Clear()
+	if m.Len() != 0 || len(m.groups) != n {
+		t.Fatalf("len = %d groups = %d", m.Len(), len(m.groups))
+	}
+	if _, ok := m.Get(1); ok {
+		t.Fatal("Get after Clear")
+	}
+	checkCtrl(t, m)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+	if m.Len() != 100 || len(m.groups) != n {
+		t.Fatalf("refill: len = %d groups = %d", m.Len(), len(m.groups))
+	}
+	checkCtrl(t, m)
+}
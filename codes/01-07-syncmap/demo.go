@@ -52,12 +52,13 @@ func f1() {
 	sm.Store("key", 123)
 	if value, ok := sm.Load("key"); ok {
 		//fmt.Println(value + 1) // 会报错
-		fmt.Println(value.(int) + 1)
+		fmt.Println(value.(int) + 1) // 类型化的封装见 syncmap/
 	}
 
 }
 
 // LoadOrStore 的原子性
+// 并发更多 goroutine 的 -race 测试见 syncmap/syncmap_test.go 的 TestLoadOrStoreAtomicity
 func f2() {
 	fmt.Println("f2 ------------LoadOrStore 的原子性------------------")
 	var sm sync.Map
@@ -0,0 +1,150 @@
+// 类型化的 sync.Map
+//
+// demo.go 的 f1 中 value + 1 编译不过，必须写成 value.(int) + 1；
+// sync.Map 也没有“读取-修改-写回”的原子操作。SyncMap[K, V] 在 sync.Map 外面包一层：
+//
+//	方法签名使用 K、V，调用方不再需要类型断言
+//	Compute 基于 CompareAndSwap 的重试循环实现原子更新
+//
+// 内部存的是 *V 而不是 V：CAS 比较的是指针，V 是切片、map 这类不可比较的类型时 Compute 也能工作。
+package syncmap
+
+import "sync"
+
+type SyncMap[K comparable, V any] struct {
+	m sync.Map // K -> *V
+}
+
+func (m *SyncMap[K, V]) Load(key K) (value V, ok bool) {
+	p, ok := m.m.Load(key)
+	if !ok {
+		return value, false
+	}
+	return *p.(*V), true
+}
+
+func (m *SyncMap[K, V]) Store(key K, value V) {
+	m.m.Store(key, &value)
+}
+
+// LoadOrStore 键存在时返回已有的值和 true，否则存入 value 并返回 value 和 false
+func (m *SyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	p, loaded := m.m.LoadOrStore(key, &value)
+	return *p.(*V), loaded
+}
+
+// LoadAndDelete 删除键并返回删除前的值
+func (m *SyncMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	p, loaded := m.m.LoadAndDelete(key)
+	if !loaded {
+		return value, false
+	}
+	return *p.(*V), true
+}
+
+func (m *SyncMap[K, V]) Delete(key K) {
+	m.m.Delete(key)
+}
+
+// Swap 存入新值并返回旧值
+func (m *SyncMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	p, loaded := m.m.Swap(key, &value)
+	if !loaded {
+		return previous, false
+	}
+	return *p.(*V), true
+}
+
+// CompareAndSwap 当前值等于 old 时替换为 new。
+// 和 sync.Map 一样按值比较，V 的动态类型不可比较时会 panic。
+func (m *SyncMap[K, V]) CompareAndSwap(key K, old, new V) bool {
+	for {
+		p, ok := m.m.Load(key)
+		if !ok || any(*p.(*V)) != any(old) {
+			return false
+		}
+		// 比较的是指针：Load 之后有人写入了相等的新值也会重试，避免误判
+		if m.m.CompareAndSwap(key, p, &new) {
+			return true
+		}
+	}
+}
+
+// CompareAndDelete 当前值等于 old 时删除，比较规则同 CompareAndSwap
+func (m *SyncMap[K, V]) CompareAndDelete(key K, old V) bool {
+	for {
+		p, ok := m.m.Load(key)
+		if !ok || any(*p.(*V)) != any(old) {
+			return false
+		}
+		if m.m.CompareAndDelete(key, p) {
+			return true
+		}
+	}
+}
+
+// Compute 原子地更新 key：fn 收到当前值和是否存在，返回新值和是否删除。
+// 返回最终的值以及键是否仍然存在。
+//
+// 实现是乐观的 CAS 重试：并发修改同一个键时 fn 可能被调用多次，
+// 所以 fn 不能有副作用，也不应该很耗时。
+func (m *SyncMap[K, V]) Compute(key K, fn func(old V, loaded bool) (newValue V, delete bool)) (V, bool) {
+	var zero V
+	for {
+		p, loaded := m.m.Load(key)
+		old := zero
+		if loaded {
+			old = *p.(*V)
+		}
+		newValue, del := fn(old, loaded)
+
+		switch {
+		case !loaded && del:
+			return zero, false
+		case !loaded:
+			if _, exists := m.m.LoadOrStore(key, &newValue); !exists {
+				return newValue, true
+			}
+		case del:
+			if m.m.CompareAndDelete(key, p) {
+				return zero, false
+			}
+		default:
+			if m.m.CompareAndSwap(key, p, &newValue) {
+				return newValue, true
+			}
+		}
+		// 期间有其它 goroutine 修改了该键，基于最新值重新计算
+	}
+}
+
+// Range 与 sync.Map.Range 相同，f 返回 false 时停止
+func (m *SyncMap[K, V]) Range(f func(key K, value V) bool) {
+	m.m.Range(func(k, p any) bool {
+		return f(k.(K), *p.(*V))
+	})
+}
+
+// Len 通过 Range 计数。sync.Map 不维护长度，并发修改时结果只是近似值，而且是 O(n)
+func (m *SyncMap[K, V]) Len() int {
+	n := 0
+	m.m.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Keys 返回当前所有的键，顺序不确定
+func (m *SyncMap[K, V]) Keys() []K {
+	var keys []K
+	m.m.Range(func(k, _ any) bool {
+		keys = append(keys, k.(K))
+		return true
+	})
+	return keys
+}
+
+func (m *SyncMap[K, V]) Clear() {
+	m.m.Clear()
+}
@@ -0,0 +1,171 @@
+package syncmap
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	cd syncmap
+	go test -v -race .
+*/
+
+func TestTypedAccess(t *testing.T) {
+	var sm SyncMap[string, int]
+	sm.Store("key", 123)
+	// 对应 demo.go 的 f1：不需要 value.(int)
+	if v, ok := sm.Load("key"); !ok || v+1 != 124 {
+		t.Fatalf("Load = %v, %v", v, ok)
+	}
+
+	if prev, loaded := sm.Swap("key", 200); !loaded || prev != 123 {
+		t.Fatalf("Swap = %v, %v", prev, loaded)
+	}
+	if prev, loaded := sm.Swap("new", 1); loaded || prev != 0 {
+		t.Fatalf("Swap on missing key = %v, %v", prev, loaded)
+	}
+
+	if sm.CompareAndSwap("key", 123, 300) {
+		t.Fatal("CompareAndSwap succeeded with a stale old value")
+	}
+	if !sm.CompareAndSwap("key", 200, 300) {
+		t.Fatal("CompareAndSwap failed with the current value")
+	}
+	if sm.CompareAndSwap("missing", 0, 1) {
+		t.Fatal("CompareAndSwap succeeded on a missing key")
+	}
+
+	if sm.CompareAndDelete("key", 200) || !sm.CompareAndDelete("key", 300) {
+		t.Fatal("CompareAndDelete did not compare values")
+	}
+	if v, loaded := sm.LoadAndDelete("new"); !loaded || v != 1 {
+		t.Fatalf("LoadAndDelete = %v, %v", v, loaded)
+	}
+	if sm.Len() != 0 {
+		t.Fatalf("Len = %d", sm.Len())
+	}
+}
+
+func TestKeysAndRange(t *testing.T) {
+	var sm SyncMap[string, string]
+	sm.Store("name", "Alice")
+	sm.Store("sex", "female")
+	sm.Store("city", "Beijing")
+
+	keys := sm.Keys()
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "city" || keys[2] != "sex" {
+		t.Fatalf("Keys = %v", keys)
+	}
+
+	n := 0
+	sm.Range(func(k, v string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("Range visited %d entries after returning false", n)
+	}
+
+	sm.Clear()
+	if sm.Len() != 0 {
+		t.Fatalf("Len = %d after Clear", sm.Len())
+	}
+}
+
+// 扩展 demo.go 的 f2：多个 goroutine 同时 LoadOrStore 同一个键，
+// 只有一个能写入成功，其它全部拿到它写入的值
+func TestLoadOrStoreAtomicity(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		var sm SyncMap[string, string]
+		const n = 64
+		results := make([]string, n)
+		stored := make([]bool, n)
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				<-start // 尽量让所有 goroutine 同时开始
+				v, loaded := sm.LoadOrStore("key", "value"+string(rune('A'+id%26)))
+				results[id], stored[id] = v, !loaded
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		winners := 0
+		final, _ := sm.Load("key")
+		for i := 0; i < n; i++ {
+			if stored[i] {
+				winners++
+			}
+			if results[i] != final {
+				t.Fatalf("goroutine %d saw %q, final value is %q", i, results[i], final)
+			}
+		}
+		if winners != 1 {
+			t.Fatalf("%d goroutines stored the key, want exactly 1", winners)
+		}
+	}
+}
+
+// Compute 的 CAS 重试保证并发自增不会丢失更新
+func TestComputeConcurrentIncrement(t *testing.T) {
+	var sm SyncMap[string, int]
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				sm.Compute("counter", func(old int, _ bool) (int, bool) { return old + 1, false })
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := sm.Load("counter"); v != 16*500 {
+		t.Fatalf("counter = %d, want %d", v, 16*500)
+	}
+}
+
+// V 是不可比较的切片时 Compute 依然可用
+func TestComputeNonComparable(t *testing.T) {
+	var sm SyncMap[string, []int]
+	appendN := func(n int) func([]int, bool) ([]int, bool) {
+		return func(old []int, _ bool) ([]int, bool) {
+			// 不能原地 append：失败重试时 old 仍可能被其它 goroutine 看到
+			next := make([]int, len(old), len(old)+1)
+			copy(next, old)
+			return append(next, n), false
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			sm.Compute("list", appendN(n))
+		}(i)
+	}
+	wg.Wait()
+	if v, _ := sm.Load("list"); len(v) != 100 {
+		t.Fatalf("len(list) = %d, want 100", len(v))
+	}
+
+	// 返回 delete=true 删除键
+	if _, ok := sm.Compute("list", func([]int, bool) ([]int, bool) { return nil, true }); ok {
+		t.Fatal("Compute delete reported the key present")
+	}
+	if _, ok := sm.Load("list"); ok {
+		t.Fatal("key still present")
+	}
+	// 键不存在时删除是空操作
+	if _, ok := sm.Compute("list", func([]int, bool) ([]int, bool) { return nil, true }); ok {
+		t.Fatal("Compute delete on missing key reported the key present")
+	}
+}
@@ -0,0 +1,337 @@
+// 带过期时间的缓存
+//
+// 在 sync.Map 上手写过期逻辑很容易出错：过期检查和删除不是原子的，
+// 后台清理协程如果没有退出条件，就会像 02-01-goroutine 的 f1 那样泄露。
+// Cache[K, V] 把这些收拢到一处：
+//
+//	每个条目有自己的 TTL，读取时发现过期就地删除（惰性过期）
+//	后台 janitor 定期清理没人读的过期条目，ctx 取消时退出
+//	条目被移除时回调 OnEvict
+//	GetOrLoad 对同一个键的并发加载去重（singleflight），只调用一次 loader
+//	统计命中、未命中、淘汰次数
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NoExpiration 作为 TTL 传入时条目永不过期
+const NoExpiration time.Duration = 0
+
+// ErrLoaderPanic 是 loader panic 时其它等待同一个键的调用方收到的错误
+var ErrLoaderPanic = errors.New("ttlcache: loader panicked")
+
+// Clock 提供当前时间，测试中用假时钟替换
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// EvictReason 说明条目为什么被移除
+type EvictReason int
+
+const (
+	Expired  EvictReason = iota // 过期
+	Deleted                     // 调用 Delete 或 Clear
+	Replaced                    // 被 Set 覆盖
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+type Options[K comparable, V any] struct {
+	// DefaultTTL 是 Set 和 GetOrLoad 使用的过期时间，NoExpiration 表示永不过期
+	DefaultTTL time.Duration
+	// CleanupInterval 是 janitor 的清理间隔，<= 0 时不启动 janitor，只做惰性过期
+	CleanupInterval time.Duration
+	// OnEvict 在条目被移除后调用，调用时不持有锁，可以在回调里访问缓存
+	OnEvict func(key K, value V, reason EvictReason)
+	// Clock 为 nil 时使用 time.Now
+	Clock Clock
+}
+
+// Stats 是累计的统计数据
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64 // 过期被移除的条目数，不含 Delete 和覆盖
+	Loads     int64 // GetOrLoad 实际调用 loader 的次数
+}
+
+type entry[V any] struct {
+	value    V
+	expireAt time.Time // 零值表示永不过期
+}
+
+func (e *entry[V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// 一次进行中的加载，后来者等待 done 关闭后读取结果
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// superseded 表示加载期间这个键被 Set、Delete 或 Clear 过：缓存里应该是那次写入的结果，
+	// 加载到的可能已经是旧数据，不再写入缓存。由持有 mu 的写入方设置。
+	superseded bool
+}
+
+type Cache[K comparable, V any] struct {
+	opts  Options[K, V]
+	clock Clock
+
+	mu      sync.Mutex
+	items   map[K]*entry[V]
+	loading map[K]*call[V]
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	loads     atomic.Int64
+
+	janitorDone chan struct{} // janitor 退出时关闭；没有 janitor 时为 nil
+}
+
+// New 创建缓存。CleanupInterval > 0 时启动 janitor，它随 ctx 取消而退出，
+// 所以调用方必须在不再使用缓存时取消 ctx。
+func New[K comparable, V any](ctx context.Context, opts Options[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		opts:    opts,
+		clock:   opts.Clock,
+		items:   make(map[K]*entry[V]),
+		loading: make(map[K]*call[V]),
+	}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+	if opts.CleanupInterval > 0 {
+		c.janitorDone = make(chan struct{})
+		go c.janitor(ctx, opts.CleanupInterval)
+	}
+	return c
+}
+
+func (c *Cache[K, V]) janitor(ctx context.Context, interval time.Duration) {
+	defer close(c.janitorDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.DeleteExpired()
+		}
+	}
+}
+
+// Done 返回的 channel 在 janitor 退出后关闭；没有启动 janitor 时返回 nil
+func (c *Cache[K, V]) Done() <-chan struct{} {
+	return c.janitorDone
+}
+
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.DefaultTTL)
+}
+
+// SetWithTTL 存入条目并单独指定过期时间，ttl <= 0 表示永不过期
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	old, ok := c.items[key]
+	c.items[key] = c.newEntry(value, ttl)
+	c.supersedeLocked(key)
+	c.mu.Unlock()
+	if ok {
+		c.evict(key, old.value, Replaced)
+	}
+}
+
+// supersedeLocked 让 key 正在进行的加载作废，它的结果不会覆盖刚才的写入
+func (c *Cache[K, V]) supersedeLocked(key K) {
+	if cl, ok := c.loading[key]; ok {
+		cl.superseded = true
+	}
+}
+
+func (c *Cache[K, V]) newEntry(value V, ttl time.Duration) *entry[V] {
+	e := &entry[V]{value: value}
+	if ttl > 0 {
+		e.expireAt = c.clock.Now().Add(ttl)
+	}
+	return e
+}
+
+// Get 返回未过期的值。读到过期条目时就地删除并触发 OnEvict
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	value, ok, evicted := c.getLocked(key)
+	c.mu.Unlock()
+	if evicted != nil {
+		c.evict(key, evicted.value, Expired)
+	}
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// getLocked 查找 key，条目已过期时把它从 map 中删掉并通过 evicted 返回，由调用方在解锁后回调
+func (c *Cache[K, V]) getLocked(key K) (value V, ok bool, evicted *entry[V]) {
+	e, ok := c.items[key]
+	if !ok {
+		return value, false, nil
+	}
+	if e.expired(c.clock.Now()) {
+		delete(c.items, key)
+		return value, false, e
+	}
+	return e.value, true, nil
+}
+
+// GetOrLoad 命中时直接返回；未命中时调用 loader 加载并以 DefaultTTL 存入。
+// 同一个键的并发调用只有一个会执行 loader，其余等待并共享结果。
+// loader 返回错误时不缓存，错误原样返回给所有等待者。
+// 加载期间对这个键的 Set、Delete 和 Clear 优先：加载结果照样返回给调用方，但不写入缓存。
+func (c *Cache[K, V]) GetOrLoad(key K, loader func(key K) (V, error)) (V, error) {
+	c.mu.Lock()
+	value, ok, evicted := c.getLocked(key)
+	if ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return value, nil
+	}
+	c.misses.Add(1)
+	if cl, loading := c.loading[key]; loading {
+		c.mu.Unlock()
+		if evicted != nil {
+			c.evict(key, evicted.value, Expired)
+		}
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.loading[key] = cl
+	c.mu.Unlock()
+	if evicted != nil {
+		c.evict(key, evicted.value, Expired)
+	}
+
+	c.loads.Add(1)
+	c.doLoad(key, cl, loader)
+	return cl.value, cl.err
+}
+
+func (c *Cache[K, V]) doLoad(key K, cl *call[V], loader func(K) (V, error)) {
+	normal := false
+	defer func() {
+		// loader panic 时也要唤醒等待者，panic 继续在当前 goroutine 传播
+		if !normal {
+			cl.err = ErrLoaderPanic
+		}
+		c.mu.Lock()
+		delete(c.loading, key)
+		var old *entry[V]
+		if cl.err == nil && !cl.superseded {
+			old = c.items[key]
+			c.items[key] = c.newEntry(cl.value, c.opts.DefaultTTL)
+		}
+		c.mu.Unlock()
+		close(cl.done)
+		if old != nil {
+			c.evict(key, old.value, Replaced)
+		}
+	}()
+	cl.value, cl.err = loader(key)
+	normal = true
+}
+
+// Delete 删除 key，键存在时以 Deleted 回调 OnEvict
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	delete(c.items, key)
+	c.supersedeLocked(key)
+	c.mu.Unlock()
+	if ok {
+		c.evict(key, e.value, Deleted)
+	}
+}
+
+// DeleteExpired 删除所有过期条目，janitor 定期调用它
+func (c *Cache[K, V]) DeleteExpired() {
+	type kv struct {
+		key   K
+		value V
+	}
+	var removed []kv
+	now := c.clock.Now()
+	c.mu.Lock()
+	for k, e := range c.items {
+		if e.expired(now) {
+			delete(c.items, k)
+			removed = append(removed, kv{k, e.value})
+		}
+	}
+	c.mu.Unlock()
+	for _, r := range removed {
+		c.evict(r.key, r.value, Expired)
+	}
+}
+
+// Clear 删除所有条目，每个条目都以 Deleted 回调 OnEvict
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	items := c.items
+	c.items = make(map[K]*entry[V])
+	for _, cl := range c.loading {
+		cl.superseded = true
+	}
+	c.mu.Unlock()
+	for k, e := range items {
+		c.evict(k, e.value, Deleted)
+	}
+}
+
+// Len 返回条目数，包括已过期但还没被清理的条目
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Loads:     c.loads.Load(),
+	}
+}
+
+func (c *Cache[K, V]) evict(key K, value V, reason EvictReason) {
+	if reason == Expired {
+		c.evictions.Add(1)
+	}
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(key, value, reason)
+	}
+}
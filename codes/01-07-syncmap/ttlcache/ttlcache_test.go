@@ -0,0 +1,360 @@
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd ttlcache
+	go test -v -race .
+*/
+
+// 假时钟：时间只在调用 Advance 时前进
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+type eviction struct {
+	key    string
+	value  int
+	reason EvictReason
+}
+
+// 记录 OnEvict 回调
+type recorder struct {
+	mu     sync.Mutex
+	events []eviction
+}
+
+func (r *recorder) onEvict(k string, v int, reason EvictReason) {
+	r.mu.Lock()
+	r.events = append(r.events, eviction{k, v, reason})
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []eviction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]eviction(nil), r.events...)
+}
+
+func TestLazyExpiry(t *testing.T) {
+	clock := newFakeClock()
+	rec := &recorder{}
+	c := New(context.Background(), Options[string, int]{
+		DefaultTTL: time.Minute,
+		OnEvict:    rec.onEvict,
+		Clock:      clock,
+	})
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, 10*time.Second)
+	c.SetWithTTL("forever", 3, NoExpiration)
+
+	clock.Advance(10 * time.Second)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should expire exactly at its TTL")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+
+	clock.Advance(time.Hour)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a should have expired")
+	}
+	if v, ok := c.Get("forever"); !ok || v != 3 {
+		t.Fatalf("Get(forever) = %v, %v", v, ok)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("Len = %d, expired entries were not removed on read", c.Len())
+	}
+
+	want := []eviction{{"b", 2, Expired}, {"a", 1, Expired}}
+	if got := rec.get(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("evictions = %v, want %v", got, want)
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 2 || s.Evictions != 2 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestEvictReasons(t *testing.T) {
+	rec := &recorder{}
+	c := New(context.Background(), Options[string, int]{OnEvict: rec.onEvict})
+	c.Set("a", 1)
+	c.Set("a", 2)
+	c.Delete("a")
+	c.Delete("missing") // 不存在的键不回调
+	c.Set("b", 3)
+	c.Clear()
+
+	want := []eviction{{"a", 1, Replaced}, {"a", 2, Deleted}, {"b", 3, Deleted}}
+	got := rec.get()
+	if len(got) != len(want) {
+		t.Fatalf("evictions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("evictions = %v, want %v", got, want)
+		}
+	}
+	// 只有过期才计入 Evictions
+	if s := c.Stats(); s.Evictions != 0 {
+		t.Fatalf("Evictions = %d", s.Evictions)
+	}
+}
+
+// 回调中可以再次访问缓存，不会死锁
+func TestCallbackMayUseCache(t *testing.T) {
+	clock := newFakeClock()
+	var c *Cache[string, int]
+	c = New(context.Background(), Options[string, int]{
+		DefaultTTL: time.Second,
+		Clock:      clock,
+		OnEvict: func(k string, v int, reason EvictReason) {
+			if reason == Expired {
+				c.SetWithTTL(k+"-archived", v, NoExpiration)
+			}
+		},
+	})
+	c.Set("a", 1)
+	clock.Advance(time.Second)
+	c.DeleteExpired()
+	if v, ok := c.Get("a-archived"); !ok || v != 1 {
+		t.Fatalf("Get(a-archived) = %v, %v", v, ok)
+	}
+}
+
+func TestJanitorCleansAndStops(t *testing.T) {
+	clock := newFakeClock()
+	evicted := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(ctx, Options[string, int]{
+		DefaultTTL:      time.Minute,
+		CleanupInterval: time.Millisecond,
+		Clock:           clock,
+		OnEvict:         func(k string, _ int, _ EvictReason) { evicted <- k },
+	})
+	c.Set("a", 1)
+	clock.Advance(time.Minute)
+
+	// 没人读 a，janitor 也会把它清掉
+	select {
+	case k := <-evicted:
+		if k != "a" {
+			t.Fatalf("evicted %q", k)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("janitor did not remove the expired entry")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+
+	// 与 02-01-goroutine 的 f1 不同，取消 ctx 后 janitor 会退出
+	cancel()
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("janitor still running after cancel")
+	}
+
+	if New(context.Background(), Options[string, int]{}).Done() != nil {
+		t.Fatal("Done should be nil without a janitor")
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	clock := newFakeClock()
+	c := New(context.Background(), Options[string, int]{DefaultTTL: time.Minute, Clock: clock})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(k string) (int, error) {
+		calls.Add(1)
+		<-release // 让所有调用方都在加载期间到达
+		return len(k), nil
+	}
+
+	const n = 50
+	var started, wg sync.WaitGroup
+	results := make([]int, n)
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			v, err := c.GetOrLoad("hello", loader)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}(i)
+	}
+	started.Wait()
+	// 等到所有 goroutine 都记了一次未命中，说明它们要么在加载、要么在等待
+	for c.Stats().Misses < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("loader called %d times, want 1", calls.Load())
+	}
+	for i, v := range results {
+		if v != 5 {
+			t.Fatalf("result %d = %d", i, v)
+		}
+	}
+
+	// 加载结果以 DefaultTTL 缓存
+	if v, err := c.GetOrLoad("hello", loader); err != nil || v != 5 || calls.Load() != 1 {
+		t.Fatalf("cached GetOrLoad = %v, %v, calls=%d", v, err, calls.Load())
+	}
+	clock.Advance(time.Minute)
+	if _, err := c.GetOrLoad("hello", loader); err != nil || calls.Load() != 2 {
+		t.Fatalf("expired entry not reloaded, calls=%d", calls.Load())
+	}
+	if s := c.Stats(); s.Loads != 2 || s.Hits != 1 || s.Evictions != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	c := New(context.Background(), Options[string, int]{})
+	errBoom := errors.New("boom")
+	if _, err := c.GetOrLoad("k", func(string) (int, error) { return 0, errBoom }); err != errBoom {
+		t.Fatalf("err = %v", err)
+	}
+	// 错误不缓存
+	if c.Len() != 0 {
+		t.Fatal("failed load was cached")
+	}
+	if v, err := c.GetOrLoad("k", func(string) (int, error) { return 7, nil }); err != nil || v != 7 {
+		t.Fatalf("GetOrLoad = %v, %v", v, err)
+	}
+}
+
+// 加载期间的 Set 和 Delete 不会被加载结果覆盖：loader 读到的可能是写入之前的旧数据
+func TestGetOrLoadDuringWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(c *Cache[string, int])
+		want  int // 加载结束后缓存里的值，0 表示没有
+	}{
+		{"set", func(c *Cache[string, int]) { c.Set("k", 2) }, 2},
+		{"delete", func(c *Cache[string, int]) { c.Delete("k") }, 0},
+		{"clear", func(c *Cache[string, int]) { c.Clear() }, 0},
+	} {
+		rec := &recorder{}
+		c := New(context.Background(), Options[string, int]{OnEvict: rec.onEvict})
+		v, err := c.GetOrLoad("k", func(string) (int, error) {
+			tc.write(c)
+			return 1, nil
+		})
+		if err != nil || v != 1 {
+			t.Fatalf("%s: GetOrLoad = %v, %v", tc.name, v, err)
+		}
+		got, ok := c.Get("k")
+		if tc.want == 0 && ok || tc.want != 0 && got != tc.want {
+			t.Fatalf("%s: after load Get = %v, %v, want %v", tc.name, got, ok, tc.want)
+		}
+		// 加载结果没有进过缓存，也就不会有它的 OnEvict
+		if ev := rec.get(); len(ev) != 0 {
+			t.Fatalf("%s: evictions = %+v", tc.name, ev)
+		}
+		// 下一次加载不受影响
+		c.Delete("k")
+		if v, _ := c.GetOrLoad("k", func(string) (int, error) { return 3, nil }); v != 3 {
+			t.Fatalf("%s: reload = %v", tc.name, v)
+		}
+		if v, ok := c.Get("k"); !ok || v != 3 {
+			t.Fatalf("%s: reload not cached: %v, %v", tc.name, v, ok)
+		}
+	}
+}
+
+// Set 和另一个 goroutine 里的加载并发：不管谁先结束，Set 之后缓存里都是 Set 的值
+func TestGetOrLoadConcurrentSet(t *testing.T) {
+	c := New(context.Background(), Options[string, int]{})
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad("k", func(string) (int, error) {
+			close(loading)
+			<-release
+			return 1, nil
+		})
+		done <- v
+	}()
+	<-loading
+	c.Set("k", 2)
+	close(release)
+	if v := <-done; v != 1 {
+		t.Fatalf("loader result = %d", v)
+	}
+	if v, ok := c.Get("k"); !ok || v != 2 {
+		t.Fatalf("Get = %v, %v, want the value from Set", v, ok)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := New(context.Background(), Options[string, int]{})
+	entered := make(chan struct{})
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		<-entered
+		_, err := c.GetOrLoad("k", func(string) (int, error) { return 1, nil })
+		waiterErr <- err
+	}()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was swallowed")
+			}
+		}()
+		c.GetOrLoad("k", func(string) (int, error) {
+			close(entered)
+			for c.Stats().Misses < 2 { // 等待者已经挂在这次加载上
+				time.Sleep(time.Millisecond)
+			}
+			panic("loader bug")
+		})
+	}()
+
+	if err := <-waiterErr; err != ErrLoaderPanic {
+		t.Fatalf("waiter err = %v, want ErrLoaderPanic", err)
+	}
+	// panic 之后键可以重新加载
+	if v, err := c.GetOrLoad("k", func(string) (int, error) { return 2, nil }); err != nil || v != 2 {
+		t.Fatalf("GetOrLoad after panic = %v, %v", v, err)
+	}
+}
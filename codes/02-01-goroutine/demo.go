@@ -11,6 +11,7 @@ func main() {
 }
 
 // goroutine泄露
+// 在测试中发现这类泄露见 leakcheck/
 func f1() {
 	ch := make(chan int)
 
@@ -21,11 +22,13 @@ func f1() {
 }
 
 // 使用 sync.WaitGroup 或 context.Context 管理 Goroutine 生命周期。
+// 子 goroutine 出错、panic 后按策略重启见 supervisor/
 func f2() {
 
 }
 
 // goroutine 调度问题
+// 循环卡住或悄悄退出时怎样发现见 watchdog/
 func f3() {
 	go func() {
 		for {
@@ -0,0 +1,3 @@
+module leakcheck
+
+go 1.21
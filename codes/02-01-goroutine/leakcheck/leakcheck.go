@@ -0,0 +1,161 @@
+// goroutine 泄露检测
+//
+// demo.go 的 f1 和 ../../02-03-select/demo.go 的 f1 都会留下永远阻塞在发送上的 goroutine，
+// 程序照常运行，没有任何报错。VerifyNone 在测试开始时记下已有的 goroutine，
+// 测试结束（t.Cleanup）时再看一次，多出来的就是泄露：
+//
+//	goroutine 退出需要时间，检查会在宽限期内反复重试，全部退出就算通过
+//	os/signal、runtime/trace、testing 自己启动的 goroutine 不算
+//	报告每个泄露的 goroutine 阻塞在哪一行（第一个不在标准库中的帧）、由哪一行的 go 语句创建
+//
+// 用法：
+//
+//	func TestX(t *testing.T) {
+//		leakcheck.VerifyNone(t)
+//		...
+//	}
+//
+// 只比较测试前后的差异，所以 t.Parallel 的测试同时运行时可能把别的测试的 goroutine 算进来。
+package leakcheck
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// TB 是 *testing.T、*testing.B 都满足的最小接口
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+type config struct {
+	grace   time.Duration
+	ignores []func(*Goroutine) bool
+}
+
+type Option func(*config)
+
+// WithGrace 设置宽限期，默认 1 秒
+func WithGrace(d time.Duration) Option {
+	return func(c *config) { c.grace = d }
+}
+
+// IgnoreTopFunction 忽略栈顶（或阻塞帧）是 fn 的 goroutine，fn 是完整的函数名，如 "net/http.(*persistConn).readLoop"
+func IgnoreTopFunction(fn string) Option {
+	return func(c *config) {
+		c.ignores = append(c.ignores, func(g *Goroutine) bool {
+			return (len(g.Frames) > 0 && g.Frames[0].Function == fn) || g.BlockedAt().Function == fn
+		})
+	}
+}
+
+// IgnoreCreatedBy 忽略由 fn 中的 go 语句创建的 goroutine
+func IgnoreCreatedBy(fn string) Option {
+	return func(c *config) {
+		c.ignores = append(c.ignores, func(g *Goroutine) bool { return g.CreatedBy.Function == fn })
+	}
+}
+
+// 标准库常驻的 goroutine，按栈顶函数精确匹配。
+// GC、finalizer 这些运行时自己的系统 goroutine 不会出现在 runtime.Stack 的输出里，不用列出来。
+// 不能按 "runtime." 前缀忽略：go 语句直接调用标准库函数时栈上没有用户代码，
+// 栈顶是阻塞它的运行时函数，那样真正的泄露也会被跳过。
+var builtinTopFunctions = []string{
+	"os/signal.signal_recv", // signal.Notify 启动的收信号的 goroutine
+	"os/signal.loop",
+	"runtime.ReadTrace", // runtime/trace.Start 启动的读 trace 的 goroutine
+}
+
+// 测试框架和 runtime/trace 的 goroutine，按栈顶函数或创建者的前缀匹配
+var builtinIgnores = []string{
+	"runtime/trace.",
+	"testing.(*T).Run",
+	"testing.(*M).",
+	"testing.runTests",
+	"testing.runFuzzing",
+}
+
+func (c *config) ignored(g *Goroutine) bool {
+	if len(g.Frames) > 0 && slices.Contains(builtinTopFunctions, g.Frames[0].Function) {
+		return true
+	}
+	for _, prefix := range builtinIgnores {
+		if strings.HasPrefix(g.CreatedBy.Function, prefix) {
+			return true
+		}
+		if len(g.Frames) > 0 && strings.HasPrefix(g.Frames[0].Function, prefix) && !isUserBlocked(g) {
+			return true
+		}
+	}
+	for _, ignore := range c.ignores {
+		if ignore(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUserBlocked：栈上有用户代码的帧，说明是用户的 goroutine 阻塞在了 runtime 里（如 runtime.Gosched、runtime.Goexit）
+func isUserBlocked(g *Goroutine) bool {
+	return !isStdlib(g.BlockedAt().File)
+}
+
+// Snapshot 是某一时刻存在的 goroutine 集合
+type Snapshot map[int64]bool
+
+// Take 记下当前所有 goroutine（不含调用者自己）
+func Take() Snapshot {
+	s := make(Snapshot)
+	for _, g := range current() {
+		s[g.ID] = true
+	}
+	return s
+}
+
+// Leaked 返回不在 s 中、也没有被忽略的 goroutine。宽限期内反复检查，直到没有泄露或者超时。
+func (s Snapshot) Leaked(opts ...Option) []*Goroutine {
+	c := &config{grace: time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	deadline := time.Now().Add(c.grace)
+	wait := time.Millisecond
+	for {
+		var leaked []*Goroutine
+		for _, g := range current() {
+			if !s[g.ID] && !c.ignored(g) {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(wait)
+		if wait < 100*time.Millisecond {
+			wait *= 2
+		}
+	}
+}
+
+// VerifyNone 在测试结束时检查是否有新的 goroutine 残留，有则通过 t.Errorf 报告。
+// 要在测试一开始调用，之前启动的 goroutine 不会被检查。
+func VerifyNone(t TB, opts ...Option) {
+	t.Helper()
+	before := Take()
+	t.Cleanup(func() {
+		t.Helper()
+		leaked := before.Leaked(opts...)
+		if len(leaked) == 0 {
+			return
+		}
+		var sb strings.Builder
+		for _, g := range leaked {
+			sb.WriteString("\n")
+			sb.WriteString(g.String())
+		}
+		t.Errorf("leakcheck: %d goroutine(s) leaked:%s", len(leaked), sb.String())
+	})
+}
@@ -0,0 +1,246 @@
+package leakcheck
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd leakcheck
+	go test -v .
+*/
+
+// fakeT 记录报告，手动执行 Cleanup
+type fakeT struct {
+	errs     []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+// 02-01-goroutine/demo.go 的 f1：没人接收，发送方永远阻塞
+func goroutineF1() {
+	ch := make(chan int)
+
+	// Goroutine 会一直阻塞
+	go func() {
+		ch <- 1
+	}()
+}
+
+// 02-03-select/demo.go 的 f1：select 只接收了 ch1，ch2 的发送方永远阻塞。
+// demo 最后还会 close(ch2) 让发送方 panic，这里停在泄露已经发生的时刻。
+func selectF1() {
+	ch1 := make(chan int)
+	ch2 := make(chan int)
+
+	go func() {
+		ch1 <- 1
+	}()
+
+	go func() {
+		ch2 <- 2
+	}()
+
+	select {
+	case <-ch1:
+	}
+}
+
+func TestDemoLeaksAreReported(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		run     func()
+		blocked string
+		created string
+	}{
+		{"02-01-goroutine f1", goroutineF1, "leakcheck.goroutineF1.func1", "leakcheck.goroutineF1"},
+		{"02-03-select f1", selectF1, "leakcheck.selectF1.func2", "leakcheck.selectF1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ft := &fakeT{}
+			VerifyNone(ft, WithGrace(50*time.Millisecond))
+			tc.run()
+			ft.finish()
+
+			if len(ft.errs) != 1 {
+				t.Fatalf("reports: %q", ft.errs)
+			}
+			report := ft.errs[0]
+			t.Log(report)
+			for _, want := range []string{
+				"1 goroutine(s) leaked",
+				"[chan send]",
+				"blocked at: " + tc.blocked + " (",
+				"created at: " + tc.created + " (",
+				"leakcheck_test.go:",
+			} {
+				if !strings.Contains(report, want) {
+					t.Errorf("report does not contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+// 测试结束时还没退出、但很快就会退出的 goroutine 不算泄露
+func TestGracePeriod(t *testing.T) {
+	ft := &fakeT{}
+	VerifyNone(ft)
+	go time.Sleep(30 * time.Millisecond)
+	ft.finish()
+	if len(ft.errs) != 0 {
+		t.Fatalf("reported a goroutine that exits: %q", ft.errs)
+	}
+}
+
+func TestIgnoreOptions(t *testing.T) {
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(release)
+
+	ft := &fakeT{}
+	VerifyNone(ft, WithGrace(10*time.Millisecond),
+		IgnoreTopFunction("leakcheck.blockOn"),
+		IgnoreCreatedBy("leakcheck.startWorker"),
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		blockOn(release)
+	}()
+	startWorker(&wg, release)
+	ft.finish()
+	if len(ft.errs) != 0 {
+		t.Fatalf("ignored goroutines reported: %q", ft.errs)
+	}
+}
+
+func blockOn(ch chan struct{}) { <-ch }
+
+func startWorker(wg *sync.WaitGroup, ch chan struct{}) {
+	go func() {
+		defer wg.Done()
+		<-ch
+	}()
+}
+
+// 阻塞在标准库里时，报告的是调用标准库的那一帧
+func TestBlockedAtSkipsStdlib(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	done := make(chan struct{})
+	before := Take()
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+	leaked := before.Leaked(WithGrace(10 * time.Millisecond))
+	wg.Done()
+	<-done
+
+	if len(leaked) != 1 {
+		t.Fatalf("leaked: %v", leaked)
+	}
+	g := leaked[0]
+	if g.State != "sync.WaitGroup.Wait" || g.Frames[0].Function == g.BlockedAt().Function {
+		t.Fatalf("state %q, frames %v", g.State, g.Frames)
+	}
+	if got := g.BlockedAt().Function; got != "leakcheck.TestBlockedAtSkipsStdlib.func1" {
+		t.Fatalf("BlockedAt = %s", got)
+	}
+}
+
+// go 语句直接调用标准库函数：栈上没有用户代码，只有 created by 指向用户代码，也要报告
+func TestBareStdlibCallIsReported(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ft := &fakeT{}
+	VerifyNone(ft, WithGrace(10*time.Millisecond))
+	go wg.Wait()
+	ft.finish()
+	wg.Done()
+	if len(ft.errs) != 1 || !strings.Contains(ft.errs[0], "created at: leakcheck.TestBareStdlibCallIsReported (") {
+		t.Fatalf("reports: %q", ft.errs)
+	}
+}
+
+// 栈顶是运行时函数的 goroutine 不一定是运行时自己的，只有列出来的才忽略
+func TestRuntimeTopFunction(t *testing.T) {
+	src := goroot + "runtime/"
+	for _, tc := range []struct {
+		stack   string
+		ignored bool
+	}{
+		{`goroutine 9 [chan send]:
+runtime.chansend1(0xc000020060, 0x4d3a20)
+	` + src + `chan.go:161 +0x1d
+created by main.main in goroutine 1
+	/src/app/main.go:10 +0x99`, false},
+		{`goroutine 7 [syscall]:
+os/signal.signal_recv()
+	` + src + `sigqueue.go:152 +0x98
+os/signal.loop()
+	` + goroot + `os/signal/signal_unix.go:23 +0x13
+created by os/signal.Notify.func2.1 in goroutine 1
+	` + goroot + `os/signal/signal.go:164 +0x1f`, true},
+	} {
+		g := parse(tc.stack)
+		if got := (&config{}).ignored(g); got != tc.ignored {
+			t.Errorf("%s: ignored = %v, want %v", g.Frames[0].Function, got, tc.ignored)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	g := parse(`goroutine 42 [chan receive, 3 minutes]:
+main.(*server).loop(0xc000010000, {0x1, 0x2})
+	/src/app/server.go:88 +0x45
+...additional frames elided...
+main.run(...)
+	/src/app/main.go:12
+created by main.main in goroutine 1
+	/src/app/main.go:10 +0x99`)
+	if g == nil || g.ID != 42 || g.State != "chan receive" {
+		t.Fatalf("parsed %+v", g)
+	}
+	want := []Frame{
+		{"main.(*server).loop", "/src/app/server.go", 88},
+		{"main.run", "/src/app/main.go", 12},
+	}
+	if fmt.Sprint(g.Frames) != fmt.Sprint(want) {
+		t.Fatalf("frames = %v", g.Frames)
+	}
+	if g.CreatedBy != (Frame{"main.main", "/src/app/main.go", 10}) {
+		t.Fatalf("created by = %v", g.CreatedBy)
+	}
+}
+
+// 正常使用：没有泄露时测试通过
+func TestVerifyNoneClean(t *testing.T) {
+	VerifyNone(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,138 @@
+package leakcheck
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Goroutine 是从 runtime.Stack 的输出中解析出的一个 goroutine
+type Goroutine struct {
+	ID        int64
+	State     string  // 方括号里的状态，如 "chan send"、"select"、"sync.WaitGroup.Wait"
+	Frames    []Frame // 从栈顶开始
+	CreatedBy Frame   // go 语句所在的位置；main goroutine 为空
+	Stack     string  // 原始文本
+}
+
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f Frame) String() string {
+	if f.Function == "" {
+		return "unknown"
+	}
+	return f.Function + " (" + f.File + ":" + strconv.Itoa(f.Line) + ")"
+}
+
+// BlockedAt 返回栈上第一个不在标准库中的帧，也就是用户代码阻塞的位置；
+// 全部是标准库的帧时返回栈顶
+func (g *Goroutine) BlockedAt() Frame {
+	for _, f := range g.Frames {
+		if !isStdlib(f.File) {
+			return f
+		}
+	}
+	if len(g.Frames) > 0 {
+		return g.Frames[0]
+	}
+	return Frame{}
+}
+
+func (g *Goroutine) String() string {
+	return "goroutine " + strconv.FormatInt(g.ID, 10) + " [" + g.State + "]:\n" +
+		"    blocked at: " + g.BlockedAt().String() + "\n" +
+		"    created at: " + g.CreatedBy.String()
+}
+
+// goroot 用标准库函数所在的源文件推算，比 runtime.GOROOT() 可靠：
+// 测试二进制可能在另一台机器上运行，但栈里的路径总是编译时的
+var goroot = func() string {
+	file, _ := runtime.FuncForPC(reflect.ValueOf(runtime.Stack).Pointer()).FileLine(0)
+	return filepath.ToSlash(filepath.Dir(filepath.Dir(file))) + "/" // .../src/runtime/mprof.go -> .../src/
+}()
+
+func isStdlib(file string) bool {
+	return strings.HasPrefix(filepath.ToSlash(file), goroot)
+}
+
+// current 返回除调用者自己以外的所有 goroutine
+func current() []*Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	blocks := bytes.Split(buf, []byte("\n\n"))
+	gs := make([]*Goroutine, 0, len(blocks))
+	for _, b := range blocks[1:] { // 第一个总是调用 runtime.Stack 的 goroutine
+		if g := parse(string(b)); g != nil {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// parse 解析一段
+//
+//	goroutine 7 [chan send]:
+//	main.main.func1()
+//		/tmp/x.go:12 +0x1e
+//	created by main.main in goroutine 1
+//		/tmp/x.go:12 +0x76
+func parse(s string) *Goroutine {
+	s = strings.TrimSpace(s)
+	header, rest, _ := strings.Cut(s, "\n")
+	idText, state, ok := strings.Cut(strings.TrimPrefix(header, "goroutine "), " [")
+	if !ok {
+		return nil
+	}
+	idText, _, _ = strings.Cut(idText, " ") // GOTRACEBACK=system 时后面还有 gp=... m=...
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		return nil
+	}
+	g := &Goroutine{ID: id, State: strings.TrimSuffix(state, "]:"), Stack: s}
+	// 状态后面可能带着附加信息，如 "chan send, 2 minutes"、"syscall, locked to thread"
+	g.State, _, _ = strings.Cut(g.State, ", ")
+
+	lines := strings.Split(rest, "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "...") { // "...additional frames elided..."
+			i--
+			continue
+		}
+		fn, loc := lines[i], strings.TrimSpace(lines[i+1])
+		frame := Frame{File: loc}
+		if j := strings.LastIndex(loc, " +0x"); j >= 0 {
+			loc = loc[:j]
+		}
+		if j := strings.LastIndexByte(loc, ':'); j >= 0 {
+			frame.File = loc[:j]
+			frame.Line, _ = strconv.Atoi(loc[j+1:])
+		}
+		if creator, ok := strings.CutPrefix(fn, "created by "); ok {
+			creator, _, _ = strings.Cut(creator, " in goroutine ")
+			frame.Function = creator
+			g.CreatedBy = frame
+			break
+		}
+		// 去掉参数：main.f(0x1, ...) -> main.f
+		if j := strings.LastIndexByte(fn, '('); j > 0 {
+			fn = fn[:j]
+		}
+		frame.Function = fn
+		g.Frames = append(g.Frames, frame)
+	}
+	return g
+}
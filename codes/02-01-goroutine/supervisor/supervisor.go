@@ -0,0 +1,294 @@
+// Erlang 风格的 supervisor：子 goroutine 出错或 panic 时按策略重启
+//
+// 知识点/03-并发/01-goroutine.md 的“Goroutine 的 panic 传播”说 panic 不会影响其他 goroutine，这不对：
+// 没有被 recover 的 panic 会让整个进程退出，不管它发生在哪个 goroutine。demo 里的 go func() 启动后就没人管了，
+// 出错没人知道，panic 会带走整个程序。Supervisor 负责启动一组子任务并盯着它们：
+//
+//	子任务是 func(ctx) error，panic 被 recover 成 *PanicError，和返回错误一样处理
+//	子任务退出后按 Restart 决定要不要重启，按 Strategy 决定连带重启哪些：
+//	  OneForOne 只重启它自己；OneForAll 全部重启；RestForOne 重启它和排在它后面（依赖它）的
+//	重启前等一段退避时间，Period 内连续重启越多等得越久（指数增长，上限 MaxBackoff）
+//	Period 内重启超过 Intensity 次说明重启解决不了问题，停掉所有子任务，Run 返回 ErrTooManyRestarts
+//	停止时按启动的相反顺序一个个停：先取消它的 ctx，最多等 Shutdown，再停下一个
+//
+// Go 没有办法杀掉一个 goroutine，等不到退出的子任务只能放弃，Run 的返回值里会有 ErrShutdownTimeout。
+// Run 本身也是 func(ctx) error，可以作为另一个 Supervisor 的子任务，组成 supervisor 树。
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+	ErrShutdownTimeout = errors.New("supervisor: shutdown timeout")
+)
+
+// PanicError 是子任务 panic 时的错误
+type PanicError struct {
+	Value any
+	Stack []byte // panic 时的栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+type Strategy int
+
+const (
+	OneForOne Strategy = iota
+	OneForAll
+	RestForOne
+)
+
+// Restart 决定子任务退出后要不要重启
+type Restart int
+
+const (
+	Permanent Restart = iota // 总是重启，正常返回也重启
+	Transient                // 返回错误或 panic 时重启，正常返回（nil）就结束
+	Temporary                // 从不重启，也不会因为别的子任务被连带重启
+)
+
+// Child 描述一个子任务
+type Child struct {
+	Name    string
+	Run     func(ctx context.Context) error // ctx 被取消时应该尽快返回
+	Restart Restart
+	// Shutdown 是停止时等它退出的时间，默认 5 秒
+	Shutdown time.Duration
+}
+
+// Event 是一次子任务的意外退出（不是 Supervisor 自己停掉的）
+type Event struct {
+	Child     string
+	Err       error // nil 表示正常返回
+	Restarted bool  // 是否因此重启了子任务
+}
+
+type Options struct {
+	Strategy Strategy
+	// Period 内最多重启 Intensity 次，默认 5 秒 3 次
+	Intensity int
+	Period    time.Duration
+	// Backoff 是第一次重启前的等待时间，之后每次翻倍，最多 MaxBackoff。0 表示立即重启。
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// OnEvent 在 Run 所在的 goroutine 里调用，可以为 nil
+	OnEvent func(Event)
+}
+
+type Supervisor struct {
+	opts     Options
+	children []Child
+}
+
+// New 创建 Supervisor，children 按顺序启动，按相反顺序停止
+func New(opts Options, children ...Child) *Supervisor {
+	if opts.Intensity <= 0 {
+		opts.Intensity = 3
+	}
+	if opts.Period <= 0 {
+		opts.Period = 5 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 32 * opts.Backoff
+	}
+	children = append([]Child(nil), children...)
+	for i := range children {
+		if children[i].Shutdown <= 0 {
+			children[i].Shutdown = 5 * time.Second
+		}
+	}
+	return &Supervisor{opts: opts, children: children}
+}
+
+// child 是一个子任务当前的运行状态
+type child struct {
+	spec     Child
+	gen      int // 每次启动加一，用来丢掉已经停掉的那一次的退出消息
+	running  bool
+	finished bool // 不需要再启动了
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+type exit struct {
+	idx, gen int
+	err      error
+}
+
+// run 是一次 Run 调用的状态，同一个 Supervisor 可以在上一次 Run 返回后再次 Run
+type run struct {
+	*Supervisor
+	ctx      context.Context
+	children []*child
+	exits    chan exit
+	quit     chan struct{} // Run 返回时关闭，放弃的子任务不会卡在发送 exit 上
+	restarts []time.Time
+}
+
+// Run 启动所有子任务，直到 ctx 被取消或者重启次数超过限制。
+// ctx 被取消时按相反顺序停止子任务，都按时退出时返回 nil。
+func (s *Supervisor) Run(ctx context.Context) error {
+	r := &run{
+		Supervisor: s,
+		ctx:        ctx,
+		exits:      make(chan exit),
+		quit:       make(chan struct{}),
+	}
+	defer close(r.quit)
+	for _, spec := range s.children {
+		r.children = append(r.children, &child{spec: spec})
+	}
+	for i := range r.children {
+		r.start(i, 0)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return r.stop(0)
+		case e := <-r.exits:
+			c := r.children[e.idx]
+			if !c.running || e.gen != c.gen || ctx.Err() != nil {
+				continue // 已经被停掉的那一次；或者 ctx 已经取消，下一轮会去停止所有子任务
+			}
+			c.running = false
+			restart := c.spec.Restart == Permanent || (c.spec.Restart == Transient && e.err != nil)
+			c.finished = !restart
+			if !restart {
+				r.event(Event{Child: c.spec.Name, Err: e.err})
+				continue
+			}
+			if err := r.tooMany(); err != nil {
+				r.event(Event{Child: c.spec.Name, Err: e.err})
+				return errors.Join(fmt.Errorf("%w: %s: %w", err, c.spec.Name, e.err), r.stop(0))
+			}
+			r.event(Event{Child: c.spec.Name, Err: e.err, Restarted: true})
+			if err := r.restart(e.idx); err != nil {
+				return errors.Join(err, r.stop(0))
+			}
+		}
+	}
+}
+
+func (r *run) event(e Event) {
+	if r.opts.OnEvent != nil {
+		r.opts.OnEvent(e)
+	}
+}
+
+// tooMany 记下这次重启，Period 内超过 Intensity 次时返回 ErrTooManyRestarts
+func (r *run) tooMany() error {
+	now := time.Now()
+	kept := r.restarts[:0]
+	for _, t := range r.restarts {
+		if now.Sub(t) < r.opts.Period {
+			kept = append(kept, t)
+		}
+	}
+	r.restarts = append(kept, now)
+	if len(r.restarts) > r.opts.Intensity {
+		return ErrTooManyRestarts
+	}
+	return nil
+}
+
+// backoff 是这次重启前要等的时间：Period 内第 n 次重启等 Backoff * 2^(n-1)
+func (r *run) backoff() time.Duration {
+	d := r.opts.Backoff
+	for i := 1; i < len(r.restarts) && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxBackoff)
+}
+
+// restart 按策略重启 idx 和连带的子任务：先按相反顺序停掉，再按顺序启动。
+// 有子任务停不下来时返回错误，不再重启，否则新旧两个实例会同时运行。
+func (r *run) restart(idx int) error {
+	from := idx
+	switch r.opts.Strategy {
+	case OneForAll:
+		from = 0
+	case OneForOne:
+		r.start(idx, r.backoff())
+		return nil
+	}
+	if err := r.stop(from); err != nil {
+		return err
+	}
+	delay := r.backoff()
+	for i := from; i < len(r.children); i++ {
+		if !r.children[i].finished {
+			r.start(i, delay)
+		}
+	}
+	return nil
+}
+
+func (r *run) start(idx int, delay time.Duration) {
+	c := r.children[idx]
+	// 不直接继承 r.ctx 的取消：否则 Run 的 ctx 一取消，所有子任务同时收到，就谈不上按顺序停止了
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.ctx))
+	c.gen++
+	c.running, c.finished = true, false
+	c.cancel, c.done = cancel, make(chan struct{})
+
+	gen, done, fn := c.gen, c.done, c.spec.Run
+	go func() {
+		err := call(ctx, delay, fn)
+		cancel()
+		close(done)
+		select {
+		case r.exits <- exit{idx: idx, gen: gen, err: err}:
+		case <-r.quit:
+		}
+	}()
+}
+
+// call 等 delay 后运行 run，把 panic 变成 *PanicError
+func call(ctx context.Context, delay time.Duration, fn func(context.Context) error) (err error) {
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+// stop 按相反顺序停止 from 及之后还在运行的子任务。Temporary 的子任务停掉后不会再启动。
+func (r *run) stop(from int) error {
+	var errs []error
+	for i := len(r.children) - 1; i >= from; i-- {
+		c := r.children[i]
+		if !c.running {
+			continue
+		}
+		c.running = false
+		c.finished = c.spec.Restart == Temporary
+		c.cancel()
+		t := time.NewTimer(c.spec.Shutdown)
+		select {
+		case <-c.done:
+		case <-t.C:
+			errs = append(errs, fmt.Errorf("%w: %s did not stop within %v", ErrShutdownTimeout, c.spec.Name, c.spec.Shutdown))
+		}
+		t.Stop()
+	}
+	return errors.Join(errs...)
+}
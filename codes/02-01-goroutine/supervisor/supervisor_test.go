@@ -0,0 +1,298 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd supervisor
+	go test -v -race .
+*/
+
+// recorder 记录子任务的启动、停止和 Supervisor 的事件
+type recorder struct {
+	mu     sync.Mutex
+	starts map[string][]time.Time
+	stops  []string
+	events []Event
+}
+
+func newRecorder() *recorder {
+	return &recorder{starts: make(map[string][]time.Time)}
+}
+
+func (r *recorder) onEvent(e Event) {
+	r.mu.Lock()
+	r.events = append(r.events, e)
+	r.mu.Unlock()
+}
+
+func (r *recorder) count(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.starts[name])
+}
+
+// errStop 让子任务正常返回 nil
+var errStop = errors.New("stop")
+
+// child 返回一个子任务：第 n 次启动（从 1 开始）时执行 fail(n)，fail 返回 nil 就一直运行到 ctx 取消，返回 errStop 就正常退出
+func (r *recorder) child(name string, fail func(n int) error) Child {
+	return Child{Name: name, Run: func(ctx context.Context) error {
+		r.mu.Lock()
+		r.starts[name] = append(r.starts[name], time.Now())
+		n := len(r.starts[name])
+		r.mu.Unlock()
+		if fail != nil {
+			if err := fail(n); err == errStop {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		r.mu.Lock()
+		r.stops = append(r.stops, name)
+		r.mu.Unlock()
+		return ctx.Err()
+	}}
+}
+
+// waitFor 等到 cond 成立，超时就失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 只在第一次启动时出问题
+func once(err error) func(int) error {
+	return func(n int) error {
+		if n == 1 {
+			return err
+		}
+		return nil
+	}
+}
+
+// 01-goroutine.md 3.6 的 panic：子任务里的 panic 被接住，只有它自己被重启
+func panicking() error {
+	panic("Goroutine panic")
+}
+
+func start(s *Supervisor) (stop func() error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.Run(ctx) }()
+	return func() error {
+		cancel()
+		return <-errc
+	}
+}
+
+func TestOneForOnePanic(t *testing.T) {
+	rec := newRecorder()
+	s := New(Options{Strategy: OneForOne, OnEvent: rec.onEvent},
+		rec.child("a", func(n int) error {
+			if n == 1 {
+				return panicking()
+			}
+			return nil
+		}),
+		rec.child("b", nil),
+	)
+	stop := start(s)
+	waitFor(t, func() bool { return rec.count("a") == 2 })
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.count("b") != 1 {
+		t.Fatalf("b started %d times", rec.count("b"))
+	}
+	if len(rec.events) != 1 || rec.events[0].Child != "a" || !rec.events[0].Restarted {
+		t.Fatalf("events = %+v", rec.events)
+	}
+	var pe *PanicError
+	if !errors.As(rec.events[0].Err, &pe) || pe.Value != "Goroutine panic" || !strings.Contains(string(pe.Stack), "supervisor.panicking") {
+		t.Fatalf("err = %v", rec.events[0].Err)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	boom := errors.New("boom")
+	for _, tc := range []struct {
+		strategy Strategy
+		want     map[string]int // 每个子任务的启动次数
+	}{
+		{OneForOne, map[string]int{"a": 1, "b": 2, "c": 1}},
+		{OneForAll, map[string]int{"a": 2, "b": 2, "c": 2}},
+		{RestForOne, map[string]int{"a": 1, "b": 2, "c": 2}},
+	} {
+		rec := newRecorder()
+		s := New(Options{Strategy: tc.strategy},
+			rec.child("a", nil),
+			rec.child("b", once(boom)),
+			rec.child("c", nil),
+		)
+		stop := start(s)
+		waitFor(t, func() bool { return rec.count("b") == 2 && rec.count("c") == tc.want["c"] })
+		if err := stop(); err != nil {
+			t.Fatal(err)
+		}
+		for name, n := range tc.want {
+			if got := rec.count(name); got != n {
+				t.Errorf("strategy %d: %s started %d times, want %d", tc.strategy, name, got, n)
+			}
+		}
+		// 连带重启前先按相反顺序停掉，最后 stop 时也按相反顺序
+		if tc.strategy == OneForAll && strings.Join(rec.stops, ",") != "c,a,c,b,a" {
+			t.Errorf("stop order = %v", rec.stops)
+		}
+	}
+}
+
+func TestRestartTypes(t *testing.T) {
+	boom := errors.New("boom")
+	exitNil := func(int) error { return errStop }
+	rec := newRecorder()
+	children := []Child{
+		rec.child("permanent-nil", once(errStop)),
+		rec.child("transient-nil", exitNil),
+		rec.child("transient-err", once(boom)),
+		rec.child("temporary-err", once(boom)),
+	}
+	children[1].Restart = Transient
+	children[2].Restart = Transient
+	children[3].Restart = Temporary
+	s := New(Options{Strategy: OneForOne, Intensity: 10, OnEvent: rec.onEvent}, children...)
+	stop := start(s)
+	waitFor(t, func() bool { return rec.count("permanent-nil") == 2 && rec.count("transient-err") == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"permanent-nil": 2, "transient-nil": 1, "transient-err": 2, "temporary-err": 1} {
+		if got := rec.count(name); got != want {
+			t.Errorf("%s started %d times, want %d", name, got, want)
+		}
+	}
+}
+
+// 一直失败：Period 内超过 Intensity 次后放弃，停掉其他子任务
+func TestIntensity(t *testing.T) {
+	boom := errors.New("boom")
+	rec := newRecorder()
+	s := New(Options{Intensity: 3, Period: time.Second},
+		rec.child("ok", nil),
+		rec.child("bad", func(int) error { return boom }),
+	)
+	err := s.Run(context.Background())
+	if !errors.Is(err, ErrTooManyRestarts) || !errors.Is(err, boom) || !strings.Contains(err.Error(), "bad") {
+		t.Fatalf("err = %v", err)
+	}
+	if rec.count("bad") != 4 {
+		t.Fatalf("bad started %d times", rec.count("bad"))
+	}
+	if len(rec.stops) != 1 || rec.stops[0] != "ok" {
+		t.Fatalf("stops = %v", rec.stops)
+	}
+}
+
+// 退避时间翻倍，到 MaxBackoff 为止
+func TestBackoff(t *testing.T) {
+	boom := errors.New("boom")
+	rec := newRecorder()
+	s := New(Options{Intensity: 5, Period: time.Minute, Backoff: 20 * time.Millisecond, MaxBackoff: 60 * time.Millisecond},
+		rec.child("bad", func(int) error { return boom }),
+	)
+	if err := s.Run(context.Background()); !errors.Is(err, ErrTooManyRestarts) {
+		t.Fatalf("err = %v", err)
+	}
+	starts := rec.starts["bad"]
+	want := []time.Duration{20, 40, 60, 60, 60}
+	if len(starts) != len(want)+1 {
+		t.Fatalf("%d starts", len(starts))
+	}
+	for i, w := range want {
+		gap, w := starts[i+1].Sub(starts[i]), w*time.Millisecond
+		if gap < w || gap > w+50*time.Millisecond {
+			t.Errorf("gap %d = %v, want ~%v", i, gap, w)
+		}
+	}
+}
+
+// 停止按相反顺序；不理 ctx 的子任务等 Shutdown 后放弃，不影响停止其他子任务
+func TestOrderedShutdown(t *testing.T) {
+	rec := newRecorder()
+	release := make(chan struct{})
+	defer close(release)
+	stubborn := Child{Name: "stubborn", Shutdown: 50 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-release
+		return nil
+	}}
+	s := New(Options{}, rec.child("a", nil), stubborn, rec.child("c", nil))
+	stop := start(s)
+	waitFor(t, func() bool { return rec.count("a") == 1 && rec.count("c") == 1 })
+
+	begin := time.Now()
+	err := stop()
+	if !errors.Is(err, ErrShutdownTimeout) || !strings.Contains(err.Error(), "stubborn") {
+		t.Fatalf("err = %v", err)
+	}
+	if d := time.Since(begin); d < 50*time.Millisecond || d > time.Second {
+		t.Fatalf("shutdown took %v", d)
+	}
+	if strings.Join(rec.stops, ",") != "c,a" {
+		t.Fatalf("stop order = %v", rec.stops)
+	}
+}
+
+// 正在退避等待中的子任务也能马上停下来
+func TestStopDuringBackoff(t *testing.T) {
+	rec := newRecorder()
+	s := New(Options{Backoff: time.Hour}, rec.child("a", once(errors.New("boom"))))
+	stop := start(s)
+	waitFor(t, func() bool { return rec.count("a") == 1 })
+	time.Sleep(20 * time.Millisecond)
+	begin := time.Now()
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(begin); d > time.Second {
+		t.Fatalf("shutdown took %v", d)
+	}
+}
+
+// supervisor 树：内层放弃后，外层把整个内层重启
+func TestTree(t *testing.T) {
+	boom := errors.New("boom")
+	rec := newRecorder()
+	inner := New(Options{Intensity: 1, Period: time.Minute},
+		rec.child("worker", func(n int) error {
+			if n <= 2 {
+				return boom
+			}
+			return nil
+		}),
+	)
+	outer := New(Options{OnEvent: rec.onEvent}, Child{Name: "inner", Run: inner.Run})
+	stop := start(outer)
+	waitFor(t, func() bool { return rec.count("worker") == 3 })
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.events) != 1 || rec.events[0].Child != "inner" || !errors.Is(rec.events[0].Err, ErrTooManyRestarts) {
+		t.Fatalf("events = %+v", rec.events)
+	}
+}
@@ -0,0 +1,48 @@
+package watchdog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goroutineID 从 runtime.Stack 第一行 "goroutine 18 [running]:" 里取出当前 goroutine 的 id。
+// 标准库故意不提供这个 id，这里只用它在报告时找回对应的栈。
+func goroutineID() int64 {
+	var buf [64]byte
+	id, _ := parseID(buf[:runtime.Stack(buf[:], false)])
+	return id
+}
+
+func parseID(b []byte) (int64, bool) {
+	b, ok := bytes.CutPrefix(b, []byte("goroutine "))
+	if !ok {
+		return 0, false
+	}
+	i := bytes.IndexByte(b, ' ')
+	if i < 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(string(b[:i]), 10, 64)
+	return id, err == nil
+}
+
+// allStacks 返回 id 到栈文本的映射
+func allStacks() map[int64]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stacks := make(map[int64]string)
+	for _, b := range bytes.Split(buf, []byte("\n\n")) {
+		if id, ok := parseID(b); ok {
+			stacks[id] = string(b)
+		}
+	}
+	return stacks
+}
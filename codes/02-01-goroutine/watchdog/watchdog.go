@@ -0,0 +1,199 @@
+// 长时间运行的 goroutine 的心跳和看门狗
+//
+// demo.go 的 f3 在 goroutine 里 for { fmt.Println(...) } 一直转。这种循环卡住了（阻塞在某个锁、通道、网络调用上）
+// 或者悄悄退出了（return、被 recover 的 panic），外面都不知道。看门狗的做法：
+//
+//	被监视的 goroutine 自己调用 Register 登记，约定每隔不超过 timeout 调用一次 Beat
+//	看门狗每隔 Options.Check 检查一遍，发现某个 goroutine 超过 timeout 没有心跳，就调用 Options.OnAlert，
+//	同时取消 Register 返回的 ctx（cause 是 ErrOverdue），循环里看 ctx 的代码就能退出；
+//	报告过的 Handle 随即注销，之后的 Beat 不起作用，不会再报告第二次
+//	报告里带上这个 goroutine 当时的栈（runtime.Stack），能直接看到卡在哪一行；
+//	goroutine 已经不在了说明它没有调用 Done 就退出了，报告里 Exited 为 true
+//
+// 心跳必须由干活的 goroutine 自己发。另起一个 goroutine 定时 Beat 没有意义，干活的卡住了它还在跳。
+// Every 把 time.Ticker 和心跳放在一起：每个 tick 执行一次工作，做完才跳一下。
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOverdue 是超时未心跳时 Register 返回的 ctx 的 cause
+var ErrOverdue = errors.New("watchdog: heartbeat overdue")
+
+// Alert 是一次超时报告
+type Alert struct {
+	Name        string
+	GoroutineID int64
+	LastBeat    time.Time
+	Overdue     time.Duration // 距离上一次心跳已经过去多久
+	Exited      bool          // 报告时这个 goroutine 已经不存在了
+	Stack       string        // 报告时这个 goroutine 的栈，Exited 时为空
+}
+
+func (a Alert) String() string {
+	s := fmt.Sprintf("watchdog: %s (goroutine %d) missed heartbeat for %v", a.Name, a.GoroutineID, a.Overdue.Round(time.Millisecond))
+	if a.Exited {
+		return s + ", goroutine exited without Done"
+	}
+	return s + "\n" + a.Stack
+}
+
+// Options 配置看门狗
+type Options struct {
+	// Check 是检查间隔，默认 100ms。超时的发现会比 timeout 晚最多一个 Check。
+	Check time.Duration
+	// OnAlert 在看门狗自己的 goroutine 里调用，不要在里面阻塞太久。为 nil 时只取消 ctx。
+	OnAlert func(Alert)
+}
+
+type Watchdog struct {
+	opts Options
+
+	mu      sync.Mutex
+	handles map[*Handle]struct{}
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New 创建看门狗并启动检查用的 goroutine，用完调用 Stop
+func New(opts Options) *Watchdog {
+	if opts.Check <= 0 {
+		opts.Check = 100 * time.Millisecond
+	}
+	w := &Watchdog{
+		opts:    opts,
+		handles: make(map[*Handle]struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// Stop 停止检查，等检查用的 goroutine 退出后返回。已登记的 ctx 不会被取消。
+func (w *Watchdog) Stop() {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Handle 代表一个被监视的 goroutine
+type Handle struct {
+	w       *Watchdog
+	name    string
+	gid     int64
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Register 登记调用它的 goroutine，要在被监视的 goroutine 里调用，栈是按调用者的 goroutine 取的。
+// 返回的 ctx 在超时未心跳时被取消，同时注销；goroutine 正常结束时调用 Done 注销。
+func (w *Watchdog) Register(ctx context.Context, name string, timeout time.Duration) (context.Context, *Handle) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	h := &Handle{
+		w:       w,
+		name:    name,
+		gid:     goroutineID(),
+		timeout: timeout,
+		cancel:  cancel,
+		last:    time.Now(),
+	}
+	w.mu.Lock()
+	w.handles[h] = struct{}{}
+	w.mu.Unlock()
+	return ctx, h
+}
+
+// Beat 发一次心跳。超时报告之后 Handle 已经注销、ctx 已经取消，Beat 什么都不做，
+// 想继续被监视要重新 Register。
+func (h *Handle) Beat() {
+	h.mu.Lock()
+	h.last = time.Now()
+	h.mu.Unlock()
+}
+
+// Done 注销，之后不再检查。可以调用多次。
+func (h *Handle) Done() {
+	h.w.mu.Lock()
+	delete(h.w.handles, h)
+	h.w.mu.Unlock()
+	h.cancel(context.Canceled)
+}
+
+// overdue 在超时时返回上一次心跳的时间和超时多久
+func (h *Handle) overdue(now time.Time) (time.Time, time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	d := now.Sub(h.last)
+	return h.last, d, d > h.timeout
+}
+
+func (w *Watchdog) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.Check)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+func (w *Watchdog) check(now time.Time) {
+	w.mu.Lock()
+	var alerts []Alert
+	var late []*Handle
+	for h := range w.handles {
+		if last, d, ok := h.overdue(now); ok {
+			// 在锁里注销，每个 Handle 只会报告一次
+			delete(w.handles, h)
+			alerts = append(alerts, Alert{Name: h.name, GoroutineID: h.gid, LastBeat: last, Overdue: d})
+			late = append(late, h)
+		}
+	}
+	w.mu.Unlock()
+	if len(alerts) == 0 {
+		return
+	}
+
+	// 取所有 goroutine 的栈要 stop the world，一次检查只取一次
+	stacks := allStacks()
+	for i, h := range late {
+		a := &alerts[i]
+		a.Stack, a.Exited = stacks[a.GoroutineID], stacks[a.GoroutineID] == ""
+		if w.opts.OnAlert != nil {
+			w.opts.OnAlert(*a)
+		}
+		h.cancel(ErrOverdue)
+	}
+}
+
+// Every 每隔 d 调用一次 fn，fn 返回后发一次心跳。ctx 被取消（包括看门狗超时取消）或 fn 返回错误时退出。
+// timeout 应该比 d 加上 fn 最长的执行时间再大一些，否则正常运行也会被当成超时。
+func Every(ctx context.Context, h *Handle, d time.Duration, fn func(ctx context.Context) error) error {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		h.Beat()
+	}
+}
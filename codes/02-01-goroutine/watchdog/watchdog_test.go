@@ -0,0 +1,227 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd watchdog
+	go test -v -race .
+*/
+
+// alerts 收集 OnAlert 的报告
+type alerts struct {
+	mu  sync.Mutex
+	got []Alert
+}
+
+func (a *alerts) add(x Alert) {
+	a.mu.Lock()
+	a.got = append(a.got, x)
+	a.mu.Unlock()
+}
+
+func (a *alerts) list() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Alert(nil), a.got...)
+}
+
+func newWatchdog(t *testing.T) (*Watchdog, *alerts) {
+	var a alerts
+	w := New(Options{Check: 10 * time.Millisecond, OnAlert: a.add})
+	t.Cleanup(w.Stop)
+	return w, &a
+}
+
+// demo.go 的 f3：goroutine 里一直循环。这里每一轮都发心跳、看 ctx，
+// 第 hangAt 轮卡在一把拿不到的锁上，模拟循环卡住
+func f3(ctx context.Context, w *Watchdog, mu *sync.Mutex, hangAt int, exited chan<- error) {
+	ctx, h := w.Register(ctx, "f3", 50*time.Millisecond)
+	defer h.Done()
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			exited <- context.Cause(ctx)
+			return
+		default:
+		}
+		if i == hangAt {
+			stuck(mu)
+		}
+		fmt.Fprintln(io.Discard, "Hello world")
+		h.Beat()
+	}
+}
+
+func stuck(mu *sync.Mutex) {
+	mu.Lock()
+	mu.Unlock()
+}
+
+func TestHungLoopIsReported(t *testing.T) {
+	w, a := newWatchdog(t)
+	var mu sync.Mutex
+	mu.Lock()
+	exited := make(chan error, 1)
+	go f3(context.Background(), w, &mu, 1000, exited)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(a.list()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no alert for a hung goroutine")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := a.list()[0]
+	if got.Name != "f3" || got.Exited || got.Overdue < 50*time.Millisecond {
+		t.Fatalf("alert = %+v", got)
+	}
+	// 栈里能看到卡在哪个函数
+	if !strings.Contains(got.Stack, "watchdog.stuck(") || !strings.Contains(got.Stack, "watchdog.f3(") {
+		t.Fatalf("stack does not show where it hangs:\n%s", got.Stack)
+	}
+	if !strings.HasPrefix(got.String(), "watchdog: f3 (goroutine ") {
+		t.Fatalf("String() = %q", got.String())
+	}
+
+	// 解开锁，循环看到 ctx 被取消后退出
+	mu.Unlock()
+	if err := <-exited; !errors.Is(err, ErrOverdue) {
+		t.Fatalf("cause = %v", err)
+	}
+	// 一次超时只报告一次
+	time.Sleep(50 * time.Millisecond)
+	if n := len(a.list()); n != 1 {
+		t.Fatalf("%d alerts", n)
+	}
+}
+
+// 一直在跳的 goroutine 不会被报告
+func TestHealthyLoop(t *testing.T) {
+	w, a := newWatchdog(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		ctx, h := w.Register(ctx, "ticker", 50*time.Millisecond)
+		defer h.Done()
+		errc <- Every(ctx, h, 10*time.Millisecond, func(context.Context) error { return nil })
+	}()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Every returned %v", err)
+	}
+	if got := a.list(); len(got) != 0 {
+		t.Fatalf("alerts for a healthy loop: %v", got)
+	}
+}
+
+// Every 里某一次工作卡住：看门狗取消 ctx，看 ctx 的工作返回，Every 带着 ErrOverdue 退出
+func TestEverySlowWork(t *testing.T) {
+	w, a := newWatchdog(t)
+	errc := make(chan error, 1)
+	go func() {
+		ctx, h := w.Register(context.Background(), "slow", 50*time.Millisecond)
+		defer h.Done()
+		n := 0
+		errc <- Every(ctx, h, 5*time.Millisecond, func(ctx context.Context) error {
+			if n++; n < 3 {
+				return nil
+			}
+			<-ctx.Done()
+			return context.Cause(ctx)
+		})
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrOverdue) {
+			t.Fatalf("Every returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stuck work was not cancelled")
+	}
+	if got := a.list(); len(got) != 1 || !strings.Contains(got[0].Stack, "TestEverySlowWork") {
+		t.Fatalf("alerts = %v", got)
+	}
+}
+
+// 没有调用 Done 就退出的 goroutine：报告 Exited
+func TestExitedWithoutDone(t *testing.T) {
+	w, a := newWatchdog(t)
+	ctxc := make(chan context.Context, 1)
+	go func() {
+		defer func() { recover() }()
+		ctx, h := w.Register(context.Background(), "died", 30*time.Millisecond)
+		ctxc <- ctx
+		h.Beat()
+		panic("boom") // 被 recover 吞掉，外面什么也看不到
+	}()
+	ctx := <-ctxc
+	<-ctx.Done()
+	got := a.list()
+	if len(got) != 1 || !got[0].Exited || got[0].Stack != "" {
+		t.Fatalf("alerts = %+v", got)
+	}
+	if !strings.HasSuffix(got[0].String(), "exited without Done") {
+		t.Fatalf("String() = %q", got[0].String())
+	}
+}
+
+// Beat 之后重新计时，再次超时会再报告一次；Done 之后不再检查
+// 按时 Beat 不会报告；超时报告一次之后 Handle 注销、ctx 取消，再 Beat 也不会重新开始计时、报告第二次
+func TestBeatRearms(t *testing.T) {
+	w, a := newWatchdog(t)
+	ctx, h := w.Register(context.Background(), "rearm", 30*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		time.Sleep(15 * time.Millisecond)
+		h.Beat()
+	}
+	if n := len(a.list()); n != 0 {
+		t.Fatalf("%d alerts while beating", n)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if n := len(a.list()); n != 1 {
+		t.Fatalf("%d alerts, want 1", n)
+	}
+	if context.Cause(ctx) != ErrOverdue {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+	h.Beat()
+	time.Sleep(80 * time.Millisecond)
+	if n := len(a.list()); n != 1 {
+		t.Fatalf("%d alerts after Beat on an overdue handle, want 1", n)
+	}
+	h.Done()
+	if context.Cause(ctx) != ErrOverdue {
+		t.Fatalf("Done changed the cause to %v", context.Cause(ctx))
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		id   int64
+		want bool
+	}{
+		{"goroutine 18 [running]:\nmain.main()", 18, true},
+		{"goroutine 7 [chan send, 2 minutes]:", 7, true},
+		{"goroutine x [running]:", 0, false},
+		{"main.main()", 0, false},
+	} {
+		if id, ok := parseID([]byte(tc.in)); id != tc.id || ok != tc.want {
+			t.Errorf("parseID(%q) = %d, %v", tc.in, id, ok)
+		}
+	}
+	if goroutineID() <= 0 {
+		t.Fatal("goroutineID() <= 0")
+	}
+}
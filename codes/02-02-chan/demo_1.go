@@ -10,6 +10,7 @@ func main() {
 	// ch1 := make(chan string)     //不带缓冲的通道,进和出都会阻塞
 	// ch3 := make(<-chan string)   //只读通道
 	// ch4 := make(chan<- string)   //只写通道
+	// 通道内部的 buf、sendq、recvq 怎么变化见 hchan/
 	ch2 := make(chan string, 10) //带10个缓冲的通道,进一次长度 +1，出一次长度 -1，如果长度等于缓冲长度时，再进就会阻塞。
 	ch2 <- "ch2"
 	if val, ok := <-ch2; ok {
@@ -29,6 +30,7 @@ func main() {
 		close(ch6)
 		close(ch6) //panic : close of closed channel
 	}
+	// 多个发送方时怎样安全地关闭、关闭后发送不 panic 见 safechan/
 
 	// fmt.Println("--------------------4--------------------")
 	// ch7 := make(<-chan string, 1)
@@ -0,0 +1,349 @@
+// 照着 runtime/chan.go 的 hchan 实现的教学用通道
+//
+// 知识点/03-并发/02-chan-02-底层.md 讲了 hchan 的字段和收发规则，这里用 Go 代码把它们写出来：
+//
+//	buf 是长度为 dataqsiz 的环形队列，sendx、recvx 是下一次写、读的位置，qcount 是元素个数
+//	sendq、recvq 是阻塞的发送方、接收方（sudog）组成的双向链表
+//	发送时先看 recvq：有等待的接收方就把值直接交给它（direct handoff），不经过 buf
+//	接收时先看 sendq：无缓冲通道直接从发送方拿值；缓冲通道此时 buf 一定是满的，
+//	从队头取一个值，再把发送方的值放进队尾，这样 FIFO 顺序不变
+//	都不满足时才用 buf，buf 也满（空）了就把自己挂到 sendq（recvq）上休眠
+//
+// 文档里“发送方阻塞时加入 recvq”是笔误：阻塞的发送方在 sendq，接收方在 recvq。
+//
+// runtime 用 gopark/goready 挂起、唤醒 goroutine，这里用每个等待者自己的 sync.Cond 代替，
+// 没有用到内置通道。关闭、nil 通道的行为和内置通道一致，包括 panic 的内容。
+package hchan
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// g 代表一个阻塞中的 goroutine
+type g struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	ready bool
+
+	// select 同时挂在多个通道上，只有第一个 CAS 成功的通道可以唤醒它
+	isSelect   bool
+	selectDone atomic.Bool
+	fired      int  // 唤醒它的 case 下标
+	success    bool // false 表示是 close 唤醒的
+}
+
+func newG() *g {
+	gp := &g{}
+	gp.cond = sync.NewCond(&gp.mu)
+	return gp
+}
+
+// gopark 休眠直到 goready
+func (gp *g) gopark() {
+	gp.mu.Lock()
+	for !gp.ready {
+		gp.cond.Wait()
+	}
+	gp.mu.Unlock()
+}
+
+func (gp *g) goready() {
+	gp.mu.Lock()
+	gp.ready = true
+	gp.cond.Signal()
+	gp.mu.Unlock()
+}
+
+// sudog 是挂在通道等待队列上的一个等待者
+type sudog[T any] struct {
+	g         *g
+	elem      *T // 发送方：要发送的值；接收方：接收到的值写到这里
+	caseIndex int
+	queued    bool
+	prev      *sudog[T]
+	next      *sudog[T]
+}
+
+type waitq[T any] struct {
+	first *sudog[T]
+	last  *sudog[T]
+	n     int
+}
+
+func (q *waitq[T]) enqueue(sg *sudog[T]) {
+	sg.queued = true
+	sg.next = nil
+	sg.prev = q.last
+	if q.last == nil {
+		q.first = sg
+	} else {
+		q.last.next = sg
+	}
+	q.last = sg
+	q.n++
+}
+
+func (q *waitq[T]) remove(sg *sudog[T]) {
+	if !sg.queued {
+		return
+	}
+	if sg.prev == nil {
+		q.first = sg.next
+	} else {
+		sg.prev.next = sg.next
+	}
+	if sg.next == nil {
+		q.last = sg.prev
+	} else {
+		sg.next.prev = sg.prev
+	}
+	sg.prev, sg.next, sg.queued = nil, nil, false
+	q.n--
+}
+
+// dequeue 取出第一个还能被唤醒的等待者：已经被别的 case 唤醒的 select 直接丢掉
+func (q *waitq[T]) dequeue() *sudog[T] {
+	for {
+		sg := q.first
+		if sg == nil {
+			return nil
+		}
+		q.remove(sg)
+		if sg.g.isSelect && !sg.g.selectDone.CompareAndSwap(false, true) {
+			continue
+		}
+		return sg
+	}
+}
+
+var nextID atomic.Uint64
+
+type Chan[T any] struct {
+	qcount   uint // buf 中的元素个数
+	dataqsiz uint // buf 的容量
+	buf      []T
+	closed   bool
+
+	sendx uint
+	recvx uint
+
+	sendq waitq[T]
+	recvq waitq[T]
+
+	lock sync.Mutex
+
+	id    uint64 // Select 按 id 的顺序加锁，避免死锁（runtime 按地址排序）
+	trace io.Writer
+}
+
+// New 相当于 make(chan T, size)
+func New[T any](size int) *Chan[T] {
+	if size < 0 {
+		panic("makechan: size out of range")
+	}
+	return &Chan[T]{dataqsiz: uint(size), buf: make([]T, size), id: nextID.Add(1)}
+}
+
+// SetTrace 把之后每次操作引起的 buf 和等待队列的变化写到 w，w 为 nil 时关闭
+func (c *Chan[T]) SetTrace(w io.Writer) {
+	c.lock.Lock()
+	c.trace = w
+	c.lock.Unlock()
+}
+
+// tracef 在持有 c.lock 时调用，末尾附上当前状态
+func (c *Chan[T]) tracef(format string, args ...any) {
+	if c.trace == nil {
+		return
+	}
+	fmt.Fprintf(c.trace, format, args...)
+	fmt.Fprintf(c.trace, " | qcount=%d sendx=%d recvx=%d sendq=%d recvq=%d closed=%v\n",
+		c.qcount, c.sendx, c.recvx, c.sendq.n, c.recvq.n, c.closed)
+}
+
+func (c *Chan[T]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return int(c.qcount)
+}
+
+func (c *Chan[T]) Cap() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.dataqsiz)
+}
+
+// Send 相当于 c <- v
+func (c *Chan[T]) Send(v T) {
+	if c == nil {
+		newG().gopark() // 向 nil 通道发送永远阻塞
+	}
+	c.lock.Lock()
+	done, closed, w := c.sendLocked(v)
+	if closed {
+		c.lock.Unlock()
+		panic("send on closed channel")
+	}
+	if done {
+		c.lock.Unlock()
+		if w != nil {
+			wake(w, true)
+		}
+		return
+	}
+	gp := newG()
+	c.sendq.enqueue(&sudog[T]{g: gp, elem: &v})
+	c.tracef("send %v: block, enqueue sendq", v)
+	c.lock.Unlock()
+
+	gp.gopark()
+	if !gp.success {
+		panic("send on closed channel")
+	}
+}
+
+// sendLocked 在持有 c.lock 时尝试立即完成发送，不解锁。
+// 返回的等待者要在解锁之后唤醒。
+func (c *Chan[T]) sendLocked(v T) (done, closed bool, waiter *sudog[T]) {
+	if c.closed {
+		return false, true, nil
+	}
+	if sg := c.recvq.dequeue(); sg != nil {
+		if sg.elem != nil {
+			*sg.elem = v
+		}
+		c.tracef("send %v: handoff to receiver dequeued from recvq", v)
+		return true, false, sg
+	}
+	if c.qcount < c.dataqsiz {
+		c.buf[c.sendx] = v
+		c.sendx++
+		if c.sendx == c.dataqsiz {
+			c.sendx = 0
+		}
+		c.qcount++
+		c.tracef("send %v: buffered", v)
+		return true, false, nil
+	}
+	return false, false, nil
+}
+
+// Recv 相当于 v, ok := <-c
+func (c *Chan[T]) Recv() (v T, ok bool) {
+	if c == nil {
+		newG().gopark() // 从 nil 通道接收永远阻塞
+	}
+	c.lock.Lock()
+	if done, ok, w := c.recvLocked(&v); done {
+		c.lock.Unlock()
+		if w != nil {
+			wake(w, true)
+		}
+		return v, ok
+	}
+	gp := newG()
+	c.recvq.enqueue(&sudog[T]{g: gp, elem: &v})
+	c.tracef("recv: block, enqueue recvq")
+	c.lock.Unlock()
+
+	gp.gopark()
+	return v, gp.success
+}
+
+// recvLocked 在持有 c.lock 时尝试立即完成接收，值写到 dst（可以为 nil），不解锁。
+// 返回的等待者要在解锁之后唤醒。
+func (c *Chan[T]) recvLocked(dst *T) (done, ok bool, waiter *sudog[T]) {
+	var zero T
+	if c.closed && c.qcount == 0 {
+		if dst != nil {
+			*dst = zero
+		}
+		c.tracef("recv: closed and empty, zero value")
+		return true, false, nil
+	}
+	if sg := c.sendq.dequeue(); sg != nil {
+		if c.dataqsiz == 0 {
+			// 无缓冲：直接从发送方拿
+			if dst != nil {
+				*dst = *sg.elem
+			}
+			c.tracef("recv %v: handoff from sender dequeued from sendq", *sg.elem)
+		} else {
+			// buf 是满的：取队头，发送方的值放到队尾（也就是刚空出来的位置）
+			v := c.buf[c.recvx]
+			if dst != nil {
+				*dst = v
+			}
+			c.buf[c.recvx] = *sg.elem
+			c.recvx++
+			if c.recvx == c.dataqsiz {
+				c.recvx = 0
+			}
+			c.sendx = c.recvx
+			c.tracef("recv %v: from buf, refill with %v from sender dequeued from sendq", v, *sg.elem)
+		}
+		return true, true, sg
+	}
+	if c.qcount > 0 {
+		v := c.buf[c.recvx]
+		if dst != nil {
+			*dst = v
+		}
+		c.buf[c.recvx] = zero // 不再持有引用
+		c.recvx++
+		if c.recvx == c.dataqsiz {
+			c.recvx = 0
+		}
+		c.qcount--
+		c.tracef("recv %v: from buf", v)
+		return true, true, nil
+	}
+	return false, false, nil
+}
+
+// Close 相当于 close(c)：唤醒所有接收方（得到零值和 false）和发送方（panic）
+func (c *Chan[T]) Close() {
+	if c == nil {
+		panic("close of nil channel")
+	}
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		panic("close of closed channel")
+	}
+	c.closed = true
+
+	var waiters []*sudog[T]
+	var zero T
+	for sg := c.recvq.dequeue(); sg != nil; sg = c.recvq.dequeue() {
+		if sg.elem != nil {
+			*sg.elem = zero
+		}
+		waiters = append(waiters, sg)
+	}
+	nrecv := len(waiters)
+	for sg := c.sendq.dequeue(); sg != nil; sg = c.sendq.dequeue() {
+		waiters = append(waiters, sg)
+	}
+	c.tracef("close: release %d receivers, %d senders", nrecv, len(waiters)-nrecv)
+	c.lock.Unlock()
+
+	// 和 runtime 一样，先放开锁再逐个唤醒
+	for _, sg := range waiters {
+		wake(sg, false)
+	}
+}
+
+// wake 在不持有通道锁时调用
+func wake[T any](sg *sudog[T], success bool) {
+	sg.g.fired = sg.caseIndex
+	sg.g.success = success
+	sg.g.goready()
+}
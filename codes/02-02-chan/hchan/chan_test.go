@@ -0,0 +1,376 @@
+package hchan
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd hchan
+	go test -v -race .
+	go test -run=Example -v .   # 查看队列变化的 trace
+*/
+
+// catch 执行 f，返回 panic 的内容（没有 panic 时为空）
+func catch(f func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprint(r)
+		}
+	}()
+	f()
+	return ""
+}
+
+// 与内置通道做差分测试：单个 goroutine 随机执行非阻塞的收发、关闭、len，
+// 每一步的结果（包括 panic 的内容）都要一致
+func TestDifferentialNonBlocking(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		size := r.Intn(4)
+		ref := make(chan int, size)
+		c := New[int](size)
+		for op := 0; op < 50; op++ {
+			var got, want string
+			switch n := r.Intn(10); {
+			case n < 4:
+				v := r.Intn(100)
+				want = catch(func() {
+					select {
+					case ref <- v:
+						want = "sent"
+					default:
+						want = "blocked"
+					}
+				}) + want
+				got = catch(func() {
+					if i, _ := TrySelect(SendCase(c, v)); i == 0 {
+						got = "sent"
+					} else {
+						got = "blocked"
+					}
+				}) + got
+			case n < 8:
+				select {
+				case v, ok := <-ref:
+					want = fmt.Sprint(v, ok)
+				default:
+					want = "blocked"
+				}
+				var v int
+				if i, ok := TrySelect(RecvCase(c, &v)); i == 0 {
+					got = fmt.Sprint(v, ok)
+				} else {
+					got = "blocked"
+				}
+			case n < 9:
+				want = catch(func() { close(ref) })
+				got = catch(c.Close)
+			default:
+				want = fmt.Sprint(len(ref), cap(ref))
+				got = fmt.Sprint(c.Len(), c.Cap())
+			}
+			if got != want {
+				t.Fatalf("seed %d op %d: got %q, want %q", seed, op, got, want)
+			}
+		}
+	}
+}
+
+// 多个生产者、消费者并发收发：每个值恰好被收到一次，关闭后所有消费者都退出
+func TestConcurrentProducersConsumers(t *testing.T) {
+	for _, size := range []int{0, 1, 8} {
+		c := New[int](size)
+		const producers, perProducer, consumers = 4, 500, 3
+		var wg sync.WaitGroup
+		for p := 0; p < producers; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				for i := 0; i < perProducer; i++ {
+					c.Send(p*perProducer + i)
+				}
+			}(p)
+		}
+		results := make([][]int, consumers)
+		var cwg sync.WaitGroup
+		for k := 0; k < consumers; k++ {
+			cwg.Add(1)
+			go func(k int) {
+				defer cwg.Done()
+				for {
+					v, ok := c.Recv()
+					if !ok {
+						return
+					}
+					results[k] = append(results[k], v)
+				}
+			}(k)
+		}
+		wg.Wait()
+		c.Close()
+		cwg.Wait()
+
+		var all []int
+		for k, got := range results {
+			// 同一个生产者的值，在同一个消费者那里也是按发送顺序到达的（FIFO）
+			last := make(map[int]int)
+			for _, v := range got {
+				p := v / perProducer
+				if prev, ok := last[p]; ok && v < prev {
+					t.Fatalf("size %d consumer %d: %d after %d", size, k, v, prev)
+				}
+				last[p] = v
+			}
+			all = append(all, got...)
+		}
+		sort.Ints(all)
+		if len(all) != producers*perProducer {
+			t.Fatalf("size %d: received %d values", size, len(all))
+		}
+		for i, v := range all {
+			if v != i {
+				t.Fatalf("size %d: value %d missing or duplicated", size, i)
+			}
+		}
+	}
+}
+
+// waitFor 等到通道的等待队列达到指定长度
+func waitFor[T any](c *Chan[T], sendq, recvq int) {
+	for {
+		c.lock.Lock()
+		s, r := c.sendq.n, c.recvq.n
+		c.lock.Unlock()
+		if s == sendq && r == recvq {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 关闭时阻塞的接收方得到零值和 false，阻塞的发送方 panic，和内置通道一样。
+// 内置通道上关闭和发送并发执行会被 -race 报告，所以发送方的 panic 只和顺序执行的结果比较。
+func TestCloseWakesWaiters(t *testing.T) {
+	refRecv := make(chan string)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(refRecv)
+	}()
+	v, ok := <-refRecv
+	wantRecv := fmt.Sprintf("%q %v", v, ok)
+	wantPanic := catch(func() {
+		ch := make(chan string)
+		close(ch)
+		ch <- "x"
+	})
+
+	// 无缓冲通道上不可能同时有阻塞的发送方和接收方，所以用两个通道
+	cR, cS := New[string](0), New[string](0)
+	recvDone := make(chan string)
+	sendDone := make(chan string)
+	go func() {
+		v, ok := cR.Recv()
+		recvDone <- fmt.Sprintf("%q %v", v, ok)
+	}()
+	go func() { sendDone <- catch(func() { cS.Send("x") }) }()
+	waitFor(cR, 0, 1)
+	waitFor(cS, 1, 0)
+	cR.Close()
+	cS.Close()
+	if got := <-recvDone; got != wantRecv {
+		t.Errorf("receiver got %s, want %s", got, wantRecv)
+	}
+	if got := <-sendDone; got != wantPanic {
+		t.Errorf("sender panic %q, want %q", got, wantPanic)
+	}
+}
+
+func TestNilChannel(t *testing.T) {
+	var c *Chan[int]
+	if msg := catch(c.Close); msg != catch(func() { var ch chan int; close(ch) }) {
+		t.Fatalf("close nil: %q", msg)
+	}
+	if i, _ := TrySelect(SendCase(c, 1), RecvCase(c, nil)); i != -1 {
+		t.Fatalf("nil channel case chosen: %d", i)
+	}
+	if c.Len() != 0 || c.Cap() != 0 {
+		t.Fatal("len/cap of nil channel")
+	}
+}
+
+func TestSelectBlocksUntilReady(t *testing.T) {
+	a, b := New[int](0), New[string](0)
+	var got string
+	done := make(chan [2]any)
+	go func() {
+		i, ok := Select(RecvCase(a, nil), RecvCase(b, &got))
+		done <- [2]any{i, ok}
+	}()
+	waitFor(a, 0, 1)
+	waitFor(b, 0, 1)
+	b.Send("hello")
+	if r := <-done; r[0] != 1 || r[1] != true || got != "hello" {
+		t.Fatalf("Select = %v, got %q", r, got)
+	}
+	// 被选中之后，select 挂在其它通道上的等待者要摘掉
+	waitFor(a, 0, 0)
+
+	// 阻塞的发送 case
+	go func() {
+		i, _ := Select(SendCase(a, 42), RecvCase(b, nil))
+		done <- [2]any{i, nil}
+	}()
+	waitFor(a, 1, 0)
+	if v, ok := a.Recv(); v != 42 || !ok {
+		t.Fatalf("Recv = %d, %v", v, ok)
+	}
+	if r := <-done; r[0] != 0 {
+		t.Fatalf("Select = %v", r)
+	}
+	waitFor(b, 0, 0)
+
+	// 阻塞中通道被关闭：接收 case 返回 ok=false
+	go func() {
+		i, ok := Select(RecvCase(a, nil), RecvCase(b, nil))
+		done <- [2]any{i, ok}
+	}()
+	waitFor(a, 0, 1)
+	a.Close()
+	if r := <-done; r[0] != 0 || r[1] != false {
+		t.Fatalf("Select on closed = %v", r)
+	}
+}
+
+func TestSelectSendOnClosedPanics(t *testing.T) {
+	a := New[int](0)
+	a.Close()
+	want := catch(func() {
+		ch := make(chan int)
+		close(ch)
+		select {
+		case ch <- 1:
+		default:
+		}
+	})
+	if got := catch(func() { TrySelect(SendCase(a, 1)) }); got != want {
+		t.Fatalf("panic %q, want %q", got, want)
+	}
+
+	// 阻塞在发送 case 上时被关闭
+	b := New[int](0)
+	out := make(chan string)
+	go func() { out <- catch(func() { Select(SendCase(b, 1)) }) }()
+	waitFor(b, 1, 0)
+	b.Close()
+	if got := <-out; got != want {
+		t.Fatalf("panic %q, want %q", got, want)
+	}
+}
+
+// 多个 case 同时就绪时随机选择
+func TestSelectIsRandom(t *testing.T) {
+	a, b := New[int](1), New[int](1)
+	counts := [2]int{}
+	for i := 0; i < 1000; i++ {
+		a.Send(1)
+		b.Send(2)
+		i, _ := Select(RecvCase(a, nil), RecvCase(b, nil))
+		counts[i]++
+		TrySelect(RecvCase(a, nil), RecvCase(b, nil)) // 取走另一个
+	}
+	if counts[0] < 400 || counts[1] < 400 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+// 很多 goroutine 在几个通道上同时 Select 收发，不丢值也不死锁
+func TestSelectStress(t *testing.T) {
+	chans := []*Chan[int]{New[int](0), New[int](1), New[int](0)}
+	const senders, perSender = 4, 300
+	var received sync.Map
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			for i := 0; i < perSender; i++ {
+				v := s*perSender + i
+				Select(SendCase(chans[0], v), SendCase(chans[1], v), SendCase(chans[2], v))
+			}
+		}(s)
+	}
+	stop := New[int](0)
+	var rwg sync.WaitGroup
+	for r := 0; r < 3; r++ {
+		rwg.Add(1)
+		go func() {
+			defer rwg.Done()
+			for {
+				var v int
+				i, _ := Select(RecvCase(chans[0], &v), RecvCase(chans[1], &v), RecvCase(chans[2], &v), RecvCase(stop, nil))
+				if i == 3 {
+					return
+				}
+				if _, dup := received.LoadOrStore(v, true); dup {
+					t.Errorf("value %d received twice", v)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// 缓冲里可能还剩一个值，在关闭 stop 前取走
+	for {
+		var v int
+		if i, _ := TrySelect(RecvCase(chans[1], &v)); i != 0 {
+			break
+		}
+		received.Store(v, true)
+	}
+	stop.Close()
+	rwg.Wait()
+	n := 0
+	received.Range(func(any, any) bool { n++; return true })
+	if n != senders*perSender {
+		t.Fatalf("received %d values", n)
+	}
+}
+
+func ExampleChan_SetTrace() {
+	c := New[int](2)
+	c.SetTrace(os.Stdout)
+	c.Send(1)
+	c.Send(2)
+	go c.Send(3) // buf 满了，阻塞在 sendq
+	waitFor(c, 1, 0)
+	c.Recv() // 取走 1，把 3 放进刚空出的位置，唤醒发送方
+	c.Recv()
+	c.Recv()
+	done := make(chan int)
+	go func() {
+		v, _ := c.Recv() // buf 空了，阻塞在 recvq
+		done <- v
+	}()
+	waitFor(c, 0, 1)
+	c.Send(4) // 直接交给等待的接收方，不经过 buf
+	<-done
+	c.Close()
+	c.Recv()
+	// Output:
+	// send 1: buffered | qcount=1 sendx=1 recvx=0 sendq=0 recvq=0 closed=false
+	// send 2: buffered | qcount=2 sendx=0 recvx=0 sendq=0 recvq=0 closed=false
+	// send 3: block, enqueue sendq | qcount=2 sendx=0 recvx=0 sendq=1 recvq=0 closed=false
+	// recv 1: from buf, refill with 3 from sender dequeued from sendq | qcount=2 sendx=1 recvx=1 sendq=0 recvq=0 closed=false
+	// recv 2: from buf | qcount=1 sendx=1 recvx=0 sendq=0 recvq=0 closed=false
+	// recv 3: from buf | qcount=0 sendx=1 recvx=1 sendq=0 recvq=0 closed=false
+	// recv: block, enqueue recvq | qcount=0 sendx=1 recvx=1 sendq=0 recvq=1 closed=false
+	// send 4: handoff to receiver dequeued from recvq | qcount=0 sendx=1 recvx=1 sendq=0 recvq=0 closed=false
+	// close: release 0 receivers, 0 senders | qcount=0 sendx=1 recvx=1 sendq=0 recvq=0 closed=true
+	// recv: closed and empty, zero value | qcount=0 sendx=1 recvx=1 sendq=0 recvq=0 closed=true
+}
@@ -0,0 +1,3 @@
+module hchan
+
+go 1.21
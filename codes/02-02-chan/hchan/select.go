@@ -0,0 +1,201 @@
+package hchan
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Case 是 Select 的一个分支，用 SendCase、RecvCase 创建
+type Case interface {
+	ch() lockable // nil 通道返回 nil
+	isSend() bool
+	// try 在持有通道锁时尝试立即完成，after 要在放开所有锁之后调用
+	try() (done, recvOK, sendClosed bool, after func())
+	// enqueue 把 gp 挂到等待队列上，返回的 remove 在持有通道锁时把它摘下来
+	enqueue(gp *g, i int) (remove func())
+}
+
+type lockable interface {
+	chanID() uint64
+	lockChan()
+	unlockChan()
+}
+
+func (c *Chan[T]) chanID() uint64 { return c.id }
+func (c *Chan[T]) lockChan()      { c.lock.Lock() }
+func (c *Chan[T]) unlockChan()    { c.lock.Unlock() }
+
+type sendCase[T any] struct {
+	c *Chan[T]
+	v T
+}
+
+// SendCase 相当于 case c <- v
+func SendCase[T any](c *Chan[T], v T) Case { return &sendCase[T]{c, v} }
+
+func (sc *sendCase[T]) ch() lockable {
+	if sc.c == nil {
+		return nil
+	}
+	return sc.c
+}
+
+func (sc *sendCase[T]) isSend() bool { return true }
+
+func (sc *sendCase[T]) try() (done, recvOK, sendClosed bool, after func()) {
+	done, closed, w := sc.c.sendLocked(sc.v)
+	if w != nil {
+		after = func() { wake(w, true) }
+	}
+	return done, false, closed, after
+}
+
+func (sc *sendCase[T]) enqueue(gp *g, i int) func() {
+	v := sc.v
+	sg := &sudog[T]{g: gp, elem: &v, caseIndex: i}
+	sc.c.sendq.enqueue(sg)
+	sc.c.tracef("select case %d: send %v, enqueue sendq", i, v)
+	return func() {
+		if sg.queued {
+			sc.c.sendq.remove(sg)
+			sc.c.tracef("select case %d: remove from sendq", i)
+		}
+	}
+}
+
+type recvCase[T any] struct {
+	c   *Chan[T]
+	dst *T
+}
+
+// RecvCase 相当于 case *dst, ok = <-c，dst 为 nil 时丢弃收到的值
+func RecvCase[T any](c *Chan[T], dst *T) Case { return &recvCase[T]{c, dst} }
+
+func (rc *recvCase[T]) ch() lockable {
+	if rc.c == nil {
+		return nil
+	}
+	return rc.c
+}
+
+func (rc *recvCase[T]) isSend() bool { return false }
+
+func (rc *recvCase[T]) try() (done, recvOK, sendClosed bool, after func()) {
+	done, ok, w := rc.c.recvLocked(rc.dst)
+	if w != nil {
+		after = func() { wake(w, true) }
+	}
+	return done, ok, false, after
+}
+
+func (rc *recvCase[T]) enqueue(gp *g, i int) func() {
+	sg := &sudog[T]{g: gp, elem: rc.dst, caseIndex: i}
+	rc.c.recvq.enqueue(sg)
+	rc.c.tracef("select case %d: recv, enqueue recvq", i)
+	return func() {
+		if sg.queued {
+			rc.c.recvq.remove(sg)
+			rc.c.tracef("select case %d: remove from recvq", i)
+		}
+	}
+}
+
+// Select 相当于没有 default 的 select 语句，返回执行的 case 下标；
+// 执行的是接收时 recvOK 和 v, ok := <-c 中的 ok 相同。
+// 所有通道都是 nil 时永远阻塞。
+func Select(cases ...Case) (chosen int, recvOK bool) {
+	return selectgo(cases, true)
+}
+
+// TrySelect 相当于带 default 的 select，没有能立即执行的 case 时返回 -1
+func TrySelect(cases ...Case) (chosen int, recvOK bool) {
+	return selectgo(cases, false)
+}
+
+// selectgo 的步骤和 runtime 相同：
+//
+//  1. 随机打乱检查顺序（pollorder），按通道 id 排序加锁（lockorder）
+//  2. 按 pollorder 检查有没有能立即完成的 case
+//  3. 没有且不阻塞时返回；否则把同一个 g 挂到每个通道的等待队列上，休眠
+//  4. 被某个通道唤醒后，重新加锁，把自己从其它通道的队列上摘掉
+func selectgo(cases []Case, block bool) (int, bool) {
+	var active []int
+	var chans []lockable
+	seen := make(map[uint64]bool)
+	for i, cs := range cases {
+		c := cs.ch()
+		if c == nil {
+			continue // nil 通道的 case 永远不会被选中
+		}
+		active = append(active, i)
+		if !seen[c.chanID()] {
+			seen[c.chanID()] = true
+			chans = append(chans, c)
+		}
+	}
+	if len(active) == 0 {
+		if !block {
+			return -1, false
+		}
+		newG().gopark()
+	}
+
+	pollorder := make([]int, len(active))
+	for i, j := range rand.Perm(len(active)) {
+		pollorder[i] = active[j]
+	}
+	sort.Slice(chans, func(i, j int) bool { return chans[i].chanID() < chans[j].chanID() })
+	lockAll := func() {
+		for _, c := range chans {
+			c.lockChan()
+		}
+	}
+	unlockAll := func() {
+		for i := len(chans) - 1; i >= 0; i-- {
+			chans[i].unlockChan()
+		}
+	}
+
+	lockAll()
+	for _, i := range pollorder {
+		done, ok, sendClosed, after := cases[i].try()
+		if sendClosed {
+			unlockAll()
+			panic("send on closed channel")
+		}
+		if done {
+			unlockAll()
+			if after != nil {
+				after()
+			}
+			return i, ok
+		}
+	}
+	if !block {
+		unlockAll()
+		return -1, false
+	}
+
+	gp := newG()
+	gp.isSelect = true
+	removes := make(map[int]func(), len(active))
+	for _, i := range active {
+		removes[i] = cases[i].enqueue(gp, i)
+	}
+	unlockAll()
+
+	gp.gopark()
+
+	lockAll()
+	for i, remove := range removes {
+		if i != gp.fired {
+			remove()
+		}
+	}
+	unlockAll()
+
+	if cases[gp.fired].isSend() && !gp.success {
+		panic("send on closed channel")
+	}
+	return gp.fired, gp.success
+}
@@ -0,0 +1,3 @@
+module safechan
+
+go 1.21
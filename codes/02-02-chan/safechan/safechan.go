@@ -0,0 +1,135 @@
+// 不会因为重复关闭、关闭后发送而 panic 的通道
+//
+// 知识点/03-并发/02-chan-01.md 的“常见陷阱”里有两条，demo_1.go 的第 2、3 段把 panic 复现了出来：
+//
+//	关闭通道后继续发送数据：panic: send on closed channel
+//	多个 goroutine 同时关闭通道：panic: close of closed channel
+//
+// 只有一个发送方时，让它发完再关闭就够了。多个发送方时谁都不知道别人发完没有，
+// 常见的错误做法是先看一眼“关了没有”再发送，但看和发之间别的 goroutine 可能已经关了。
+// SafeChan 的做法：
+//
+//	发送方先登记（在锁里检查 closing 并给 senders 加一），登记失败说明已经开始关闭，直接返回 false
+//	Close 先在锁里把 closing 置为 true，之后不会再有新的发送方登记；再关闭 done，
+//	让阻塞在发送上的发送方放弃；等所有登记过的发送方退出后才真正 close(ch)
+//
+// 这样 close(ch) 时一定没有发送方在 ch 上，不会 panic，也没有数据竞争。
+// 锁只出现在发送和关闭上，接收方直接读 C() 返回的通道，和内置通道一样用 for range、select。
+package safechan
+
+import "sync"
+
+// SafeChan 是可以被多个发送方安全关闭的通道，零值不能用，用 New 创建
+type SafeChan[T any] struct {
+	ch   chan T
+	done chan struct{} // Close 开始时关闭，通知阻塞的发送方
+
+	mu      sync.Mutex
+	closing bool
+	senders sync.WaitGroup // 已登记、还没返回的发送方
+
+	once sync.Once
+}
+
+// New 创建缓冲区大小为 size 的 SafeChan，size 为 0 时是无缓冲通道
+func New[T any](size int) *SafeChan[T] {
+	return &SafeChan[T]{
+		ch:   make(chan T, size),
+		done: make(chan struct{}),
+	}
+}
+
+// C 返回只读的底层通道，接收方直接用它，不经过任何锁。
+// Close 之后缓冲区里剩下的值仍然可以读出来，读完后 ok 为 false。
+func (c *SafeChan[T]) C() <-chan T {
+	return c.ch
+}
+
+// enter 登记一个发送方，已经开始关闭时返回 false
+func (c *SafeChan[T]) enter() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return false
+	}
+	c.senders.Add(1)
+	return true
+}
+
+// Send 发送 v，缓冲区满时阻塞。发送成功返回 true，v 一定能被接收方读到；
+// 调用前已经关闭，或者阻塞期间有人调用了 Close，返回 false，v 被丢弃。
+// 和 Close 同时发生时两种结果都有可能：Close 开始后仍可能有值在 close(ch) 之前进入通道。
+func (c *SafeChan[T]) Send(v T) bool {
+	if !c.enter() {
+		return false
+	}
+	defer c.senders.Done()
+	// done 和 ch 同时可以进行时 select 随机选一个，先看一眼 done，Close 已经开始时不去碰运气。
+	// 这只是缩小窗口：检查之后 Close 随时可能开始，下面的 select 仍然可能选中 ch。
+	// 这不影响正确性，登记过的发送方退出前 Close 不会 close(ch)，发进去的值接收方照样能读到。
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.ch <- v:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// TrySend 不阻塞地发送 v：缓冲区满（无缓冲通道没有接收方在等）或者已经关闭时返回 false
+func (c *SafeChan[T]) TrySend(v T) bool {
+	if !c.enter() {
+		return false
+	}
+	defer c.senders.Done()
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 关闭通道，可以被多个 goroutine 调用多次。第一次调用会等阻塞中的发送方都放弃后再 close，
+// 同时调用的其他 Close 会等它完成；Close 返回时通道一定已经关闭了。
+func (c *SafeChan[T]) Close() {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.closing = true
+		c.mu.Unlock()
+
+		close(c.done)
+		c.senders.Wait()
+		close(c.ch)
+	})
+}
+
+// Closed 报告是否已经调用过 Close。返回 true 之后 Send、TrySend 一定失败；
+// 返回 false 只说明调用的那一刻还没关，发送时可能已经关了，所以不要用它代替 Send 的返回值。
+func (c *SafeChan[T]) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len 返回缓冲区里还没被读走的元素个数，和 len(ch) 一样只是一个瞬间的值
+func (c *SafeChan[T]) Len() int {
+	return len(c.ch)
+}
+
+// Cap 返回缓冲区大小
+func (c *SafeChan[T]) Cap() int {
+	return cap(c.ch)
+}
@@ -0,0 +1,177 @@
+package safechan
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd safechan
+	go test -v -race .
+*/
+
+func mustPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || err.Error() != want {
+			t.Fatalf("recover() = %v, want %q", r, want)
+		}
+	}()
+	f()
+}
+
+// demo_1.go 第 2、3 段：内置通道会 panic
+func TestBuiltinPanics(t *testing.T) {
+	mustPanic(t, "send on closed channel", func() {
+		ch := make(chan string, 1)
+		close(ch)
+		ch <- "ch5"
+	})
+	mustPanic(t, "close of closed channel", func() {
+		ch := make(chan string, 1)
+		close(ch)
+		close(ch)
+	})
+}
+
+func TestSendAfterClose(t *testing.T) {
+	c := New[string](1)
+	if c.Closed() || !c.Send("a") {
+		t.Fatal("send on open channel failed")
+	}
+	c.Close()
+	c.Close()
+	if !c.Closed() {
+		t.Fatal("Closed() = false after Close")
+	}
+	if c.Send("b") || c.TrySend("b") {
+		t.Fatal("send after Close succeeded")
+	}
+	// 关闭前发送的值还能读出来
+	if v, ok := <-c.C(); !ok || v != "a" {
+		t.Fatalf("got %q, %v", v, ok)
+	}
+	if _, ok := <-c.C(); ok {
+		t.Fatal("channel not closed")
+	}
+}
+
+func TestTrySendFull(t *testing.T) {
+	c := New[int](2)
+	if !c.TrySend(1) || !c.TrySend(2) {
+		t.Fatal("TrySend on non-full channel failed")
+	}
+	if c.TrySend(3) {
+		t.Fatal("TrySend on full channel succeeded")
+	}
+	if c.Len() != 2 || c.Cap() != 2 {
+		t.Fatalf("len = %d, cap = %d", c.Len(), c.Cap())
+	}
+	<-c.C()
+	if c.Len() != 1 || !c.TrySend(3) {
+		t.Fatalf("len = %d after receive", c.Len())
+	}
+
+	// 无缓冲通道：没有接收方在等就失败
+	u := New[int](0)
+	if u.TrySend(1) {
+		t.Fatal("TrySend on unbuffered channel without receiver succeeded")
+	}
+}
+
+// 阻塞在 Send 上的发送方：Close 让它们返回 false，并且等它们都返回后才关闭通道
+func TestCloseReleasesBlockedSenders(t *testing.T) {
+	c := New[int](0)
+	const n = 8
+	results := make(chan bool, n)
+	for i := 0; i < n; i++ {
+		go func(i int) { results <- c.Send(i) }(i)
+	}
+	time.Sleep(20 * time.Millisecond) // 让发送方都阻塞住
+
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by senders")
+	}
+	for i := 0; i < n; i++ {
+		if <-results {
+			t.Fatal("blocked Send succeeded without a receiver")
+		}
+	}
+}
+
+// 同时关闭：只关一次，每个 Close 返回时通道都已经关了
+func TestConcurrentClose(t *testing.T) {
+	c := New[int](0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Close()
+			select {
+			case _, ok := <-c.C():
+				if ok {
+					t.Error("received a value")
+				}
+			default:
+				t.Error("Close returned before the channel was closed")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 多个发送方、多个关闭方、一个接收方一起跑：不 panic，发送成功的值一个不少地被收到
+func TestManySendersAndClosers(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		c := New[int](4)
+		var sent atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					send := c.Send
+					if j%2 == 0 {
+						send = c.TrySend
+					}
+					if send(1) {
+						sent.Add(1)
+					} else if c.Closed() {
+						return
+					}
+				}
+			}(i)
+		}
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Millisecond)
+				c.Close()
+			}()
+		}
+
+		received := 0
+		for v := range c.C() {
+			received += v
+		}
+		wg.Wait()
+		if int64(received) != sent.Load() {
+			t.Fatalf("round %d: sent %d, received %d", round, sent.Load(), received)
+		}
+	}
+}
@@ -35,6 +35,8 @@ func f0() {
 }
 
 // 通道泄露
+// 在测试中发现这类泄露见 ../02-01-goroutine/leakcheck/
+// 要监听的通道运行时才确定时，用 selector/ 动态添加 case
 func f1() {
 	fmt.Println("------------------------------ f1")
 	ch1 := make(chan int)
@@ -0,0 +1,184 @@
+// 运行时决定 case 的 select
+//
+// select 语句的 case 在编译时就固定了，demo.go 的 f1 漏写了 ch2 的 case，发送方就永远阻塞。
+// 要监听的通道个数只有运行时才知道时（比如每个连接一个通道），可以用 reflect.Select，
+// Selector 在它上面包了一层：
+//
+//	AddRecv、AddSend 随时添加 case，返回的 CaseID 用来 Remove 和识别是哪个 case 执行了
+//	SetTimeout 相当于多一个 case <-time.After(d)，SetDefault 相当于 default
+//	接收 case 的通道关闭后，Select 照常返回一次 ok=false，然后把这个 case 的通道置为 nil，
+//	之后不会再被选中（03-select.md 里“用 nil 通道禁用 case”的做法）
+//	所有 case 都被禁用或删除时 Select 返回 Done，而不是永远阻塞
+//
+// 发送到已关闭的通道和 select 语句一样会 panic。
+// reflect.Select 比 select 语句慢得多，对比见 benchmark_test.go。Selector 不能并发使用。
+package selector
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// CaseID 标识一个 case，删除后不会被复用
+type CaseID int
+
+// Select 不是因为某个 case 执行而返回时的 ID
+const (
+	Timeout CaseID = -1 // 超时
+	Default CaseID = -2 // 没有就绪的 case，执行了 default
+	Done    CaseID = -3 // 没有可用的 case 了
+)
+
+type Result struct {
+	ID    CaseID
+	Value any  // 接收 case 收到的值，通道关闭时是零值
+	OK    bool // 和 v, ok := <-ch 的 ok 相同；发送 case 为 true
+}
+
+type Selector struct {
+	// cases 的最后一个是留给 default 或超时的位置，SetDefault、SetTimeout 时填好，Select 直接把 cases 交给 reflect.Select；
+	// 两个都没设置时 Select 不带上它
+	cases []reflect.SelectCase
+	ids   []CaseID // 和 cases 除最后一个之外一一对应
+	next  CaseID
+	live  int // 通道不为 nil 的 case 个数
+
+	timeout    time.Duration
+	timer      *time.Timer
+	hasDefault bool
+}
+
+func New() *Selector {
+	return &Selector{cases: []reflect.SelectCase{{Dir: reflect.SelectRecv}}}
+}
+
+// AddRecv 添加 case <-ch，ch 必须是可接收的通道，nil 通道的 case 永远不会执行
+func (s *Selector) AddRecv(ch any) CaseID {
+	v := chanValue(ch, reflect.RecvDir, "AddRecv")
+	return s.add(reflect.SelectCase{Dir: reflect.SelectRecv, Chan: v})
+}
+
+// AddSend 添加 case ch <- v。case 执行后仍然保留，下次 Select 还会再发送 v，不需要时要 Remove
+func (s *Selector) AddSend(ch any, v any) CaseID {
+	c := chanValue(ch, reflect.SendDir, "AddSend")
+	elem := c.Type().Elem()
+	var val reflect.Value
+	if v == nil {
+		val = reflect.Zero(elem)
+	} else {
+		val = reflect.ValueOf(v)
+		if !val.Type().AssignableTo(elem) {
+			panic(fmt.Sprintf("selector: AddSend: %s is not assignable to %s", val.Type(), elem))
+		}
+	}
+	return s.add(reflect.SelectCase{Dir: reflect.SelectSend, Chan: c, Send: val})
+}
+
+func chanValue(ch any, dir reflect.ChanDir, op string) reflect.Value {
+	v := reflect.ValueOf(ch)
+	if v.Kind() != reflect.Chan {
+		panic(fmt.Sprintf("selector: %s of non-chan %T", op, ch))
+	}
+	if v.Type().ChanDir()&dir == 0 {
+		panic(fmt.Sprintf("selector: %s on %s", op, v.Type()))
+	}
+	return v
+}
+
+func (s *Selector) add(c reflect.SelectCase) CaseID {
+	if c.Chan.IsNil() {
+		c.Chan = reflect.Value{} // reflect.Select 忽略 Chan 为零值的 case
+	} else {
+		s.live++
+	}
+	id := s.next
+	s.next++
+	s.cases = slices.Insert(s.cases, len(s.ids), c) // 插在预留位置前面
+	s.ids = append(s.ids, id)
+	return id
+}
+
+// Remove 删除 case，id 不存在时返回 false
+func (s *Selector) Remove(id CaseID) bool {
+	for i, cid := range s.ids {
+		if cid == id {
+			if s.cases[i].Chan.IsValid() {
+				s.live--
+			}
+			s.cases = append(s.cases[:i], s.cases[i+1:]...)
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Len 返回还能被选中的 case 个数，不含被禁用的和 nil 通道的 case
+func (s *Selector) Len() int { return s.live }
+
+// SetTimeout 设置每次 Select 最多等多久，d <= 0 表示一直等
+func (s *Selector) SetTimeout(d time.Duration) {
+	s.timeout = d
+	s.setExtra()
+}
+
+// SetDefault 设置是否有 default：有 default 时 Select 不会阻塞
+func (s *Selector) SetDefault(on bool) {
+	s.hasDefault = on
+	s.setExtra()
+}
+
+// setExtra 按 hasDefault 和 timeout 填好 cases 最后的预留位置。
+// 超时用的计时器只创建一次，它的通道不会变，之后每次 Select 只需要 Reset。
+func (s *Selector) setExtra() {
+	extra := reflect.SelectCase{Dir: reflect.SelectRecv}
+	switch {
+	case s.hasDefault:
+		extra.Dir = reflect.SelectDefault
+	case s.timeout > 0:
+		if s.timer == nil {
+			s.timer = time.NewTimer(s.timeout)
+			s.timer.Stop()
+		}
+		extra.Chan = reflect.ValueOf(s.timer.C)
+	}
+	s.cases[len(s.cases)-1] = extra
+}
+
+// Select 等待一个 case 执行并返回它。多个 case 同时就绪时随机选择一个。
+func (s *Selector) Select() Result {
+	if s.live == 0 && !s.hasDefault && s.timeout <= 0 {
+		return Result{ID: Done}
+	}
+
+	n := len(s.ids)
+	cases := s.cases
+	switch {
+	case s.hasDefault:
+	case s.timeout > 0:
+		s.timer.Reset(s.timeout) // go 1.23 起 Reset 会丢掉通道里没取走的值
+		defer s.timer.Stop()
+	default:
+		cases = cases[:n]
+	}
+
+	chosen, v, ok := reflect.Select(cases)
+	switch {
+	case chosen == n && s.hasDefault:
+		return Result{ID: Default}
+	case chosen == n:
+		return Result{ID: Timeout}
+	}
+
+	c := &s.cases[chosen]
+	if c.Dir == reflect.SelectSend {
+		return Result{ID: s.ids[chosen], OK: true}
+	}
+	if !ok {
+		c.Chan = reflect.Value{} // 通道已关闭，禁用这个 case
+		s.live--
+	}
+	return Result{ID: s.ids[chosen], Value: v.Interface(), OK: ok}
+}
@@ -0,0 +1,291 @@
+package selector
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd selector
+	go test -v -race .
+*/
+
+// catch 执行 f，返回 panic 的内容（没有 panic 时为空）
+func catch(f func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprint(r)
+		}
+	}()
+	f()
+	return ""
+}
+
+// demo.go 的 f1 改用 Selector：ch1、ch2 都在 case 里，两个发送方都能退出
+func TestDemoF1NoLeak(t *testing.T) {
+	ch1 := make(chan int)
+	ch2 := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); ch1 <- 1 }()
+	go func() { defer wg.Done(); ch2 <- 2 }()
+
+	s := New()
+	id1, id2 := s.AddRecv(ch1), s.AddRecv(ch2)
+	got := map[CaseID]any{}
+	for len(got) < 2 {
+		r := s.Select()
+		got[r.ID] = r.Value
+		s.Remove(r.ID) // 每个通道只收一次
+	}
+	if got[id1] != 1 || got[id2] != 2 {
+		t.Fatalf("got %v", got)
+	}
+	if r := s.Select(); r.ID != Done {
+		t.Fatalf("Select after all removed = %+v", r)
+	}
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sender still blocked")
+	}
+}
+
+// 通道个数运行时才知道：每个生产者一个通道，发完就关闭，全部关闭后 Select 返回 Done
+func TestFanIn(t *testing.T) {
+	const producers, perProducer = 50, 10
+	s := New()
+	for p := 0; p < producers; p++ {
+		ch := make(chan int)
+		s.AddRecv((<-chan int)(ch))
+		go func(p int) {
+			defer close(ch)
+			for i := 0; i < perProducer; i++ {
+				ch <- p*perProducer + i
+			}
+		}(p)
+	}
+	seen := make(map[int]bool)
+	closed := 0
+	for {
+		r := s.Select()
+		if r.ID == Done {
+			break
+		}
+		if !r.OK {
+			if r.Value != 0 {
+				t.Fatalf("closed channel value %v", r.Value)
+			}
+			closed++
+			continue
+		}
+		v := r.Value.(int)
+		if seen[v] {
+			t.Fatalf("value %d received twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != producers*perProducer || closed != producers {
+		t.Fatalf("received %d values, %d closes", len(seen), closed)
+	}
+}
+
+// 关闭的通道只报告一次 ok=false，之后这个 case 被禁用，不会一直被选中
+func TestClosedChannelDisabled(t *testing.T) {
+	closedCh := make(chan string)
+	close(closedCh)
+	open := make(chan string, 3)
+	open <- "a"
+	open <- "b"
+
+	s := New()
+	idClosed := s.AddRecv(closedCh)
+	idOpen := s.AddRecv(open)
+	s.SetDefault(true)
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		r := s.Select()
+		switch r.ID {
+		case idClosed:
+			got = append(got, fmt.Sprintf("closed %q %v", r.Value, r.OK))
+		case idOpen:
+			got = append(got, "open "+r.Value.(string))
+		case Default:
+			got = append(got, "default")
+		}
+	}
+	counts := map[string]int{}
+	for _, g := range got {
+		counts[g]++
+	}
+	want := map[string]int{`closed "" false`: 1, "open a": 1, "open b": 1, "default": 2}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Fatalf("got %q", got)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len = %d", s.Len())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := New()
+	ch := make(chan int)
+	s.AddRecv(ch)
+	s.SetTimeout(20 * time.Millisecond)
+	start := time.Now()
+	if r := s.Select(); r.ID != Timeout {
+		t.Fatalf("Select = %+v", r)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("returned after %v", d)
+	}
+
+	// 复用计时器：上一次超时不影响下一次
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		ch <- 7
+	}()
+	if r := s.Select(); r.ID != 0 || r.Value != 7 {
+		t.Fatalf("Select = %+v", r)
+	}
+	time.Sleep(30 * time.Millisecond) // 如果计时器没停，它的值会留到下一次
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		ch <- 8
+	}()
+	if r := s.Select(); r.ID != 0 || r.Value != 8 {
+		t.Fatalf("Select = %+v", r)
+	}
+
+	// 没有 case 时只等超时
+	s.Remove(0)
+	if r := s.Select(); r.ID != Timeout {
+		t.Fatalf("Select with no cases = %+v", r)
+	}
+}
+
+func TestSendCase(t *testing.T) {
+	ch := make(chan int, 2)
+	s := New()
+	id := s.AddSend(ch, 5)
+	s.SetDefault(true)
+	for i := 0; i < 3; i++ {
+		r := s.Select()
+		if i < 2 && (r.ID != id || !r.OK) {
+			t.Fatalf("send %d: %+v", i, r)
+		}
+		if i == 2 && r.ID != Default {
+			t.Fatalf("send to full channel: %+v", r)
+		}
+	}
+	if <-ch != 5 || <-ch != 5 {
+		t.Fatal("wrong values sent")
+	}
+
+	// nil 作为值时发送零值，接口类型的值可以赋给接口通道
+	errs := make(chan error, 2)
+	s = New()
+	s.AddSend(errs, nil)
+	s.Select()
+	s = New()
+	s.AddSend(errs, fmt.Errorf("x"))
+	s.Select()
+	if e := <-errs; e != nil {
+		t.Fatalf("got %v", e)
+	}
+	if e := <-errs; e == nil || e.Error() != "x" {
+		t.Fatalf("got %v", e)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	a, b := make(chan int, 1), make(chan int, 1)
+	s := New()
+	idA := s.AddRecv(a)
+	idB := s.AddRecv(b)
+	if !s.Remove(idA) || s.Remove(idA) || s.Remove(42) {
+		t.Fatal("Remove result")
+	}
+	a <- 1
+	b <- 2
+	if r := s.Select(); r.ID != idB || r.Value != 2 {
+		t.Fatalf("Select = %+v", r)
+	}
+	// 删除后 ID 不复用
+	if idC := s.AddRecv(a); idC == idA || idC == idB {
+		t.Fatalf("reused id %d", idC)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len = %d", s.Len())
+	}
+}
+
+func TestNilChannel(t *testing.T) {
+	var ch chan int
+	s := New()
+	s.AddRecv(ch)
+	s.AddSend(ch, 1)
+	if s.Len() != 0 {
+		t.Fatalf("Len = %d", s.Len())
+	}
+	if r := s.Select(); r.ID != Done {
+		t.Fatalf("Select = %+v", r)
+	}
+}
+
+func TestRandomChoice(t *testing.T) {
+	a, b := make(chan int, 1), make(chan int, 1)
+	s := New()
+	s.AddRecv(a)
+	s.AddRecv(b)
+	counts := [2]int{}
+	for i := 0; i < 1000; i++ {
+		a <- 1
+		b <- 2
+		counts[s.Select().ID]++
+		select {
+		case <-a:
+		case <-b:
+		}
+	}
+	if counts[0] < 400 || counts[1] < 400 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	closed := make(chan int)
+	close(closed)
+	want := catch(func() {
+		select {
+		case closed <- 1:
+		default:
+		}
+	})
+	for _, tc := range []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"not a channel", func() { New().AddRecv(1) }, "selector: AddRecv of non-chan int"},
+		{"recv on send-only", func() { New().AddRecv(make(chan<- int)) }, "selector: AddRecv on chan<- int"},
+		{"send on recv-only", func() { New().AddSend(make(<-chan int), 1) }, "selector: AddSend on <-chan int"},
+		{"wrong type", func() { New().AddSend(make(chan int), "x") }, "selector: AddSend: string is not assignable to int"},
+		{"send on closed", func() {
+			s := New()
+			s.AddSend(closed, 1)
+			s.Select()
+		}, want},
+	} {
+		if got := catch(tc.f); got != tc.want {
+			t.Errorf("%s: panic %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
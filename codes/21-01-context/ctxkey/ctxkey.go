@@ -0,0 +1,60 @@
+// 类型安全的 context key
+//
+// demo.go 的 f3 用字符串 "key" 作为 context.WithValue 的 key。不同的包只要都用了 "key"，
+// 就会互相覆盖、读到对方的值；取出来的还是 any，要自己做类型断言。01-context.md 的“避免滥用 WithValue”
+// 说的就是这类问题。Key[T] 的做法：
+//
+//	每个 *Key[T] 是一个独立的指针，只和它自己相等，别的包不可能造出同一个 key
+//	WithValue 只接受 T，Value 直接返回 T，不需要类型断言
+//
+// 用法：
+//
+//	var RequestID = ctxkey.New[string]("request-id")
+//
+//	ctx = RequestID.WithValue(ctx, "abc")
+//	id, ok := RequestID.Value(ctx)
+//
+// ../keycheck 是配套的 vet 检查，找出用 string、int 等内置类型做 key 的 context.WithValue 调用。
+package ctxkey
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Key 要用 New 创建，用包级变量保存，不要复制
+type Key[T any] struct {
+	name string
+}
+
+// New 创建一个 key，name 只用于打印和 MustValue 的错误信息，不参与比较
+func New[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// WithValue 返回带有 v 的 ctx，相当于 context.WithValue(ctx, k, v)
+func (k *Key[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value 返回 ctx 中 k 对应的值，没有时返回零值和 false。
+// T 是接口类型时，存进去的 nil 和没有值一样。
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// MustValue 和 Value 一样，但没有值时 panic，用在中间件一定会设置的值上
+func (k *Key[T]) MustValue(ctx context.Context) T {
+	v, ok := k.Value(ctx)
+	if !ok {
+		panic(fmt.Sprintf("ctxkey: %s not found in context", k))
+	}
+	return v
+}
+
+// String 在打印 context 时显示，如 context.Background.WithValue(ctxkey.Key[string](request-id), abc)
+func (k *Key[T]) String() string {
+	return fmt.Sprintf("ctxkey.Key[%v](%s)", reflect.TypeFor[T](), k.name)
+}
@@ -0,0 +1,81 @@
+package ctxkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+/*
+shell:
+	cd ctxkey
+	go test -v .
+*/
+
+// 两个包都用字符串 "key"：后设置的把前一个覆盖了，而且类型也对不上
+func TestStringKeysCollide(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "key", "value") // demo.go 的 f3
+	ctx = context.WithValue(ctx, "key", 42)                        // 另一个包，碰巧也叫 "key"
+	if _, ok := ctx.Value("key").(string); ok {
+		t.Fatal("expected the string value to be shadowed")
+	}
+
+	// 同名的 Key 互不影响
+	a := New[string]("key")
+	b := New[int]("key")
+	ctx = a.WithValue(context.Background(), "value")
+	ctx = b.WithValue(ctx, 42)
+	if v, ok := a.Value(ctx); !ok || v != "value" {
+		t.Fatalf("a = %q, %v", v, ok)
+	}
+	if v, ok := b.Value(ctx); !ok || v != 42 {
+		t.Fatalf("b = %d, %v", v, ok)
+	}
+	if ctx.Value("key") != nil {
+		t.Fatal("Key matched a string key")
+	}
+}
+
+func TestValue(t *testing.T) {
+	k := New[int]("n")
+	ctx := context.Background()
+	if v, ok := k.Value(ctx); ok || v != 0 {
+		t.Fatalf("Value on empty ctx = %d, %v", v, ok)
+	}
+	ctx = k.WithValue(ctx, 1)
+	ctx = k.WithValue(ctx, 2) // 内层的值遮住外层的
+	if v := k.MustValue(ctx); v != 2 {
+		t.Fatalf("MustValue = %d", v)
+	}
+
+	// 接口类型：存进去的 nil 当作没有值
+	ke := New[error]("err")
+	if _, ok := ke.Value(ke.WithValue(ctx, nil)); ok {
+		t.Fatal("nil interface reported as present")
+	}
+	errX := errors.New("x")
+	if v, ok := ke.Value(ke.WithValue(ctx, errX)); !ok || v != errX {
+		t.Fatalf("Value = %v, %v", v, ok)
+	}
+}
+
+func TestMustValuePanics(t *testing.T) {
+	k := New[string]("user")
+	defer func() {
+		if r := recover(); fmt.Sprint(r) != "ctxkey: ctxkey.Key[string](user) not found in context" {
+			t.Fatalf("panic = %v", r)
+		}
+	}()
+	k.MustValue(context.Background())
+}
+
+func ExampleKey() {
+	requestID := New[string]("request-id")
+	ctx := requestID.WithValue(context.Background(), "abc")
+	fmt.Println(ctx)
+	fmt.Println(requestID.MustValue(ctx))
+	// Output:
+	// context.Background.WithValue(ctxkey.Key[string](request-id), abc)
+	// abc
+}
@@ -0,0 +1,3 @@
+module ctxkey
+
+go 1.22
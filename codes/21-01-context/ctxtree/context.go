@@ -0,0 +1,348 @@
+// 照着标准库 context 包写的精简版，用来看清取消是怎么在树上传播的
+//
+// 01-cotext-底层.md 讲了 cancelCtx、children、propagateCancel、timerCtx，这里把它们写出来：
+//
+//	cancelCtx 记住自己的子节点（children），cancel 时先关闭 done，再逐个取消子节点，然后把自己从父节点上摘掉
+//	propagateCancel 把新节点挂到最近的 cancelCtx 祖先上；中间隔着 valueCtx 也没关系，
+//	valueCtx 不在取消树上，只在 Value 的查找链上
+//	timerCtx 是带计时器的 cancelCtx，到期时用 DeadlineExceeded 取消自己
+//	父节点比子节点先到期时，WithDeadline 直接退化成 WithCancel
+//
+// 和标准库互通：标准库的 context 可以做父节点（用 context.AfterFunc 挂上去），
+// 这里的 context 也可以做标准库 context 的父节点（标准库会调用 cancelCtx 的 AfterFunc 方法挂上来）。
+// Err 返回的就是 context.Canceled 和 context.DeadlineExceeded。
+//
+// 没有实现 Cause、WithoutCancel 等后来加的功能。Dump、DOT 打印当前的取消树，见 dump.go。
+package ctxtree
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type emptyCtx struct{ name string }
+
+func (emptyCtx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (emptyCtx) Done() <-chan struct{}       { return nil }
+func (emptyCtx) Err() error                  { return nil }
+func (emptyCtx) Value(key any) any           { return nil }
+func (e emptyCtx) String() string            { return e.name }
+
+var (
+	background = emptyCtx{"ctxtree.Background"}
+	todo       = emptyCtx{"ctxtree.TODO"}
+)
+
+// Background 是取消树的根，永远不会被取消，Done 返回 nil
+func Background() context.Context { return background }
+
+func TODO() context.Context { return todo }
+
+// canceler 是可以挂在 children 里的节点：*cancelCtx、*timerCtx、*afterFuncCtx
+type canceler interface {
+	cancel(removeFromParent bool, err error)
+	Done() <-chan struct{}
+}
+
+// closedchan 是共享的已关闭通道：取消时还没人调用过 Done，就不用再创建一个通道
+var closedchan = make(chan struct{})
+
+func init() {
+	close(closedchan)
+}
+
+// cancelCtxKey 的地址用作 key，Value(&cancelCtxKey) 返回链上最近的 *cancelCtx
+var cancelCtxKey int
+
+// seq 记录节点的创建顺序，只用于 Dump 时给子节点排序
+var seq atomic.Uint64
+
+type cancelCtx struct {
+	context.Context // 父节点
+
+	mu       sync.Mutex
+	done     atomic.Value          // chan struct{}，第一次调用 Done 时才创建
+	children map[canceler]struct{} // 第一次 cancel 时置为 nil
+	err      error
+	stop     func() bool // 父节点不是这个包的 cancelCtx 时，撤销 context.AfterFunc
+
+	node canceler // 外层的节点（*cancelCtx 或 *timerCtx），Dump 用
+	seq  uint64
+}
+
+func (c *cancelCtx) Value(key any) any {
+	if key == &cancelCtxKey {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+func (c *cancelCtx) Done() <-chan struct{} {
+	if d := c.done.Load(); d != nil {
+		return d.(chan struct{})
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.done.Load()
+	if d == nil {
+		d = make(chan struct{})
+		c.done.Store(d)
+	}
+	return d.(chan struct{})
+}
+
+func (c *cancelCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *cancelCtx) String() string { return contextName(c.Context) + ".WithCancel" }
+
+func contextName(c context.Context) string {
+	if s, ok := c.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "context"
+}
+
+// WithCancel 返回 parent 的子节点，cancel 或 parent 被取消时关闭 Done
+func WithCancel(parent context.Context) (context.Context, context.CancelFunc) {
+	c := newCancelCtx(parent)
+	return c, func() { c.cancel(true, context.Canceled) }
+}
+
+func newCancelCtx(parent context.Context) *cancelCtx {
+	if parent == nil {
+		panic("cannot create context from nil parent")
+	}
+	c := &cancelCtx{seq: seq.Add(1)}
+	c.node = c
+	c.propagateCancel(parent, c)
+	return c
+}
+
+// propagateCancel 让 parent 被取消时 child 也被取消
+func (c *cancelCtx) propagateCancel(parent context.Context, child canceler) {
+	c.Context = parent
+
+	done := parent.Done()
+	if done == nil {
+		return // parent 永远不会被取消
+	}
+	select {
+	case <-done:
+		child.cancel(false, parent.Err())
+		return
+	default:
+	}
+
+	if p, ok := parentCancelCtx(parent); ok {
+		p.mu.Lock()
+		if p.err != nil {
+			p.mu.Unlock()
+			child.cancel(false, p.err)
+			return
+		}
+		if p.children == nil {
+			p.children = make(map[canceler]struct{})
+		}
+		p.children[child] = struct{}{}
+		p.mu.Unlock()
+		return
+	}
+
+	// parent 是标准库或者别的实现。标准库的 cancelCtx 有 AfterFunc，不需要额外的 goroutine；
+	// 其它实现 context.AfterFunc 会起一个 goroutine 等 parent.Done()
+	stop := context.AfterFunc(parent, func() { child.cancel(false, parent.Err()) })
+	c.mu.Lock()
+	c.stop = stop
+	c.mu.Unlock()
+}
+
+// parentCancelCtx 找到 parent 链上最近的 *cancelCtx。
+// parent 自己包装了 Done（返回的不是那个 cancelCtx 的通道）时不能直接挂上去，返回 false。
+func parentCancelCtx(parent context.Context) (*cancelCtx, bool) {
+	done := parent.Done()
+	if done == closedchan || done == nil {
+		return nil, false
+	}
+	p, ok := parent.Value(&cancelCtxKey).(*cancelCtx)
+	if !ok {
+		return nil, false
+	}
+	pdone, _ := p.done.Load().(chan struct{})
+	if pdone != done {
+		return nil, false
+	}
+	return p, true
+}
+
+// removeChild 把 child 从 parent 的 children 中摘掉
+func removeChild(parent context.Context, child canceler) {
+	p, ok := parentCancelCtx(parent)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	if p.children != nil {
+		delete(p.children, child)
+	}
+	p.mu.Unlock()
+}
+
+// cancel 关闭 done，取消所有子节点。removeFromParent 为 true 时把自己从父节点摘掉：
+// 自己调用 cancel 时要摘，被父节点取消时父节点会整个清空 children，不用摘。
+func (c *cancelCtx) cancel(removeFromParent bool, err error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return // 已经取消过
+	}
+	c.err = err
+	d, _ := c.done.Load().(chan struct{})
+	if d == nil {
+		c.done.Store(closedchan)
+	} else {
+		close(d)
+	}
+	for child := range c.children {
+		// 持有父节点的锁去取消子节点，加锁顺序总是从父到子
+		child.cancel(false, err)
+	}
+	c.children = nil
+	stop := c.stop
+	c.stop = nil
+	c.mu.Unlock()
+
+	if removeFromParent {
+		removeChild(c.Context, c)
+	}
+	if stop != nil {
+		stop()
+	}
+}
+
+// AfterFunc 让标准库把它创建的子节点挂到这里：
+// 标准库的 propagateCancel 和 context.AfterFunc 发现 parent 有这个方法时就调用它，而不是起一个 goroutine
+func (c *cancelCtx) AfterFunc(f func()) (stop func() bool) {
+	a := &afterFuncCtx{f: f, seq: seq.Add(1)}
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		a.cancel(false, c.err)
+		return func() bool { return false }
+	}
+	if c.children == nil {
+		c.children = make(map[canceler]struct{})
+	}
+	c.children[a] = struct{}{}
+	c.mu.Unlock()
+
+	return func() bool {
+		stopped := false
+		a.once.Do(func() { stopped = true })
+		if stopped {
+			c.mu.Lock()
+			delete(c.children, a)
+			c.mu.Unlock()
+		}
+		return stopped
+	}
+}
+
+// afterFuncCtx 是 AfterFunc 挂在 children 里的节点，被取消时在新的 goroutine 中调用 f
+type afterFuncCtx struct {
+	once sync.Once
+	f    func()
+	seq  uint64
+}
+
+func (a *afterFuncCtx) cancel(_ bool, _ error) {
+	a.once.Do(func() { go a.f() })
+}
+
+func (a *afterFuncCtx) Done() <-chan struct{} { return nil }
+
+type timerCtx struct {
+	cancelCtx
+	timer    *time.Timer // 在 cancelCtx.mu 下访问
+	deadline time.Time
+}
+
+// WithDeadline 返回在 d 时刻被取消的子节点。parent 的截止时间更早时，等同于 WithCancel。
+func WithDeadline(parent context.Context, d time.Time) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		panic("cannot create context from nil parent")
+	}
+	if cur, ok := parent.Deadline(); ok && cur.Before(d) {
+		return WithCancel(parent)
+	}
+	c := &timerCtx{deadline: d}
+	c.seq = seq.Add(1)
+	c.node = c
+	c.cancelCtx.propagateCancel(parent, c)
+	dur := time.Until(d)
+	if dur <= 0 {
+		c.cancel(true, context.DeadlineExceeded)
+		return c, func() { c.cancel(false, context.Canceled) }
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.timer = time.AfterFunc(dur, func() { c.cancel(true, context.DeadlineExceeded) })
+	}
+	return c, func() { c.cancel(true, context.Canceled) }
+}
+
+// WithTimeout 相当于 WithDeadline(parent, time.Now().Add(timeout))
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithDeadline(parent, time.Now().Add(timeout))
+}
+
+func (c *timerCtx) Deadline() (time.Time, bool) { return c.deadline, true }
+
+func (c *timerCtx) String() string {
+	return contextName(c.cancelCtx.Context) + ".WithDeadline(" + c.deadline.String() + ")"
+}
+
+func (c *timerCtx) cancel(removeFromParent bool, err error) {
+	c.cancelCtx.cancel(false, err)
+	if removeFromParent {
+		// 父节点的 children 里存的是 *timerCtx，不是里面的 cancelCtx
+		removeChild(c.cancelCtx.Context, c)
+	}
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+}
+
+type valueCtx struct {
+	context.Context
+	key, val any
+}
+
+// WithValue 返回带有 key、val 的子节点。它不在取消树上，Value 沿着链逐层向上查找。
+func WithValue(parent context.Context, key, val any) context.Context {
+	if parent == nil {
+		panic("cannot create context from nil parent")
+	}
+	if key == nil {
+		panic("nil key")
+	}
+	return &valueCtx{parent, key, val}
+}
+
+func (c *valueCtx) Value(key any) any {
+	if c.key == key {
+		return c.val
+	}
+	return c.Context.Value(key)
+}
+
+func (c *valueCtx) String() string { return contextName(c.Context) + ".WithValue" }
@@ -0,0 +1,382 @@
+package ctxtree
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd ctxtree
+	go test -v -race .
+	go test -run=ExampleDOT -v . | sed -n '/digraph/,/^}/p' | dot -Tsvg > tree.svg
+*/
+
+// pair 是同一个操作在标准库和这里各自得到的 context
+type pair struct {
+	parent   int // 在 nodes 中的下标，根为 -1
+	cancels  bool
+	ref, got context.Context
+	refStop  context.CancelFunc
+	gotStop  context.CancelFunc
+}
+
+// 随机建树、随机取消，每一步之后每个节点的 Err、Deadline 都要和标准库一致；
+// 而且每个没取消的节点，children 里恰好是它下面还活着的直接可取消子节点（取消的会被摘掉）
+func TestDifferential(t *testing.T) {
+	base := time.Now().Add(time.Hour)
+	deadlines := []time.Time{base, base.Add(time.Minute), base.Add(2 * time.Minute)}
+	type key int
+
+	for seed := int64(0); seed < 100; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		nodes := []*pair{{parent: -1, ref: context.Background(), got: Background()}}
+		for op := 0; op < 60; op++ {
+			if r.Intn(3) > 0 {
+				pi := r.Intn(len(nodes))
+				p := nodes[pi]
+				n := &pair{parent: pi}
+				switch r.Intn(3) {
+				case 0:
+					n.ref, n.refStop = context.WithCancel(p.ref)
+					n.got, n.gotStop = WithCancel(p.got)
+					n.cancels = true
+				case 1:
+					d := deadlines[r.Intn(len(deadlines))]
+					n.ref, n.refStop = context.WithDeadline(p.ref, d)
+					n.got, n.gotStop = WithDeadline(p.got, d)
+					n.cancels = true
+				case 2:
+					n.ref = context.WithValue(p.ref, key(len(nodes)), len(nodes))
+					n.got = WithValue(p.got, key(len(nodes)), len(nodes))
+				}
+				nodes = append(nodes, n)
+			} else {
+				n := nodes[r.Intn(len(nodes))]
+				if n.cancels {
+					n.refStop()
+					n.gotStop()
+				}
+			}
+
+			for i, n := range nodes {
+				if got, want := n.got.Err(), n.ref.Err(); got != want {
+					t.Fatalf("seed %d op %d node %d: Err = %v, want %v", seed, op, i, got, want)
+				}
+				gd, gok := n.got.Deadline()
+				rd, rok := n.ref.Deadline()
+				if gok != rok || !gd.Equal(rd) {
+					t.Fatalf("seed %d op %d node %d: Deadline = %v %v, want %v %v", seed, op, i, gd, gok, rd, rok)
+				}
+				if i > 0 && !n.cancels && n.got.Value(key(i)) != i {
+					t.Fatalf("seed %d node %d: Value lost", seed, i)
+				}
+			}
+			checkChildren(t, nodes)
+		}
+	}
+}
+
+func checkChildren(t *testing.T, nodes []*pair) {
+	t.Helper()
+	// 每个节点最近的可取消祖先
+	nearest := func(i int) int {
+		for p := nodes[i].parent; p >= 0; p = nodes[p].parent {
+			if nodes[p].cancels {
+				return p
+			}
+		}
+		return -1
+	}
+	want := make(map[int]int)
+	for i, n := range nodes {
+		if n.cancels && n.got.Err() == nil {
+			want[nearest(i)]++
+		}
+	}
+	for i, n := range nodes {
+		if !n.cancels {
+			continue
+		}
+		c := n.got.Value(&cancelCtxKey).(*cancelCtx)
+		c.mu.Lock()
+		got := len(c.children)
+		c.mu.Unlock()
+		if got != want[i] {
+			t.Fatalf("node %d has %d children, want %d", i, got, want[i])
+		}
+	}
+}
+
+// 取消子节点：子节点从父节点摘掉，父节点和兄弟节点不受影响
+func TestChildCancelDetaches(t *testing.T) {
+	root, cancelRoot := WithCancel(Background())
+	defer cancelRoot()
+	a, cancelA := WithCancel(root)
+	b, cancelB := WithCancel(WithValue(root, "k", "v"))
+	defer cancelB()
+	aa, cancelAA := WithCancel(a)
+	defer cancelAA()
+
+	cancelA()
+	if root.Err() != nil || b.Err() != nil {
+		t.Fatal("cancel of child affected parent or sibling")
+	}
+	if aa.Err() != context.Canceled {
+		t.Fatalf("grandchild Err = %v", aa.Err())
+	}
+	var sb strings.Builder
+	Dump(&sb, root)
+	want := "WithCancel depth=0 chain=2 children=1\n" +
+		"└── WithCancel depth=1 chain=4 children=0\n"
+	if sb.String() != want {
+		t.Fatalf("Dump:\n%s\nwant:\n%s", sb.String(), want)
+	}
+	cancelA() // 重复 cancel 没有影响
+}
+
+// 父节点取消时整棵子树一起取消，子树在取消后不再挂在树上
+func TestParentCancelPropagates(t *testing.T) {
+	root, cancelRoot := WithCancel(Background())
+	var leaves []context.Context
+	for i := 0; i < 3; i++ {
+		c, cancel := WithCancel(root)
+		defer cancel()
+		d, cancel := WithTimeout(WithValue(c, i, i), time.Hour)
+		defer cancel()
+		leaves = append(leaves, c, d)
+	}
+	cancelRoot()
+	for i, c := range leaves {
+		select {
+		case <-c.Done():
+		default:
+			t.Fatalf("leaf %d not done", i)
+		}
+		if c.Err() != context.Canceled {
+			t.Fatalf("leaf %d Err = %v", i, c.Err())
+		}
+	}
+	var sb strings.Builder
+	Dump(&sb, root)
+	if sb.String() != "WithCancel depth=0 chain=2 children=0 err=context canceled\n" {
+		t.Fatalf("Dump after cancel:\n%s", sb.String())
+	}
+
+	// 在已经取消的父节点下创建，立即被取消，不会挂上去
+	c, cancel := WithCancel(root)
+	defer cancel()
+	if c.Err() != context.Canceled {
+		t.Fatalf("Err = %v", c.Err())
+	}
+	if root.Value(&cancelCtxKey).(*cancelCtx).children != nil {
+		t.Fatal("child attached to canceled parent")
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	ctx, cancel := WithTimeout(Background(), 20*time.Millisecond)
+	defer cancel()
+	child, cc := WithCancel(ctx)
+	defer cc()
+	select {
+	case <-child.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadline not reached")
+	}
+	if ctx.Err() != context.DeadlineExceeded || child.Err() != context.DeadlineExceeded {
+		t.Fatalf("Err = %v, %v", ctx.Err(), child.Err())
+	}
+
+	// parent 先到期：直接退化成 WithCancel，没有计时器
+	parent, cp := WithDeadline(Background(), time.Now().Add(time.Hour))
+	defer cp()
+	c, cancel := WithDeadline(parent, time.Now().Add(2*time.Hour))
+	defer cancel()
+	if _, ok := c.(*cancelCtx); !ok {
+		t.Fatalf("got %T, want *cancelCtx", c)
+	}
+	pd, _ := parent.Deadline()
+	if d, _ := c.Deadline(); !d.Equal(pd) {
+		t.Fatalf("Deadline = %v, want parent's %v", d, pd)
+	}
+
+	// 截止时间已过：立即到期，也不挂到父节点上
+	c, cancel = WithDeadline(parent, time.Now().Add(-time.Second))
+	defer cancel()
+	if c.Err() != context.DeadlineExceeded {
+		t.Fatalf("Err = %v", c.Err())
+	}
+	checkCount(t, parent, 1)
+
+	// cancel 停掉计时器
+	c, cancel = WithTimeout(parent, time.Minute)
+	cancel()
+	if c.(*timerCtx).timer != nil || c.Err() != context.Canceled {
+		t.Fatal("timer not stopped")
+	}
+}
+
+func checkCount(t *testing.T, ctx context.Context, want int) {
+	t.Helper()
+	c := ctx.Value(&cancelCtxKey).(*cancelCtx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.children) != want {
+		t.Fatalf("%d children, want %d", len(c.children), want)
+	}
+}
+
+// 标准库的 context 做父节点：取消通过 context.AfterFunc 传过来
+func TestStdlibParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	c, cancel := WithCancel(context.WithValue(parent, "k", "v"))
+	if c.Value("k") != "v" {
+		t.Fatal("value from stdlib parent lost")
+	}
+	cancel()
+	// 自己取消时撤销 AfterFunc：之后取消 parent 不会再调用它
+	if stop := c.(*cancelCtx).stop; stop != nil {
+		t.Fatal("AfterFunc not stopped")
+	}
+
+	c, cancel = WithCancel(parent)
+	defer cancel()
+	d, cd := WithTimeout(c, time.Hour)
+	defer cd()
+	cancelParent()
+	select {
+	case <-d.Done():
+	case <-time.After(time.Second):
+		t.Fatal("cancel not propagated from stdlib parent")
+	}
+	if c.Err() != context.Canceled || d.Err() != context.Canceled {
+		t.Fatalf("Err = %v, %v", c.Err(), d.Err())
+	}
+}
+
+// 这里的 context 做标准库 context 的父节点：标准库调用 AfterFunc 挂到 children 里
+func TestStdlibChild(t *testing.T) {
+	root, cancelRoot := WithCancel(Background())
+	defer cancelRoot()
+	std, cancelStd := context.WithTimeout(root, time.Hour)
+	checkCount(t, root, 1)
+
+	var sb strings.Builder
+	Dump(&sb, root)
+	if want := "WithCancel depth=0 chain=2 children=1\n└── AfterFunc depth=1\n"; sb.String() != want {
+		t.Fatalf("Dump:\n%s", sb.String())
+	}
+
+	cancelStd() // 标准库在 removeChild 时调用 stop，从 children 里摘掉
+	checkCount(t, root, 0)
+
+	std, cancelStd = context.WithCancel(WithValue(root, "k", "v"))
+	defer cancelStd()
+	// 中间隔着 WithValue 的 valueCtx 没有 AfterFunc 方法，标准库用 goroutine 等待，所以不在 children 里
+	checkCount(t, root, 0)
+	std2, cancelStd2 := context.WithCancel(root)
+	defer cancelStd2()
+	checkCount(t, root, 1)
+
+	cancelRoot()
+	for _, c := range []context.Context{std, std2} {
+		select {
+		case <-c.Done():
+		case <-time.After(time.Second):
+			t.Fatal("cancel not propagated to stdlib child")
+		}
+		if c.Err() != context.Canceled {
+			t.Fatalf("Err = %v", c.Err())
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	root, cancel := WithCancel(Background())
+	defer cancel()
+	a, ca := WithCancel(root)
+	defer ca()
+	_, cb := WithCancel(a)
+	defer cb()
+	var sb strings.Builder
+	DOT(&sb, root)
+	want := `digraph ctxtree {
+	node [shape=box fontname=monospace];
+	n0 [label="WithCancel\ndepth=0 chain=2 children=1"];
+	n1 [label="WithCancel\ndepth=1 chain=3 children=1"];
+	n2 [label="WithCancel\ndepth=2 chain=4 children=0"];
+	n1 -> n2;
+	n0 -> n1;
+}
+`
+	if sb.String() != want {
+		t.Fatalf("DOT:\n%s", sb.String())
+	}
+
+	sb.Reset()
+	Dump(&sb, WithValue(Background(), "k", "v"))
+	if sb.String() != "(no cancelable context)\n" {
+		t.Fatalf("Dump = %q", sb.String())
+	}
+}
+
+func TestString(t *testing.T) {
+	c, cancel := WithCancel(WithValue(Background(), "k", "v"))
+	defer cancel()
+	if s := fmt.Sprint(c); s != "ctxtree.Background.WithValue.WithCancel" {
+		t.Fatalf("String = %s", s)
+	}
+}
+
+// 01-cotext-底层.md 的“链式调用层级过深”：chain 显示了 Value 最坏要查几层
+func ExampleDump() {
+	deadline := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	root, cancel := WithCancel(Background())
+	defer cancel()
+
+	reqCtx := root
+	for i := 0; i < 3; i++ {
+		reqCtx = WithValue(reqCtx, i, i) // 中间件一层层加值
+	}
+	req, c1 := WithDeadline(reqCtx, deadline)
+	defer c1()
+	db, c2 := WithCancel(req)
+	defer c2()
+	_, c3 := WithCancel(db)
+	defer c3()
+	_, c4 := WithCancel(root)
+	c4() // 已经取消的节点从树上摘掉了
+
+	Dump(os.Stdout, root)
+	// Output:
+	// WithCancel depth=0 chain=2 children=1
+	// └── WithDeadline(2100-01-01T00:00:00Z) depth=1 chain=6 children=1
+	//     └── WithCancel depth=2 chain=7 children=1
+	//         └── WithCancel depth=3 chain=8 children=0
+}
+
+func ExampleDOT() {
+	root, cancel := WithCancel(Background())
+	defer cancel()
+	_, c1 := WithCancel(root)
+	defer c1()
+	_, c2 := context.WithCancel(root)
+	defer c2()
+	DOT(os.Stdout, root)
+	// Output:
+	// digraph ctxtree {
+	// 	node [shape=box fontname=monospace];
+	// 	n0 [label="WithCancel\ndepth=0 chain=2 children=2"];
+	// 	n1 [label="WithCancel\ndepth=1 chain=3 children=0"];
+	// 	n0 -> n1;
+	// 	n2 [label="AfterFunc\ndepth=1"];
+	// 	n0 -> n2;
+	// }
+}
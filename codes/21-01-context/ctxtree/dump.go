@@ -0,0 +1,164 @@
+package ctxtree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// node 是某一时刻取消树上的一个节点
+type node struct {
+	label    string
+	depth    int   // 在取消树中的深度，Dump 的起点为 0
+	chain    int   // 到根要经过几层 context，Value 找不到 key 时要查这么多层
+	err      error // 已经取消时不为 nil
+	children []*node
+}
+
+func (n *node) summary() string {
+	if n.label == "AfterFunc" {
+		return fmt.Sprintf("depth=%d", n.depth)
+	}
+	s := fmt.Sprintf("depth=%d chain=%d children=%d", n.depth, n.chain, len(n.children))
+	if n.err != nil {
+		s += " err=" + n.err.Error()
+	}
+	return s
+}
+
+// snapshot 从 c 开始复制一份子树。每个节点只在复制自己的 children 时持有自己的锁。
+func snapshot(c canceler, depth int) *node {
+	n := &node{depth: depth}
+	var cc *cancelCtx
+	switch x := c.(type) {
+	case *afterFuncCtx:
+		n.label = "AfterFunc"
+		return n
+	case *timerCtx:
+		n.label = "WithDeadline(" + x.deadline.Format(time.RFC3339Nano) + ")"
+		cc = &x.cancelCtx
+	case *cancelCtx:
+		n.label = "WithCancel"
+		cc = x
+	}
+	n.chain = chainLen(cc.node.(context.Context))
+
+	cc.mu.Lock()
+	n.err = cc.err
+	children := make([]canceler, 0, len(cc.children))
+	for child := range cc.children {
+		children = append(children, child)
+	}
+	cc.mu.Unlock()
+
+	sort.Slice(children, func(i, j int) bool { return seqOf(children[i]) < seqOf(children[j]) })
+	for _, child := range children {
+		n.children = append(n.children, snapshot(child, depth+1))
+	}
+	return n
+}
+
+func seqOf(c canceler) uint64 {
+	switch x := c.(type) {
+	case *afterFuncCtx:
+		return x.seq
+	case *timerCtx:
+		return x.seq
+	case *cancelCtx:
+		return x.seq
+	}
+	return 0
+}
+
+// chainLen 数从 c 到根一共几层；遇到不是这个包的 context（标准库的、Background）算一层，不再往上数
+func chainLen(c context.Context) int {
+	n := 0
+	for {
+		n++
+		switch x := c.(type) {
+		case *cancelCtx:
+			c = x.Context
+		case *timerCtx:
+			c = x.cancelCtx.Context
+		case *valueCtx:
+			c = x.Context
+		default:
+			return n
+		}
+	}
+}
+
+// root 找到 ctx 链上最近的取消树节点
+func root(ctx context.Context) *node {
+	if t, ok := ctx.(*timerCtx); ok {
+		return snapshot(t, 0)
+	}
+	if p, ok := ctx.Value(&cancelCtxKey).(*cancelCtx); ok {
+		return snapshot(p.node, 0)
+	}
+	return nil
+}
+
+// Dump 以文本形式打印 ctx 下面当前还挂着的取消树。起点是 ctx 链上最近的 WithCancel 或 WithDeadline 节点，
+// WithValue 不在取消树上，只体现在 chain 里。已经取消的节点没有子节点，也就是说取消后整棵子树就被摘掉了。
+//
+//	WithCancel depth=0 chain=2 children=2
+//	├── WithDeadline(2030-01-01T00:00:00Z) depth=1 chain=4 children=0
+//	└── WithCancel depth=1 chain=3 children=1
+//	    └── AfterFunc depth=2
+//
+// AfterFunc 是标准库 context 挂上来的子节点，或者调用了 context.AfterFunc。
+func Dump(w io.Writer, ctx context.Context) error {
+	n := root(ctx)
+	if n == nil {
+		_, err := fmt.Fprintln(w, "(no cancelable context)")
+		return err
+	}
+	var sb strings.Builder
+	writeText(&sb, n, "", "")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeText(sb *strings.Builder, n *node, prefix, childPrefix string) {
+	fmt.Fprintf(sb, "%s%s %s\n", prefix, n.label, n.summary())
+	for i, c := range n.children {
+		if i == len(n.children)-1 {
+			writeText(sb, c, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			writeText(sb, c, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// DOT 和 Dump 一样，但输出 Graphviz 的 dot 格式：
+//
+//	go test -run=ExampleDOT -v . | dot -Tsvg > tree.svg
+func DOT(w io.Writer, ctx context.Context) error {
+	var sb strings.Builder
+	sb.WriteString("digraph ctxtree {\n\tnode [shape=box fontname=monospace];\n")
+	if n := root(ctx); n != nil {
+		id := 0
+		writeDOT(&sb, n, &id)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeDOT(sb *strings.Builder, n *node, id *int) int {
+	me := *id
+	*id++
+	style := ""
+	if n.err != nil {
+		style = " style=dashed"
+	}
+	fmt.Fprintf(sb, "\tn%d [label=%q%s];\n", me, n.label+"\n"+n.summary(), style)
+	for _, c := range n.children {
+		fmt.Fprintf(sb, "\tn%d -> n%d;\n", me, writeDOT(sb, c, id))
+	}
+	return me
+}
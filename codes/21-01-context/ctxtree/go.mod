@@ -0,0 +1,3 @@
+module ctxtree
+
+go 1.21
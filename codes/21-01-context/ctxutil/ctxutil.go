@@ -0,0 +1,260 @@
+// context 包没有提供、服务里又经常要用的几个函数
+//
+// demo.go 只用到了 WithCancel、WithTimeout、WithDeadline、WithValue，实际写服务时还会遇到：
+//
+//	Merge：请求的 ctx 和服务关闭的 ctx，任何一个结束都要停下来
+//	WithoutCancel：请求结束后还要继续做的事（写日志、异步通知），要保留 ctx 里的值但不随请求取消
+//	AfterFunc：ctx 结束时执行清理，可以撤销
+//	SleepContext：代替 f0~f2 里 default 分支的 time.Sleep，ctx 结束时立即醒来
+//	WithDeadlineCause、WithTimeoutCause：到期时 context.Cause 返回指定的原因，而不只是 DeadlineExceeded
+//	Cause：取 ctx 结束的原因，自己实现的 Context 也能拿到
+//
+// 后几个在 Go 1.21 起 context 包已经有了（WithoutCancel、AfterFunc、WithDeadlineCause、WithTimeoutCause，
+// 以及 context.Cause 对自定义 Context 返回 Err），这里的实现只用到 1.20 的公开 API，可以在老版本上用，
+// 也可以对照着理解标准库是怎么做的。
+package ctxutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clock 是用到的时间函数，测试中用假时钟替换
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// errCtx 是取消时 Err 可以不是 context.Canceled 的 context，Merge 和 WithDeadlineCause 都要用到。
+//
+// 原因记在内嵌的 WithCancelCause 节点上，context.Cause 通过 Value 找到它。Done 用自己的 channel：
+// 标准库发现 Done 和找到的取消节点对不上，就不会把子 context 直接挂到那个节点上（那样子 context 的 Err 总是 Canceled），
+// 而是起一个 goroutine 等 Done，再用这里的 Err 和 Cause 取消子 context，子 context 看到的和 errCtx 自己的一致。
+type errCtx struct {
+	context.Context // WithCancelCause 得到的 ctx
+	cancel          context.CancelCauseFunc
+	done            chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func newErrCtx(parent context.Context) *errCtx {
+	ctx, cancel := context.WithCancelCause(parent)
+	c := &errCtx{Context: ctx, cancel: cancel, done: make(chan struct{})}
+	// parent 结束时内嵌的 ctx 被标准库取消，这里跟着结束
+	AfterFunc(ctx, func() { c.cancelWith(context.Canceled, nil) })
+	return c
+}
+
+// cancelWith 结束 c，之后 Err 返回 err，context.Cause 返回 cause。已经结束时什么都不做。
+// 内嵌的 ctx 已经因为 parent 结束而取消时，以它的 Err 和 Cause 为准。
+func (c *errCtx) cancelWith(err, cause error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if e := c.Context.Err(); e != nil {
+		err = e
+	}
+	c.cancel(cause)
+	c.err = err
+	close(c.done)
+}
+
+func (c *errCtx) Done() <-chan struct{} { return c.done }
+
+func (c *errCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func earlier(a context.Context, d time.Time, ok bool) (time.Time, bool) {
+	if ad, aok := a.Deadline(); aok && (!ok || ad.Before(d)) {
+		return ad, true
+	}
+	return d, ok
+}
+
+type mergeCtx struct {
+	*errCtx
+	a, b context.Context
+}
+
+// Merge 返回的 context 在 a、b 任何一个结束时结束，Err 和 context.Cause 来自先结束的那个，
+// 从它派生的子 context 看到的也是这个 Err 和 Cause。
+// Deadline 取两者中较早的，Value 先在 a 中找，找不到再找 b。
+// 和 WithCancel 一样，不再使用时要调用 cancel，否则等待 a、b 的 goroutine 会泄露。
+func Merge(a, b context.Context) (context.Context, context.CancelFunc) {
+	m := &mergeCtx{errCtx: newErrCtx(a), a: a, b: b}
+	stop := AfterFunc(b, func() { m.cancelWith(b.Err(), context.Cause(b)) })
+	return m, func() {
+		stop()
+		m.cancelWith(context.Canceled, context.Canceled)
+	}
+}
+
+func (m *mergeCtx) Deadline() (time.Time, bool) {
+	d, ok := m.b.Deadline()
+	return earlier(m.a, d, ok)
+}
+
+func (m *mergeCtx) Value(key any) any {
+	// context.Cause 要找的取消节点在 a 这条链上（newErrCtx 建的那个），所以看到的是 cancelWith 记下的原因
+	if v := m.errCtx.Value(key); v != nil {
+		return v
+	}
+	return m.b.Value(key)
+}
+
+func (m *mergeCtx) String() string { return fmt.Sprintf("ctxutil.Merge(%v, %v)", m.a, m.b) }
+
+type withoutCancelCtx struct {
+	parent context.Context
+}
+
+// neverCanceled 永远不会被取消。WithoutCancel 的 Value 先在它上面找，context.Cause 沿着 Value 找最近的取消节点时
+// 停在它这里（原因为 nil），不会找到 parent 的取消节点、返回 parent 的取消原因。
+// 它的 parent 是 Background，没有 goroutine，也不会挂到别的节点上，不调用 cancel 也不会泄露。
+var neverCanceled, _ = context.WithCancelCause(context.Background())
+
+// WithoutCancel 返回的 context 保留 parent 的值，但不会因为 parent 取消或到期而结束，也没有 Deadline
+func WithoutCancel(parent context.Context) context.Context {
+	if parent == nil {
+		panic("cannot create context from nil parent")
+	}
+	return withoutCancelCtx{parent}
+}
+
+func (withoutCancelCtx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (withoutCancelCtx) Done() <-chan struct{}       { return nil }
+func (withoutCancelCtx) Err() error                  { return nil }
+
+func (c withoutCancelCtx) Value(key any) any {
+	if v := neverCanceled.Value(key); v != nil {
+		return v
+	}
+	return c.parent.Value(key)
+}
+
+func (c withoutCancelCtx) String() string { return fmt.Sprintf("ctxutil.WithoutCancel(%v)", c.parent) }
+
+// AfterFunc 在 ctx 结束后在一个新的 goroutine 中调用 f，ctx 已经结束时立即调用。
+// stop 阻止 f 被调用，返回 true 表示确实阻止了，false 表示 f 已经开始执行或者已经 stop 过。
+//
+// 等待 ctx 需要一个 goroutine，所以 ctx 永远不会结束时一定要调用 stop，否则这个 goroutine 会泄露。
+// 标准库的 context.AfterFunc 直接挂在 cancelCtx 上，不需要额外的 goroutine。
+func AfterFunc(ctx context.Context, f func()) (stop func() bool) {
+	const (
+		waiting = iota
+		running
+		stopped
+	)
+	var state atomic.Int32
+	stopc := make(chan struct{})
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+				if state.CompareAndSwap(waiting, running) {
+					f()
+				}
+			case <-stopc:
+			}
+		}()
+	}
+	return func() bool {
+		if state.CompareAndSwap(waiting, stopped) {
+			close(stopc)
+			return true
+		}
+		return false
+	}
+}
+
+// SleepContext 睡 d 或者直到 ctx 结束，返回 nil 表示睡够了，否则返回 ctx.Err()
+func SleepContext(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, realClock{}, d)
+}
+
+func sleep(ctx context.Context, clk clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	wake := make(chan struct{})
+	stop := clk.AfterFunc(d, func() { close(wake) })
+	defer stop()
+	select {
+	case <-wake:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type deadlineCtx struct {
+	*errCtx
+	parent   context.Context
+	deadline time.Time
+}
+
+// WithDeadlineCause 和 context.WithDeadline 一样，但到期时 context.Cause 返回 cause（Err 仍是 DeadlineExceeded）。
+// cause 为 nil 时等同于 WithDeadline。cancel 或 parent 结束时的原因不变。
+func WithDeadlineCause(parent context.Context, d time.Time, cause error) (context.Context, context.CancelFunc) {
+	return withDeadlineCause(parent, realClock{}, d, cause)
+}
+
+// WithTimeoutCause 相当于 WithDeadlineCause(parent, time.Now().Add(timeout), cause)
+func WithTimeoutCause(parent context.Context, timeout time.Duration, cause error) (context.Context, context.CancelFunc) {
+	return withDeadlineCause(parent, realClock{}, time.Now().Add(timeout), cause)
+}
+
+func withDeadlineCause(parent context.Context, clk clock, d time.Time, cause error) (context.Context, context.CancelFunc) {
+	if cause == nil {
+		cause = context.DeadlineExceeded
+	}
+	c := &deadlineCtx{errCtx: newErrCtx(parent), parent: parent, deadline: d}
+	expire := func() { c.cancelWith(context.DeadlineExceeded, cause) }
+	stop := func() bool { return false }
+	if dur := d.Sub(clk.Now()); dur <= 0 {
+		expire()
+	} else {
+		stop = clk.AfterFunc(dur, expire)
+	}
+	return c, func() {
+		stop()
+		c.cancelWith(context.Canceled, context.Canceled)
+	}
+}
+
+func (c *deadlineCtx) Deadline() (time.Time, bool) { return earlier(c.parent, c.deadline, true) }
+
+func (c *deadlineCtx) String() string {
+	return fmt.Sprintf("%v.WithDeadlineCause(%v)", c.parent, c.deadline)
+}
+
+// Cause 和 context.Cause 一样返回 ctx 结束的原因，但 ctx 没有经过 WithCancelCause 一类的节点时返回 ctx.Err()。
+// Go 1.20 的 context.Cause 对自己实现的 Context（只实现了四个方法）总是返回 nil，
+// ctx 明明已经结束了却拿不到原因；Go 1.21 起 context.Cause 也是这样回退到 Err 的。
+func Cause(ctx context.Context) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,486 @@
+package ctxutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd ctxutil
+	go test -v -race .
+*/
+
+// fakeClock 只在 Advance 时前进，到期的回调在 Advance 中按到期顺序同步执行
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers map[*fakeTimer]bool
+}
+
+type fakeTimer struct {
+	at time.Time
+	f  func()
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), timers: make(map[*fakeTimer]bool)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers[t] = true
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		ok := c.timers[t]
+		delete(c.timers, t)
+		return ok
+	}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	for t := range c.timers {
+		if !t.at.After(c.now) {
+			due = append(due, t)
+			delete(c.timers, t)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// waitTimers 等到有 n 个计时器在等待（另一个 goroutine 里的 sleep 已经开始睡了）
+func (c *fakeClock) waitTimers(n int) {
+	for {
+		c.mu.Lock()
+		got := len(c.timers)
+		c.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitDone 等 ctx 结束，最多 1 秒
+func waitDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("%v not done", ctx)
+	}
+}
+
+func assertNotDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+		t.Fatalf("%v done: %v", ctx, ctx.Err())
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+}
+
+type key string
+
+func TestMerge(t *testing.T) {
+	errShutdown := errors.New("server shutting down")
+	for _, tc := range []struct {
+		name      string
+		first     string // 先结束的是 a 还是 b
+		wantErr   error
+		wantCause error
+	}{
+		{"a canceled", "a", context.Canceled, context.Canceled},
+		{"b canceled with cause", "b", context.Canceled, errShutdown},
+		{"b deadline", "b-deadline", context.DeadlineExceeded, context.DeadlineExceeded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, cancelA := context.WithCancel(context.WithValue(context.Background(), key("req"), "a"))
+			defer cancelA()
+			b, cancelB := context.WithCancelCause(context.WithValue(context.Background(), key("srv"), "b"))
+			defer cancelB(nil)
+			var bd context.Context = b
+			if tc.first == "b-deadline" {
+				var c context.CancelFunc
+				bd, c = context.WithDeadline(b, time.Now().Add(10*time.Millisecond))
+				defer c()
+			}
+
+			ctx, cancel := Merge(a, bd)
+			defer cancel()
+			if ctx.Value(key("req")) != "a" || ctx.Value(key("srv")) != "b" || ctx.Value(key("x")) != nil {
+				t.Fatal("values not merged")
+			}
+			assertNotDone(t, ctx)
+
+			switch tc.first {
+			case "a":
+				cancelA()
+			case "b":
+				cancelB(errShutdown)
+			}
+			waitDone(t, ctx)
+			if err := ctx.Err(); err != tc.wantErr {
+				t.Errorf("Err = %v, want %v", err, tc.wantErr)
+			}
+			if cause := context.Cause(ctx); cause != tc.wantCause {
+				t.Errorf("Cause = %v, want %v", cause, tc.wantCause)
+			}
+
+			// 之后另一个也结束，不影响已经记下的结果
+			cancelA()
+			cancelB(errors.New("later"))
+			if err := ctx.Err(); err != tc.wantErr {
+				t.Errorf("Err changed to %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeDeadlineAndChildren(t *testing.T) {
+	now := time.Now()
+	a, ca := context.WithDeadline(context.Background(), now.Add(time.Hour))
+	defer ca()
+	b, cb := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cb()
+	ctx, cancel := Merge(a, b)
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Deadline = %v, %v", d, ok)
+	}
+
+	// 从合并的 context 派生的子 context 也随之取消
+	child, cc := context.WithCancel(ctx)
+	defer cc()
+	cancel()
+	waitDone(t, child)
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("Err = %v", ctx.Err())
+	}
+	if s := fmt.Sprint(ctx); !strings.HasPrefix(s, "ctxutil.Merge(context.Background.WithDeadline(") {
+		t.Fatalf("String = %s", s)
+	}
+}
+
+// b 先到期：Merge 得到的 ctx 和从它派生的子 context 看到的 Err、Cause 一样
+func TestMergeChildSeesFirstErr(t *testing.T) {
+	errShutdown := errors.New("server shutting down")
+	a, ca := context.WithCancel(context.Background())
+	defer ca()
+	b, cb := context.WithCancelCause(context.Background())
+	bd, cbd := context.WithDeadline(b, time.Now().Add(10*time.Millisecond))
+	defer cbd()
+	ctx, cancel := Merge(a, bd)
+	defer cancel()
+
+	child, cc := context.WithCancel(ctx)
+	defer cc()
+	grandchild, cg := context.WithTimeout(child, time.Hour)
+	defer cg()
+	waitDone(t, grandchild)
+	for _, c := range []context.Context{ctx, child, grandchild} {
+		if c.Err() != context.DeadlineExceeded || context.Cause(c) != context.DeadlineExceeded {
+			t.Fatalf("%v: Err = %v, Cause = %v", c, c.Err(), context.Cause(c))
+		}
+	}
+
+	// b 带原因取消时，子 context 也拿到这个原因
+	ctx, cancel = Merge(a, b)
+	defer cancel()
+	child, cc = context.WithCancel(ctx)
+	defer cc()
+	cb(errShutdown)
+	waitDone(t, child)
+	if child.Err() != context.Canceled || context.Cause(child) != errShutdown {
+		t.Fatalf("child Err = %v, Cause = %v", child.Err(), context.Cause(child))
+	}
+}
+
+func TestWithoutCancel(t *testing.T) {
+	parent, cancel := context.WithCancelCause(context.WithValue(context.Background(), key("k"), "v"))
+	parent, c2 := context.WithTimeout(parent, time.Hour)
+	defer c2()
+	ctx := WithoutCancel(parent)
+	cancel(errors.New("request finished"))
+	waitDone(t, parent)
+
+	assertNotDone(t, ctx)
+	if ctx.Done() != nil {
+		t.Fatal("Done is not nil")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("has deadline")
+	}
+	if ctx.Value(key("k")) != "v" {
+		t.Fatal("value lost")
+	}
+	if cause := context.Cause(ctx); cause != nil {
+		t.Fatalf("Cause = %v, parent's cause leaked", cause)
+	}
+
+	// 派生的 context 可以正常取消，不受 parent 影响
+	child, cc := context.WithCancel(ctx)
+	assertNotDone(t, child)
+	cc()
+	waitDone(t, child)
+	if context.Cause(child) != context.Canceled {
+		t.Fatalf("child Cause = %v", context.Cause(child))
+	}
+}
+
+func TestAfterFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	called := make(chan struct{})
+	stop := AfterFunc(ctx, func() { close(called) })
+	cancel()
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("f not called")
+	}
+	if stop() {
+		t.Fatal("stop after f ran returned true")
+	}
+
+	// stop 之后 f 不会被调用
+	ctx, cancel = context.WithCancel(context.Background())
+	stop = AfterFunc(ctx, func() { t.Error("stopped f called") })
+	if !stop() || stop() {
+		t.Fatal("stop results")
+	}
+	cancel()
+
+	// 永远不会结束的 ctx 不启动 goroutine
+	stop = AfterFunc(context.Background(), func() { t.Error("called for Background") })
+	if !stop() {
+		t.Fatal("stop returned false")
+	}
+	time.Sleep(10 * time.Millisecond)
+}
+
+// f0 的循环改用 SleepContext：cancel 后立即退出，不用等这一轮 Sleep 结束
+func TestSleep(t *testing.T) {
+	clk := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticks := make(chan int)
+	exited := make(chan error)
+	go func() {
+		for i := 0; ; i++ {
+			ticks <- i
+			if err := sleep(ctx, clk, 500*time.Millisecond); err != nil {
+				exited <- err
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		if got := <-ticks; got != i {
+			t.Fatalf("tick %d, want %d", got, i)
+		}
+		clk.waitTimers(1)
+		clk.Advance(499 * time.Millisecond)
+		select {
+		case <-ticks:
+			t.Fatal("woke up early")
+		case <-time.After(5 * time.Millisecond):
+		}
+		clk.Advance(time.Millisecond)
+	}
+	<-ticks
+	clk.waitTimers(1)
+	cancel() // 假时钟不前进，只有 ctx 能让它醒来
+	if err := <-exited; err != context.Canceled {
+		t.Fatalf("err = %v", err)
+	}
+	clk.waitTimers(0) // 醒来后计时器被 stop 了
+
+	if err := sleep(ctx, clk, time.Second); err != context.Canceled {
+		t.Fatalf("sleep on done ctx = %v", err)
+	}
+	if err := sleep(context.Background(), clk, 0); err != nil {
+		t.Fatalf("sleep 0 = %v", err)
+	}
+	if err := SleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("SleepContext = %v", err)
+	}
+}
+
+func TestDeadlineCause(t *testing.T) {
+	errSlow := errors.New("backend too slow")
+	clk := newFakeClock()
+	ctx, cancel := withDeadlineCause(context.Background(), clk, clk.Now().Add(2*time.Second), errSlow)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(clk.Now().Add(2*time.Second)) {
+		t.Fatalf("Deadline = %v, %v", d, ok)
+	}
+	clk.Advance(time.Second)
+	assertNotDone(t, ctx)
+	clk.Advance(time.Second)
+	waitDone(t, ctx)
+	if ctx.Err() != context.DeadlineExceeded || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("Err = %v", ctx.Err())
+	}
+	if context.Cause(ctx) != errSlow {
+		t.Fatalf("Cause = %v", context.Cause(ctx))
+	}
+	cancel() // 到期后再 cancel 不改变结果
+	if ctx.Err() != context.DeadlineExceeded || context.Cause(ctx) != errSlow {
+		t.Fatalf("after cancel: %v, %v", ctx.Err(), context.Cause(ctx))
+	}
+}
+
+// 子 context 到期时看到的也是 DeadlineExceeded 和指定的原因
+func TestDeadlineCauseChild(t *testing.T) {
+	errSlow := errors.New("backend too slow")
+	clk := newFakeClock()
+	ctx, cancel := withDeadlineCause(context.Background(), clk, clk.Now().Add(time.Second), errSlow)
+	defer cancel()
+	child, cc := context.WithCancel(ctx)
+	defer cc()
+
+	clk.Advance(time.Second)
+	waitDone(t, child)
+	if child.Err() != context.DeadlineExceeded || Cause(child) != errSlow {
+		t.Fatalf("Err = %v, Cause = %v", child.Err(), Cause(child))
+	}
+}
+
+// plainCtx 只实现了 Context 的四个方法，context.Cause 在它上面找不到取消节点
+type plainCtx struct {
+	context.Context
+	done chan struct{}
+	err  error
+}
+
+func (c *plainCtx) Done() <-chan struct{} { return c.done }
+func (c *plainCtx) Err() error            { return c.err }
+
+func TestCause(t *testing.T) {
+	errSlow := errors.New("backend too slow")
+	clk := newFakeClock()
+	ctx, cancel := withDeadlineCause(context.Background(), clk, clk.Now().Add(time.Second), errSlow)
+	defer cancel()
+	if Cause(ctx) != nil {
+		t.Fatalf("Cause before deadline = %v", Cause(ctx))
+	}
+	clk.Advance(time.Second)
+	if Cause(ctx) != errSlow {
+		t.Fatalf("Cause = %v", Cause(ctx))
+	}
+
+	// 自己实现的 Context：回退到 Err
+	p := &plainCtx{Context: context.Background(), done: make(chan struct{})}
+	if Cause(p) != nil {
+		t.Fatalf("Cause = %v", Cause(p))
+	}
+	p.err = context.DeadlineExceeded
+	close(p.done)
+	if Cause(p) != context.DeadlineExceeded {
+		t.Fatalf("Cause = %v", Cause(p))
+	}
+
+	// WithoutCancel 不带出 parent 的原因
+	parent, cp := context.WithCancelCause(context.Background())
+	cp(errSlow)
+	if Cause(WithoutCancel(parent)) != nil {
+		t.Fatal("WithoutCancel leaked parent's cause")
+	}
+}
+
+func TestDeadlineCauseCanceledFirst(t *testing.T) {
+	clk := newFakeClock()
+	parent, cancelParent := context.WithCancelCause(context.Background())
+	errGone := errors.New("client went away")
+
+	ctx, cancel := withDeadlineCause(parent, clk, clk.Now().Add(time.Second), errors.New("unused"))
+	defer cancel()
+	cancelParent(errGone)
+	waitDone(t, ctx)
+	if ctx.Err() != context.Canceled || context.Cause(ctx) != errGone {
+		t.Fatalf("Err = %v, Cause = %v", ctx.Err(), context.Cause(ctx))
+	}
+	clk.Advance(time.Hour)
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("Err changed to %v", ctx.Err())
+	}
+
+	// cancel 后计时器被撤销
+	ctx, cancel = withDeadlineCause(context.Background(), clk, clk.Now().Add(time.Second), nil)
+	cancel()
+	clk.waitTimers(0)
+	if ctx.Err() != context.Canceled || context.Cause(ctx) != context.Canceled {
+		t.Fatalf("Err = %v, Cause = %v", ctx.Err(), context.Cause(ctx))
+	}
+
+	// 已经过了的截止时间立即到期；cause 为 nil 时和 WithDeadline 一样
+	ctx, cancel = withDeadlineCause(context.Background(), clk, clk.Now(), nil)
+	defer cancel()
+	if ctx.Err() != context.DeadlineExceeded || context.Cause(ctx) != context.DeadlineExceeded {
+		t.Fatalf("Err = %v, Cause = %v", ctx.Err(), context.Cause(ctx))
+	}
+}
+
+func TestTimeoutCauseRealClock(t *testing.T) {
+	errSlow := errors.New("slow")
+	ctx, cancel := WithTimeoutCause(context.Background(), 10*time.Millisecond, errSlow)
+	defer cancel()
+	waitDone(t, ctx)
+	if ctx.Err() != context.DeadlineExceeded || context.Cause(ctx) != errSlow {
+		t.Fatalf("Err = %v, Cause = %v", ctx.Err(), context.Cause(ctx))
+	}
+
+	// parent 的截止时间更早时，Deadline 返回 parent 的
+	parent, cp := context.WithTimeout(context.Background(), time.Minute)
+	defer cp()
+	ctx, cancel = WithDeadlineCause(parent, time.Now().Add(time.Hour), errSlow)
+	defer cancel()
+	if d, _ := ctx.Deadline(); d.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("Deadline = %v", d)
+	}
+}
+
+func ExampleSleepContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	// demo.go f1 的循环：Sleep 被 ctx 打断，到期后立即退出
+	for {
+		fmt.Println("running")
+		if err := SleepContext(ctx, 100*time.Millisecond); err != nil {
+			fmt.Println("stopped:", err)
+			return
+		}
+	}
+	// Output:
+	// running
+	// running
+	// running
+	// stopped: context deadline exceeded
+}
@@ -0,0 +1,3 @@
+module ctxutil
+
+go 1.20
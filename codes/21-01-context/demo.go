@@ -13,6 +13,7 @@ func main() {
 	f3()
 }
 
+// 循环里的 time.Sleep 不会被 ctx 打断，可以换成 ctxutil.SleepContext，见 ctxutil/
 func f0() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -82,6 +83,7 @@ func f2() {
 }
 
 // 携带值的context
+// 字符串 key 容易冲突，改用 ctxkey.Key[T]，keycheck 可以找出这类调用，见 ctxkey/、keycheck/
 func f3() {
 	fmt.Println("f3 start.")
 
@@ -0,0 +1,3 @@
+module httpdeadline
+
+go 1.21
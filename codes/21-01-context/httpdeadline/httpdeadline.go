@@ -0,0 +1,114 @@
+// 跨 HTTP 调用传递 context 的截止时间
+//
+// demo.go 里的 WithTimeout、WithDeadline 只在一个进程里有效。客户端 2 秒后就放弃了，
+// 服务端并不知道，还会继续把活干完，再去调下游，白白占着资源。这里的做法和 gRPC 的 grpc-timeout 一样：
+//
+//	客户端（Transport）把 ctx 剩下的时间写进请求头 X-Request-Timeout-Ms
+//	服务端（Handler）收到后，用“剩下的时间 - 安全余量”建一个带超时的 ctx 交给后面的处理函数，
+//	余量留给写响应和网络传输；服务端再用这个 ctx 调下游时，预算会一层层变少
+//	预算已经用完时两边都立即失败：客户端不发请求，服务端直接返回 504，不进入处理函数
+//
+// 传的是相对时间而不是绝对时刻，两台机器的时钟不一致也没关系，代价是网络传输的时间没有算进去，这也是要留余量的原因之一。
+package httpdeadline
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header 的值是剩余的毫秒数
+const Header = "X-Request-Timeout-Ms"
+
+// Transport 把请求 ctx 的剩余时间写进 Header，ctx 没有截止时间时什么都不加
+type Transport struct {
+	// Base 为 nil 时使用 http.DefaultTransport
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+	d, ok := ctx.Deadline()
+	if !ok {
+		return base.RoundTrip(req)
+	}
+	remaining := time.Until(d)
+	if remaining < time.Millisecond {
+		// 发出去服务端也来不及处理，不如现在就失败
+		closeBody(req)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, context.DeadlineExceeded
+	}
+	// RoundTripper 不能修改传进来的请求
+	req = req.Clone(ctx)
+	req.Header.Set(Header, strconv.FormatInt(remaining.Milliseconds(), 10))
+	return base.RoundTrip(req)
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// Options 配置服务端
+type Options struct {
+	// Margin 是安全余量，处理函数拿到的 ctx 比客户端的预算早这么久到期
+	Margin time.Duration
+	// Default 是请求没有带 Header 时的超时，0 表示不加超时
+	Default time.Duration
+	// Max 限制客户端能要求的最长时间，0 表示不限制
+	Max time.Duration
+}
+
+// Handler 读取 Header，给请求的 ctx 加上截止时间再交给 next：
+//
+//	Header 格式不对：400
+//	预算减去 Margin 之后不剩时间了：504，不调用 next
+func Handler(next http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		budget, ok, err := parse(r.Header.Get(Header))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !ok {
+			if opts.Default <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			budget = opts.Default + opts.Margin
+		}
+		if opts.Max > 0 && budget > opts.Max {
+			budget = opts.Max
+		}
+		budget -= opts.Margin
+		if budget <= 0 {
+			http.Error(w, "deadline budget exhausted", http.StatusGatewayTimeout)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), budget)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func parse(v string) (budget time.Duration, ok bool, err error) {
+	if v == "" {
+		return 0, false, nil
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 || ms > int64(math.MaxInt64/time.Millisecond) {
+		return 0, false, fmt.Errorf("invalid %s: %q", Header, v)
+	}
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
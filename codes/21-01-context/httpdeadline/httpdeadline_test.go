@@ -0,0 +1,186 @@
+package httpdeadline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd httpdeadline
+	go test -v -race .
+*/
+
+// echoHeader 把收到的 Header 原样写回
+func echoHeader() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get(Header))
+	}))
+}
+
+func get(ctx context.Context, client *http.Client, url string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), nil
+}
+
+func TestTransportSetsHeader(t *testing.T) {
+	srv := echoHeader()
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	ms, err := strconv.Atoi(string(body))
+	if err != nil || ms <= 500 || ms > 1000 {
+		t.Fatalf("header = %q", body)
+	}
+	if req.Header.Get(Header) != "" {
+		t.Fatal("Transport modified the caller's request")
+	}
+
+	// 没有截止时间时不加 Header
+	if _, body, err := get(context.Background(), client, srv.URL); err != nil || body != "" {
+		t.Fatalf("header = %q, err = %v", body, err)
+	}
+}
+
+// 预算已经用完：不发请求，返回 DeadlineExceeded
+func TestTransportFailsFast(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	// 截止时间已经过了：http.Client 自己也会报错，但 ctx.Err 可能还没来得及变成非 nil
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, _, err := get(ctx, client, srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+
+	// 只剩不到 1 毫秒：直接交给 Transport，确认它不发请求
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Microsecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := (&Transport{}).RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("server got %d requests", hits.Load())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	type result struct {
+		called      bool
+		hasDeadline bool
+		budget      time.Duration
+	}
+	for _, tc := range []struct {
+		name       string
+		header     string
+		opts       Options
+		wantStatus int
+		wantBudget time.Duration // 0 表示处理函数拿到的 ctx 没有截止时间
+	}{
+		{"no header", "", Options{Margin: 50 * time.Millisecond}, 200, 0},
+		{"no header default", "", Options{Margin: 50 * time.Millisecond, Default: time.Second}, 200, time.Second},
+		{"margin subtracted", "2000", Options{Margin: 500 * time.Millisecond}, 200, 1500 * time.Millisecond},
+		{"capped by max", "60000", Options{Margin: 500 * time.Millisecond, Max: 3 * time.Second}, 200, 2500 * time.Millisecond},
+		{"budget spent", "100", Options{Margin: 100 * time.Millisecond}, 504, 0},
+		{"zero", "0", Options{}, 504, 0},
+		{"malformed", "1.5s", Options{}, 400, 0},
+		{"negative", "-1", Options{}, 400, 0},
+		{"overflow", "9999999999999999", Options{}, 400, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got result
+			h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.called = true
+				var d time.Time
+				d, got.hasDeadline = r.Context().Deadline()
+				got.budget = time.Until(d)
+			}), tc.opts)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(Header, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if got.called != (tc.wantStatus == 200) {
+				t.Fatalf("next called = %v", got.called)
+			}
+			if got.hasDeadline != (tc.wantBudget > 0) {
+				t.Fatalf("has deadline = %v", got.hasDeadline)
+			}
+			if tc.wantBudget > 0 && (got.budget > tc.wantBudget || got.budget < tc.wantBudget-100*time.Millisecond) {
+				t.Fatalf("budget = %v, want ~%v", got.budget, tc.wantBudget)
+			}
+		})
+	}
+}
+
+// 客户端 -> A -> B：每一跳都用收到的 ctx 调下一跳，B 看到的预算比 A 少
+func TestPropagationAcrossHops(t *testing.T) {
+	const margin = 100 * time.Millisecond
+	client := &http.Client{Transport: &Transport{}}
+	seenA, seenB := make(chan string, 1), make(chan string, 1)
+
+	b := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenB <- r.Header.Get(Header)
+	}), Options{Margin: margin}))
+	defer b.Close()
+
+	a := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenA <- r.Header.Get(Header)
+		time.Sleep(50 * time.Millisecond) // A 自己干了点活
+		status, _, err := get(r.Context(), client, b.URL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(status)
+	}), Options{Margin: margin}))
+	defer a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	status, _, err := get(ctx, client, a.URL)
+	if err != nil || status != 200 {
+		t.Fatalf("status = %d, err = %v", status, err)
+	}
+	msA, _ := strconv.Atoi(<-seenA)
+	msB, _ := strconv.Atoi(<-seenB)
+	// A 收到约 1000ms；B 收到的是 A 的预算减去余量、再减去 A 干活用掉的时间
+	if msA > 1000 || msA < 900 || msB > msA-150 || msB < msA-300 {
+		t.Fatalf("A got %dms, B got %dms", msA, msB)
+	}
+}
@@ -0,0 +1,13 @@
+// keycheck 命令，用法见 keycheck 包的文档
+//
+// 用 multichecker 而不是 singlechecker：singlechecker 不注册以分析器命名的开关，
+// go vet -vettool=... -keycheck 会报 flag provided but not defined
+package main
+
+import (
+	"keycheck"
+
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() { multichecker.Main(keycheck.Analyzer) }
@@ -0,0 +1,14 @@
+module keycheck
+
+// x/tools 用 v0.38.0：分析器本身 v0.26.0 起就能用（types.Unalias 要求 go 1.22），
+// 但 v0.38.0 之前的 unitchecker 不认 go vet 新加的 Stdout、WarnDiagnostics 配置，
+// 用新的工具链（这里是 go1.27）go vet -vettool 时报告变成 JSON、退出码为 0。go 1.24.0 是 v0.38.0 的要求
+
+go 1.24.0
+
+require golang.org/x/tools v0.38.0
+
+require (
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+)
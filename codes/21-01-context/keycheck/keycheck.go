@@ -0,0 +1,53 @@
+// vet 检查：context.WithValue 的 key 不能是内置类型
+//
+// demo.go 的 f3 写的是 context.WithValue(ctx, "key", "value")。key 是 string 时，
+// 任何包用同一个字符串都会拿到、覆盖这个值，context.WithValue 的文档也要求 key 用自己定义的类型。
+// 这个检查报告 key 的静态类型是 string、int 等内置类型（包括它们的别名）的调用，
+// 自定义类型（type key string）、指针、结构体不报告。改法见 ../ctxkey。
+//
+// 单独运行或者作为 go vet 的 vettool：
+//
+//	go run ./cmd/keycheck ./...
+//	go build -o /tmp/keycheck ./cmd/keycheck && go vet -vettool=/tmp/keycheck ./...
+package keycheck
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "keycheck",
+	Doc:      "report context.WithValue calls whose key has a built-in type such as string or int",
+	URL:      "https://pkg.go.dev/context#WithValue",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		if !isWithValue(typeutil.Callee(pass.TypesInfo, call)) || len(call.Args) != 3 {
+			return
+		}
+		key := call.Args[1]
+		// 无类型常量（如 "key"、1）记录的是转换后的默认类型
+		b, ok := types.Unalias(pass.TypesInfo.TypeOf(key)).(*types.Basic)
+		if !ok || b.Kind() == types.UntypedNil {
+			return // nil key 运行时就会 panic，不用这里报告
+		}
+		pass.ReportRangef(key, "context.WithValue key should not be of built-in type %s; define an unexported key type to avoid collisions", b)
+	})
+	return nil, nil
+}
+
+func isWithValue(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "context" && fn.Name() == "WithValue"
+}
@@ -0,0 +1,41 @@
+package keycheck
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+/*
+shell:
+	cd keycheck
+	go test -v .
+	go run ./cmd/keycheck ./testdata/src/a   # 看报告的样子
+*/
+
+// testdata/src/a 里每个 want 注释都要有对应的报告，没有 want 的行不能有报告
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
+
+// go vet 按分析器的名字加开关：-vettool 指向编译好的 cmd/keycheck 时，-keycheck 要能被接受，
+// 报告要从 vet 的输出里出来
+func TestVetFlag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds cmd/keycheck and runs go vet")
+	}
+	tool := filepath.Join(t.TempDir(), "keycheck")
+	if out, err := exec.Command("go", "build", "-o", tool, "./cmd/keycheck").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	out, err := exec.Command("go", "vet", "-vettool="+tool, "-keycheck", "./testdata/src/a").CombinedOutput()
+	if err == nil {
+		t.Fatalf("go vet reported nothing:\n%s", out)
+	}
+	if !strings.Contains(string(out), "a.go:10:49: context.WithValue key should not be of built-in type string") {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+}
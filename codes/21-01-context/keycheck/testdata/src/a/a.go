@@ -0,0 +1,50 @@
+package a
+
+import (
+	"context"
+	"fmt"
+)
+
+// demo.go 的 f3
+func f3() {
+	ctx := context.WithValue(context.Background(), "key", "value") // want `context.WithValue key should not be of built-in type string`
+	fmt.Println(ctx.Value("key"))
+}
+
+type (
+	keyType  string
+	aliasKey = string
+	ctxKey   struct{}
+)
+
+const userKey keyType = "user"
+
+var requestKey = new(int)
+
+func builtins(ctx context.Context, s string, n int) {
+	context.WithValue(ctx, s, 1)                  // want `built-in type string`
+	context.WithValue(ctx, n, 1)                  // want `built-in type int`
+	context.WithValue(ctx, 1, 1)                  // want `built-in type int`
+	context.WithValue(ctx, 1.5, 1)                // want `built-in type float64`
+	context.WithValue(ctx, true, 1)               // want `built-in type bool`
+	context.WithValue(ctx, aliasKey("a"), 1)      // want `built-in type string`
+	context.WithValue(ctx, fmt.Sprint("k", n), 1) // want `built-in type string`
+	context.WithValue(ctx, uint8(n), 1)           // want `built-in type uint8`
+	context.WithValue(ctx, interface{}("key"), 1) // 静态类型是 interface{}，不知道里面是什么
+
+	// 自定义类型、指针、结构体都可以
+	context.WithValue(ctx, keyType("a"), 1)
+	context.WithValue(ctx, userKey, 1)
+	context.WithValue(ctx, ctxKey{}, 1)
+	context.WithValue(ctx, requestKey, 1)
+	context.WithValue(ctx, struct{ name string }{}, 1)
+}
+
+// 同名但不是 context 包的函数不检查
+func WithValue(ctx context.Context, key, val any) context.Context { return ctx }
+
+func other(ctx context.Context) {
+	WithValue(ctx, "key", 1)
+	f := context.WithValue
+	f(ctx, "key", 1) // 通过函数值调用时不知道调用的是谁，不报告
+}
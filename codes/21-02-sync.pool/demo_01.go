@@ -2,14 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"math/rand"
+
+	"httpdeadline"
+	"pooledserver"
 )
 
 func main() {
@@ -138,58 +142,50 @@ func f3() {
 
 // 网络连接的对象复用
 // 处理高并发网络请求时，用 sync.Pool 来存储临时对象（如 HTTP 请求或响应的处理结构）。
-type RequestHandler struct {
-	RequestID int
-}
+// RequestHandler 和处理函数在 pooledserver/，测试用的是同一个服务。
+//
+// 外面包一层 httpdeadline.Handler：客户端用 httpdeadline.Transport 把剩余时间带过来，
+// 处理函数拿到的 ctx 比客户端早 Margin 到期，时间不够就返回 504，预算用完时连池子都不碰。
+// 见 ../21-01-context/httpdeadline/
 
-// curl -X GET -d "example data" http://localhost:8088
+// curl -X GET -d "example data" -H "X-Request-Timeout-Ms: 500" http://localhost:8088
 func f4() {
 	fmt.Println("HTTP request pool ------------")
 
-	// RequestHandler计数器
-	var count int32 = 0
-
-	// 创建 RequestHandler 的对象池
-	pool := sync.Pool{
-		New: func() interface{} {
-			fmt.Println("Creating new RequestHandler", atomic.AddInt32(&count, 1))
-			return &RequestHandler{}
+	srv := &pooledserver.Server{
+		Work: 10 * time.Millisecond, // 模拟处理耗时
+		OnNew: func(n int32) {
+			fmt.Println("Creating new RequestHandler", n)
 		},
 	}
-
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		handler := pool.Get().(*RequestHandler)
-		handler.RequestID = int(r.ContentLength) // 模拟处理 ID
-
-		body, _ := io.ReadAll(r.Body)
-		// 输出结果
-		fmt.Fprintf(w, "Handling request with handler: %p, body: %s", handler, body)
-
-		// 清理状态并放回池
-		handler.RequestID = 0
-		pool.Put(handler)
-	})
+	http.Handle("/", httpdeadline.Handler(srv, httpdeadline.Options{Margin: 20 * time.Millisecond}))
 
 	fmt.Println("Server running at :8088")
 	go http.ListenAndServe(":8088", nil)
 }
 
+// 只发固定 100 个请求并打印响应；需要统计延迟分布、状态码时使用 loadgen/ 压测工具
 func testHTTPReq() {
 	const url = "http://localhost:8088"
 	var wg sync.WaitGroup
+	// 请求的 ctx 带截止时间，Transport 把剩余时间写进请求头
+	client := &http.Client{Transport: &httpdeadline.Transport{}}
 
 	// 模拟 100 个并发请求
 	for i := 0; i < 100; i++ {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			resp, err := http.Post(url, "text/plain", bytes.NewBufferString(fmt.Sprintf("Request %d", id)))
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(fmt.Sprintf("Request %d", id)))
+			resp, err := client.Do(req)
 			if err != nil {
 				fmt.Println("Error:", err)
 				return
 			}
 			body, _ := io.ReadAll(resp.Body)
-			fmt.Printf("Response %d: '%s'\n", id, body)
+			fmt.Printf("Response %d: %d '%s'\n", id, resp.StatusCode, body)
 			resp.Body.Close()
 		}(i)
 	}
@@ -0,0 +1,16 @@
+module syncpool
+
+// demo_01.go、demo_02.go 各自是一个 main，在这个目录下逐个运行：go run demo_01.go
+// demo_01.go 的 f4 用到的两个包和 pooledserver/go.mod 一样用 replace 引入
+
+go 1.21
+
+require (
+	httpdeadline v0.0.0
+	pooledserver v0.0.0
+)
+
+replace (
+	httpdeadline => ../21-01-context/httpdeadline
+	pooledserver => ./pooledserver
+)
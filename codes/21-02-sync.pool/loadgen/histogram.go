@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// HDR 风格的直方图（对数分段 + 线性子桶）
+//
+//	每个大桶覆盖 [2^k, 2^(k+1)) 区间，大桶内再均分 subBucketHalf 个子桶，
+//	所以任意值的相对误差都不超过 1/subBucketHalf（约 0.1%，即 3 位有效数字），
+//	同时计数数组大小只和 log2(最大值) 成正比，不随样本数增长。
+const (
+	subBucketBits  = 11
+	subBucketCount = 1 << subBucketBits // 2048
+	subBucketHalf  = subBucketCount / 2 // 1024
+)
+
+type Histogram struct {
+	highest int64 // 可记录的最大值，超过的样本会被截断到该值
+	counts  []int64
+	total   int64
+	min     int64
+	max     int64
+	sum     float64
+}
+
+// NewHistogram 创建可记录 [0, highest] 的直方图
+func NewHistogram(highest int64) *Histogram {
+	if highest < subBucketCount {
+		highest = subBucketCount
+	}
+	bucket := bits.Len64(uint64(highest)) - subBucketBits
+	return &Histogram{
+		highest: highest,
+		counts:  make([]int64, (bucket+2)*subBucketHalf),
+		min:     math.MaxInt64,
+	}
+}
+
+func countsIndex(v int64) int {
+	bucket := bits.Len64(uint64(v)) - subBucketBits
+	if bucket < 0 {
+		bucket = 0
+	}
+	sub := int(v >> uint(bucket))
+	return bucket*subBucketHalf + sub
+}
+
+// indexRange 返回下标对应的取值区间 [lo, hi]
+func indexRange(idx int) (lo, hi int64) {
+	bucket, sub := 0, idx
+	if idx >= subBucketHalf {
+		bucket = idx/subBucketHalf - 1
+		sub = idx%subBucketHalf + subBucketHalf
+	}
+	lo = int64(sub) << uint(bucket)
+	hi = int64(sub+1)<<uint(bucket) - 1
+	return lo, hi
+}
+
+// Record 记录一个样本，负数按 0 处理
+func (h *Histogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	if v > h.highest {
+		v = h.highest
+	}
+	h.counts[countsIndex(v)]++
+	h.total++
+	h.sum += float64(v)
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge 把 other 的样本合并进来，两者的 highest 必须相同
+func (h *Histogram) Merge(other *Histogram) {
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	if other.total > 0 {
+		if other.min < h.min {
+			h.min = other.min
+		}
+		if other.max > h.max {
+			h.max = other.max
+		}
+	}
+}
+
+func (h *Histogram) TotalCount() int64 { return h.total }
+
+func (h *Histogram) Max() int64 { return h.max }
+
+func (h *Histogram) Min() int64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.min
+}
+
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// ValueAtQuantile 返回分位值 q（0~100），与 HdrHistogram 一样取所在子桶的上界，
+// 但不会超过实际记录到的最大值
+func (h *Histogram) ValueAtQuantile(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	if q > 100 {
+		q = 100
+	}
+	target := int64(math.Ceil(q / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			_, hi := indexRange(i)
+			if hi > h.max {
+				hi = h.max
+			}
+			return hi
+		}
+	}
+	return h.max
+}
+
+// countAtOrBelow 返回不大于 v 的样本数（按子桶粒度）
+func (h *Histogram) countAtOrBelow(v int64) int64 {
+	if v > h.highest {
+		v = h.highest
+	}
+	if v < 0 {
+		return 0
+	}
+	var n int64
+	for i := 0; i <= countsIndex(v); i++ {
+		n += h.counts[i]
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHistogramQuantiles(t *testing.T) {
+	h := NewHistogram(highestLatency)
+	for v := int64(1); v <= 10000; v++ {
+		h.Record(v)
+	}
+	cases := []struct {
+		q    float64
+		want int64
+	}{
+		{50, 5000}, {90, 9000}, {99, 9900}, {100, 10000},
+	}
+	for _, c := range cases {
+		got := h.ValueAtQuantile(c.q)
+		// 3 位有效数字，允许 0.1% 的误差
+		if diff := got - c.want; diff < 0 || diff > c.want/1000+1 {
+			t.Errorf("p%v = %d, want ~%d", c.q, got, c.want)
+		}
+	}
+	if h.Max() != 10000 || h.Min() != 1 || h.TotalCount() != 10000 {
+		t.Fatalf("min=%d max=%d total=%d", h.Min(), h.Max(), h.TotalCount())
+	}
+}
+
+// 随机样本与排序后的精确分位值对比
+func TestHistogramRelativeError(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	h := NewHistogram(highestLatency)
+	values := make([]int64, 5000)
+	for i := range values {
+		values[i] = r.Int63n(highestLatency)
+		h.Record(values[i])
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	for _, q := range []float64{10, 50, 90, 99, 99.9} {
+		exact := values[int(math.Ceil(q/100*float64(len(values))))-1]
+		got := h.ValueAtQuantile(q)
+		if got < exact || float64(got-exact) > float64(exact)/subBucketHalf+1 {
+			t.Errorf("p%v = %d, exact %d", q, got, exact)
+		}
+	}
+}
+
+func TestHistogramMergeAndClamp(t *testing.T) {
+	a, b := NewHistogram(1<<20), NewHistogram(1<<20)
+	a.Record(10)
+	b.Record(1 << 30) // 超过上限，截断
+	b.Record(-5)      // 负数按 0
+	a.Merge(b)
+	if a.TotalCount() != 3 || a.Max() != 1<<20 || a.Min() != 0 {
+		t.Fatalf("total=%d max=%d min=%d", a.TotalCount(), a.Max(), a.Min())
+	}
+}
+
+func BenchmarkHistogramRecord(b *testing.B) {
+	h := NewHistogram(highestLatency)
+	for i := 0; i < b.N; i++ {
+		h.Record(int64(i) & 0xfffff)
+	}
+}
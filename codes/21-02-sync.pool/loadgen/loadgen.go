@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 压测配置
+//
+//	闭环（closed-loop）：Rate == 0，Concurrency 个 worker 收到响应后立刻发下一个请求，
+//	                    服务端变慢时发压速度也跟着变慢。
+//	开环（open-loop）：  Rate > 0，按固定速率调度请求，延迟从“计划发送时间”开始算，
+//	                    worker 全忙时的排队时间也计入延迟，避免 coordinated omission。
+type Config struct {
+	URL         string
+	Method      string
+	Body        []byte
+	Concurrency int
+	Requests    int64         // 总请求数，0 表示不限制
+	Duration    time.Duration // 持续时间，0 表示不限制
+	Rate        float64       // 每秒请求数，0 表示闭环模式
+	Timeout     time.Duration // 单个请求超时
+	Client      *http.Client  // 为 nil 时按 Concurrency 创建
+}
+
+// 延迟上限，超过 1 分钟的样本按 1 分钟计
+const highestLatency = int64(time.Minute / time.Microsecond)
+
+type result struct {
+	latency time.Duration
+	status  int
+	err     error
+}
+
+func (c *Config) validate() error {
+	if c.URL == "" {
+		return errors.New("loadgen: url is required")
+	}
+	if c.Concurrency <= 0 {
+		return errors.New("loadgen: concurrency must be positive")
+	}
+	if c.Requests <= 0 && c.Duration <= 0 {
+		return errors.New("loadgen: either requests or duration must be set")
+	}
+	if c.Rate < 0 {
+		return errors.New("loadgen: rate must not be negative")
+	}
+	return nil
+}
+
+// Run 按配置发压，直到请求数用完、持续时间到期或 ctx 被取消
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        cfg.Concurrency,
+				MaxIdleConnsPerHost: cfg.Concurrency,
+			},
+		}
+		defer client.CloseIdleConnections()
+	}
+
+	// 到期只停止发新请求，在途请求只在外部 ctx 取消时中断
+	loopCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	// 单独的 collector 汇总结果，worker 之间不共享直方图，也就不需要加锁
+	results := make(chan result, cfg.Concurrency)
+	rep := newReport()
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range results {
+			rep.add(r)
+		}
+	}()
+
+	do := func(start time.Time) {
+		status, err := send(ctx, client, cfg)
+		results <- result{latency: time.Since(start), status: status, err: err}
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	if cfg.Rate > 0 {
+		openLoop(loopCtx, cfg, &wg, do)
+	} else {
+		closedLoop(loopCtx, cfg, &wg, do)
+	}
+	wg.Wait()
+	close(results)
+	<-collected
+
+	rep.finish(time.Since(start))
+	return rep, nil
+}
+
+// 闭环：每个 worker 串行发请求，共享一个计数器决定何时停止
+func closedLoop(ctx context.Context, cfg Config, wg *sync.WaitGroup, do func(time.Time)) {
+	var issued atomic.Int64
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if cfg.Requests > 0 && issued.Add(1) > cfg.Requests {
+					return
+				}
+				do(time.Now())
+			}
+		}()
+	}
+}
+
+// 开环：调度协程按固定间隔产生“计划发送时间”，worker 从通道里取出后发送
+func openLoop(ctx context.Context, cfg Config, wg *sync.WaitGroup, do func(time.Time)) {
+	schedule := make(chan time.Time, cfg.Concurrency)
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for intended := range schedule {
+				do(intended)
+			}
+		}()
+	}
+
+	interval := time.Duration(float64(time.Second) / cfg.Rate)
+	begin := time.Now()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	defer close(schedule)
+	for i := int64(0); cfg.Requests <= 0 || i < cfg.Requests; i++ {
+		intended := begin.Add(time.Duration(i) * interval)
+		timer.Reset(time.Until(intended))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case schedule <- intended:
+		}
+	}
+}
+
+func send(ctx context.Context, client *http.Client, cfg Config) (int, error) {
+	var body io.Reader
+	if len(cfg.Body) > 0 {
+		body = bytes.NewReader(cfg.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, body)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 读完响应体才能复用连接
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return resp.StatusCode, fmt.Errorf("read body: %w", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+shell:
+	cd loadgen
+	go test -v .
+	go test -run=^$ -bench=. -benchmem
+*/
+
+func TestClosedLoopRequestCount(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "example data" || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	rep, err := Run(context.Background(), Config{
+		URL:         srv.URL,
+		Method:      http.MethodPost,
+		Body:        []byte("example data"),
+		Concurrency: 8,
+		Requests:    100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Requests != 100 || hits.Load() != 100 {
+		t.Fatalf("requests = %d, server hits = %d, want 100", rep.Requests, hits.Load())
+	}
+	if rep.StatusCodes[http.StatusOK] != 100 || rep.Errors != 0 {
+		t.Fatalf("status codes = %v, errors = %d", rep.StatusCodes, rep.Errors)
+	}
+	if rep.Latency.P50 > rep.Latency.P90 || rep.Latency.P90 > rep.Latency.P99 || rep.Latency.P99 > rep.Latency.Max {
+		t.Fatalf("percentiles not monotonic: %+v", rep.Latency)
+	}
+}
+
+func TestStatusCodesAndErrors(t *testing.T) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	rep, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 1, Requests: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.StatusCodes[200] != 5 || rep.StatusCodes[503] != 5 {
+		t.Fatalf("status codes = %v", rep.StatusCodes)
+	}
+
+	// 服务关闭后所有请求都应计为错误
+	url := srv.URL
+	srv.Close()
+	rep, err = Run(context.Background(), Config{URL: url, Concurrency: 2, Requests: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Errors != 4 || len(rep.ErrorKinds) == 0 {
+		t.Fatalf("errors = %d, kinds = %v", rep.Errors, rep.ErrorKinds)
+	}
+}
+
+// 开环模式下服务端变慢时，排队时间也要算进延迟
+func TestOpenLoopMeasuresFromIntendedTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// 1 个 worker，每 5ms 计划一个请求，但每个请求要 20ms
+	rep, err := Run(context.Background(), Config{
+		URL:         srv.URL,
+		Concurrency: 1,
+		Requests:    10,
+		Rate:        200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Requests != 10 {
+		t.Fatalf("requests = %d, want 10", rep.Requests)
+	}
+	// 最后一个请求计划在 45ms 发出，实际要排队到约 180ms 才开始
+	if rep.Latency.Max < (100 * time.Millisecond).Microseconds() {
+		t.Fatalf("max latency %dus does not include queueing delay", rep.Latency.Max)
+	}
+}
+
+func TestDurationStopsLoad(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	start := time.Now()
+	rep, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 4, Duration: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("run took %v", elapsed)
+	}
+	if rep.Requests == 0 || rep.Errors != 0 {
+		t.Fatalf("requests = %d, errors = %d", rep.Requests, rep.Errors)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Concurrency: 1, Requests: 1},
+		{URL: "http://x", Requests: 1},
+		{URL: "http://x", Concurrency: 1},
+		{URL: "http://x", Concurrency: 1, Requests: 1, Rate: -1},
+	} {
+		if _, err := Run(context.Background(), cfg); err == nil {
+			t.Errorf("Run(%+v) returned no error", cfg)
+		}
+	}
+}
+
+func TestReportOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	rep, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 2, Requests: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Requests    int64            `json:"requests"`
+		StatusCodes map[string]int64 `json:"status_codes"`
+		Latency     map[string]any   `json:"latency"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Requests != 20 || decoded.StatusCodes["200"] != 20 {
+		t.Fatalf("decoded = %+v", decoded)
+	}
+	for _, k := range []string{"p50_us", "p90_us", "p99_us", "max_us"} {
+		if _, ok := decoded.Latency[k]; !ok {
+			t.Errorf("latency.%s missing", k)
+		}
+	}
+
+	buf.Reset()
+	rep.WriteText(&buf)
+	if !strings.Contains(buf.String(), "[200] 20") || !strings.Contains(buf.String(), "Percentile") {
+		t.Fatalf("text report:\n%s", buf.String())
+	}
+}
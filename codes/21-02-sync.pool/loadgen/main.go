@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+/*
+loadgen：由 demo_01.go 中的 testHTTPReq 演化而来的压测工具
+
+shell:
+	cd loadgen
+
+	# 先运行 demo_01.go 启动 f4 的 :8088 服务
+	# 闭环：10 个并发，共 1000 个请求
+	go run . -url http://localhost:8088 -c 10 -n 1000 -method POST -body "example data"
+	# 开环：固定 200 req/s，持续 10 秒，输出 JSON
+	go run . -url http://localhost:8088 -c 50 -d 10s -rate 200 -json
+*/
+
+func main() {
+	var (
+		cfg    Config
+		body   string
+		asJSON bool
+	)
+	flag.StringVar(&cfg.URL, "url", "", "target URL")
+	flag.StringVar(&cfg.Method, "method", "GET", "HTTP method")
+	flag.StringVar(&body, "body", "", "request body")
+	flag.IntVar(&cfg.Concurrency, "c", 10, "number of concurrent workers")
+	flag.Int64Var(&cfg.Requests, "n", 0, "total number of requests (0 = unlimited)")
+	flag.DurationVar(&cfg.Duration, "d", 0, "test duration (0 = unlimited)")
+	flag.Float64Var(&cfg.Rate, "rate", 0, "fixed request rate per second (0 = closed-loop)")
+	flag.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "per-request timeout")
+	flag.BoolVar(&asJSON, "json", false, "print the report as JSON")
+	flag.Parse()
+	cfg.Body = []byte(body)
+
+	// Ctrl+C 时中断在途请求，仍然输出已收集的结果
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if asJSON {
+		if err := rep.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	rep.WriteText(os.Stdout)
+}
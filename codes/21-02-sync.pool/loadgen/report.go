@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// 延迟统计，单位微秒
+type LatencySummary struct {
+	Mean float64 `json:"mean_us"`
+	Min  int64   `json:"min_us"`
+	P50  int64   `json:"p50_us"`
+	P90  int64   `json:"p90_us"`
+	P99  int64   `json:"p99_us"`
+	Max  int64   `json:"max_us"`
+}
+
+// 压测结果
+type Report struct {
+	Requests    int64            `json:"requests"`
+	Errors      int64            `json:"errors"`
+	Elapsed     time.Duration    `json:"elapsed_ns"`
+	Throughput  float64          `json:"rps"`
+	Latency     LatencySummary   `json:"latency"`
+	StatusCodes map[int]int64    `json:"status_codes"`
+	ErrorKinds  map[string]int64 `json:"error_kinds,omitempty"`
+
+	hist *Histogram
+}
+
+func newReport() *Report {
+	return &Report{
+		StatusCodes: make(map[int]int64),
+		ErrorKinds:  make(map[string]int64),
+		hist:        NewHistogram(highestLatency),
+	}
+}
+
+func (r *Report) add(res result) {
+	r.Requests++
+	r.hist.Record(res.latency.Microseconds())
+	if res.err != nil {
+		r.Errors++
+		r.ErrorKinds[rootCause(res.err)]++
+		return
+	}
+	r.StatusCodes[res.status]++
+}
+
+func (r *Report) finish(elapsed time.Duration) {
+	r.Elapsed = elapsed
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	h := r.hist
+	r.Latency = LatencySummary{
+		Mean: h.Mean(),
+		Min:  h.Min(),
+		P50:  h.ValueAtQuantile(50),
+		P90:  h.ValueAtQuantile(90),
+		P99:  h.ValueAtQuantile(99),
+		Max:  h.Max(),
+	}
+}
+
+// 错误按最内层原因归类，避免 url.Error 中的地址、端口把同类错误拆散
+func rootCause(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err.Error()
+		}
+		err = next
+	}
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// 百分位分布表，和 HdrHistogram 的输出格式类似
+var distribution = []float64{50, 75, 90, 95, 99, 99.9, 100}
+
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "requests:   %d (errors %d)\n", r.Requests, r.Errors)
+	fmt.Fprintf(w, "elapsed:    %v\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "throughput: %.1f req/s\n", r.Throughput)
+	fmt.Fprintf(w, "latency(us): mean=%.1f p50=%d p90=%d p99=%d max=%d\n",
+		r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+
+	fmt.Fprintf(w, "\n%12s %12s %12s\n", "Value(us)", "Percentile", "TotalCount")
+	for _, q := range distribution {
+		v := r.hist.ValueAtQuantile(q)
+		fmt.Fprintf(w, "%12d %11.3f%% %12d\n", v, q, r.hist.countAtOrBelow(v))
+	}
+
+	fmt.Fprintln(w, "\nstatus codes:")
+	codes := make([]int, 0, len(r.StatusCodes))
+	for c := range r.StatusCodes {
+		codes = append(codes, c)
+	}
+	sort.Ints(codes)
+	for _, c := range codes {
+		fmt.Fprintf(w, "  [%d] %d\n", c, r.StatusCodes[c])
+	}
+	if len(r.ErrorKinds) > 0 {
+		fmt.Fprintln(w, "errors:")
+		kinds := make([]string, 0, len(r.ErrorKinds))
+		for k := range r.ErrorKinds {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for _, k := range kinds {
+			fmt.Fprintf(w, "  %s: %d\n", k, r.ErrorKinds[k])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// minipool 与 sync.Pool 的基准测试
+
+package minipool
+
+import (
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	go test -bench=. -run=^$ -benchmem
+	go test -bench=. -run=^$ -benchmem -cpu=1,4
+*/
+
+/*
+单 goroutine Get/Put
+
+BenchmarkSyncPool               47101851                25.93 ns/op            0 B/op          0 allocs/op
+BenchmarkSyncPool-4             43533776                26.66 ns/op            0 B/op          0 allocs/op
+BenchmarkMiniPool               20224711                60.30 ns/op            0 B/op          0 allocs/op
+BenchmarkMiniPool-4             20270323                66.06 ns/op            0 B/op          0 allocs/op
+
+并发 Get/Put（RunParallel）
+
+BenchmarkSyncPoolParallel       58598150                22.03 ns/op            0 B/op          0 allocs/op
+BenchmarkSyncPoolParallel-4     55780036                24.09 ns/op            0 B/op          0 allocs/op
+BenchmarkMiniPoolParallel       21113322                57.56 ns/op            0 B/op          0 allocs/op
+BenchmarkMiniPoolParallel-4     16254942                71.31 ns/op            0 B/op          0 allocs/op
+
+一次取出/放回 32 个对象
+
+BenchmarkSyncPoolBatch            880914              1586 ns/op               0 B/op          0 allocs/op
+BenchmarkSyncPoolBatch-4          794661              1538 ns/op               0 B/op          0 allocs/op
+BenchmarkMiniPoolBatch            292804              4804 ns/op             496 B/op         31 allocs/op
+BenchmarkMiniPoolBatch-4          289220              4997 ns/op             496 B/op         31 allocs/op
+
+1. sync.Pool 的 pin 只是禁止抢占并读取 P 的 id，成本接近 0；
+   minipool 的 pin/unpin 需要 CAS 和原子写，单个 Get/Put 就慢了一倍多。
+2. 并发时 minipool 的 goroutine 可能在占着分片时被抢占，其它 goroutine 只能换分片或让出 CPU，
+   这正是 runtime 用 procPin 禁止抢占的原因。
+3. Batch 中 31 次分配来自 shared：runtime 用 unsafe 直接把 eface 写进环形队列的槽位，
+   这里用 atomic.Pointer[any] 保存，每次 pushHead 都要为 any 分配一次。
+*/
+
+type payload struct {
+	buf [64]byte
+}
+
+func BenchmarkSyncPool(b *testing.B) {
+	p := sync.Pool{New: func() any { return new(payload) }}
+	for i := 0; i < b.N; i++ {
+		x := p.Get().(*payload)
+		x.buf[0] = 1
+		p.Put(x)
+	}
+}
+
+func BenchmarkMiniPool(b *testing.B) {
+	p := Pool{New: func() any { return new(payload) }}
+	for i := 0; i < b.N; i++ {
+		x := p.Get().(*payload)
+		x.buf[0] = 1
+		p.Put(x)
+	}
+}
+
+func BenchmarkSyncPoolParallel(b *testing.B) {
+	p := sync.Pool{New: func() any { return new(payload) }}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x := p.Get().(*payload)
+			x.buf[0] = 1
+			p.Put(x)
+		}
+	})
+}
+
+func BenchmarkMiniPoolParallel(b *testing.B) {
+	p := Pool{New: func() any { return new(payload) }}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x := p.Get().(*payload)
+			x.buf[0] = 1
+			p.Put(x)
+		}
+	})
+}
+
+// 一次放入多个对象再全部取出，覆盖 shared 链表的 push/pop
+func BenchmarkSyncPoolBatch(b *testing.B) {
+	p := sync.Pool{New: func() any { return new(payload) }}
+	var objs [32]*payload
+	for i := 0; i < b.N; i++ {
+		for j := range objs {
+			objs[j] = p.Get().(*payload)
+		}
+		for j := range objs {
+			p.Put(objs[j])
+		}
+	}
+}
+
+func BenchmarkMiniPoolBatch(b *testing.B) {
+	p := Pool{New: func() any { return new(payload) }}
+	var objs [32]*payload
+	for i := 0; i < b.N; i++ {
+		for j := range objs {
+			objs[j] = p.Get().(*payload)
+		}
+		for j := range objs {
+			p.Put(objs[j])
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module minipool
+
+go 1.21
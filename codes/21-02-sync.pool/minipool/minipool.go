@@ -0,0 +1,153 @@
+// minipool：按 知识点/21-数据结构/02-sync-pool-底层.md 的结构重写的教学版 sync.Pool
+//
+//	local  每个分片一个 poolLocal（private 私有槽 + shared 无锁链表）
+//	victim 上一轮 GC 留下的 local，GC 后的 Get 仍可以从这里复用对象
+//	OnGC   显式模拟 poolCleanup：victim = local，local 清空，旧 victim 丢弃
+//
+// 与 runtime 的差别：用户态拿不到当前 P 的编号，也无法禁止抢占，
+// 这里用“CAS 占用一个分片”来模拟 procPin/procUnpin，分片数取 GOMAXPROCS。
+package minipool
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+type Pool struct {
+	// New 在池为空时创建新对象，可以为 nil
+	New func() any
+
+	once   sync.Once
+	pins   []atomic.Bool // 分片是否被占用，对应 runtime 中 P 的独占
+	local  []poolLocal
+	victim []poolLocal
+
+	// 对应 runtime 中 victimSize 置 0：victim 已经被取空，后续 Get 跳过它
+	victimEmpty atomic.Bool
+}
+
+type poolLocalInternal struct {
+	private any       // 只有占用该分片的 goroutine 能访问
+	shared  poolChain // 占用者 pushHead/popHead，其它分片 popTail
+}
+
+type poolLocal struct {
+	poolLocalInternal
+	// 按 128 字节对齐，避免相邻分片之间的伪共享
+	pad [128 - unsafe.Sizeof(poolLocalInternal{})%128]byte
+}
+
+func (p *Pool) init() {
+	p.once.Do(func() {
+		n := runtime.GOMAXPROCS(0)
+		p.pins = make([]atomic.Bool, n)
+		p.local = make([]poolLocal, n)
+		p.victimEmpty.Store(true)
+	})
+}
+
+// pin 占用一个分片并返回其编号，直到 unpin 前其它 goroutine 不能使用该分片。
+// runtime 直接用当前 P 的 id；这里总是从 0 号分片开始找空闲分片，全部被占用就让出 CPU 再试。
+// 这样没有竞争时同一个 goroutine 总落在同一个分片上，相当于一直运行在同一个 P 上。
+func (p *Pool) pin() int {
+	p.init()
+	for {
+		for pid := range p.pins {
+			if p.pins[pid].CompareAndSwap(false, true) {
+				return pid
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+func (p *Pool) unpin(pid int) {
+	p.pins[pid].Store(false)
+}
+
+// Put 放回对象：private 为空时放到 private，否则放入 shared 的头部
+func (p *Pool) Put(x any) {
+	if x == nil {
+		return
+	}
+	pid := p.pin()
+	l := &p.local[pid]
+	if l.private == nil {
+		l.private = x
+	} else {
+		l.shared.pushHead(x)
+	}
+	p.unpin(pid)
+}
+
+// Get 取出对象：private -> 本分片 shared 头部 -> 其它分片 shared 尾部 -> victim -> New
+func (p *Pool) Get() any {
+	pid := p.pin()
+	l := &p.local[pid]
+	x := l.private
+	l.private = nil
+	if x == nil {
+		// 从头部取，时间局部性更好（刚放回的对象更可能还在 CPU 缓存中）
+		x, _ = l.shared.popHead()
+		if x == nil {
+			x = p.getSlow(pid)
+		}
+	}
+	p.unpin(pid)
+	if x == nil && p.New != nil {
+		x = p.New()
+	}
+	return x
+}
+
+func (p *Pool) getSlow(pid int) any {
+	n := len(p.local)
+	// 从其它分片的尾部窃取
+	for i := 0; i < n; i++ {
+		l := &p.local[(pid+i+1)%n]
+		if x, _ := l.shared.popTail(); x != nil {
+			return x
+		}
+	}
+
+	// 再尝试 victim，和 local 的查找顺序相同
+	if p.victimEmpty.Load() {
+		return nil
+	}
+	l := &p.victim[pid]
+	if x := l.private; x != nil {
+		l.private = nil
+		return x
+	}
+	for i := 0; i < n; i++ {
+		l := &p.victim[(pid+i)%n]
+		if x, _ := l.shared.popTail(); x != nil {
+			return x
+		}
+	}
+	// victim 已空，标记后后续 Get 不再查找
+	p.victimEmpty.Store(true)
+	return nil
+}
+
+// OnGC 模拟一次 GC 中的 poolCleanup。
+// runtime 在 STW 期间执行清理，这里占用全部分片来达到同样的效果。
+func (p *Pool) OnGC() {
+	p.init()
+	for i := range p.pins {
+		for !p.pins[i].CompareAndSwap(false, true) {
+			runtime.Gosched()
+		}
+	}
+
+	// 上一轮的 victim 直接丢弃，交给真正的 GC 回收
+	p.victim = p.local
+	p.local = make([]poolLocal, len(p.pins))
+	p.victimEmpty.Store(false)
+
+	for i := range p.pins {
+		p.pins[i].Store(false)
+	}
+}
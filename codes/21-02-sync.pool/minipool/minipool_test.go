@@ -0,0 +1,171 @@
+package minipool
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+shell:
+	cd minipool
+	go test -v -race .
+	go test -bench=. -run=^$ -benchmem
+*/
+
+func withProcs(t testing.TB, n int) {
+	old := runtime.GOMAXPROCS(n)
+	t.Cleanup(func() { runtime.GOMAXPROCS(old) })
+}
+
+// 同一个 goroutine 总是落在同一个分片上，顺序是确定的：
+// private 先出，然后 shared 按 LIFO 出，和 Put 的顺序并不一致
+func TestSingleShardOrder(t *testing.T) {
+	withProcs(t, 1)
+	p := &Pool{New: func() any { return "New Object" }}
+	p.Put("Object 1") // private
+	p.Put("Object 2") // shared
+	p.Put("Object 3") // shared 头部
+
+	var got []any
+	for i := 0; i < 5; i++ {
+		got = append(got, p.Get())
+	}
+	want := []any{"Object 1", "Object 3", "Object 2", "New Object", "New Object"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+// 复现 demo_02.go：放入 3 个对象后 5 个 goroutine 并发 Get。
+// 每轮中每个对象恰好被取到一次，另外两个 goroutine 拿到 New 的对象，
+// 但哪个 goroutine 拿到哪个对象每轮都可能不同
+func TestConcurrentGetLikeDemo02(t *testing.T) {
+	withProcs(t, 4)
+	outcomes := make(map[string]int)
+	for round := 0; round < 100; round++ {
+		p := &Pool{New: func() any { return "New Object" }}
+		p.Put("Object 1")
+		p.Put("Object 2")
+		p.Put("Object 3")
+
+		got := make([]string, 5)
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				got[id] = p.Get().(string)
+			}(i)
+		}
+		wg.Wait()
+		outcomes[strings.Join(got, ", ")]++
+
+		sorted := append([]string(nil), got...)
+		sort.Strings(sorted)
+		want := []string{"New Object", "New Object", "Object 1", "Object 2", "Object 3"}
+		if fmt.Sprint(sorted) != fmt.Sprint(want) {
+			t.Fatalf("round %d: got %v, want the multiset %v", round, got, want)
+		}
+	}
+	for o, n := range outcomes {
+		t.Logf("%3d x [%s]", n, o)
+	}
+}
+
+func TestOnGCMovesToVictim(t *testing.T) {
+	p := &Pool{}
+	p.Put("survivor")
+	p.OnGC()
+	// 第一次 GC 后对象在 victim 中，仍然可以取回
+	if got := p.Get(); got != "survivor" {
+		t.Fatalf("after one GC got %v, want survivor", got)
+	}
+
+	p.Put("dropped")
+	p.OnGC()
+	p.OnGC()
+	// 连续两次 GC 后，victim 也被丢弃
+	if got := p.Get(); got != nil {
+		t.Fatalf("after two GCs got %v, want nil", got)
+	}
+}
+
+func TestShardGrowsChain(t *testing.T) {
+	withProcs(t, 1)
+	p := &Pool{}
+	const n = 1000
+	for i := 0; i < n; i++ {
+		p.Put(i)
+	}
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		x := p.Get()
+		if x == nil {
+			t.Fatalf("pool drained after %d gets", i)
+		}
+		seen[x.(int)] = true
+	}
+	if len(seen) != n || p.Get() != nil {
+		t.Fatalf("got %d distinct objects", len(seen))
+	}
+}
+
+// 并发 Put/Get/OnGC：同一个对象不能同时被两个 goroutine 持有
+func TestConcurrentNoDuplicateOwners(t *testing.T) {
+	withProcs(t, 4)
+	type obj struct{ inUse atomic.Bool }
+	p := &Pool{New: func() any { return new(obj) }}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				o := p.Get().(*obj)
+				if !o.inUse.CompareAndSwap(false, true) {
+					t.Error("object handed out twice")
+					return
+				}
+				o.inUse.Store(false)
+				p.Put(o)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			p.OnGC()
+			runtime.Gosched()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestDequeue(t *testing.T) {
+	d := newChainElt(4)
+	for i := 0; i < 4; i++ {
+		if !d.pushHead(i) {
+			t.Fatalf("push %d failed", i)
+		}
+	}
+	if d.pushHead(4) {
+		t.Fatal("push into a full dequeue succeeded")
+	}
+	if v, _ := d.popTail(); v != 0 {
+		t.Fatalf("popTail = %v, want 0", v)
+	}
+	if v, _ := d.popHead(); v != 3 {
+		t.Fatalf("popHead = %v, want 3", v)
+	}
+	// popTail 释放的槽可以再次 push
+	if !d.pushHead(5) {
+		t.Fatal("push after popTail failed")
+	}
+}
@@ -0,0 +1,161 @@
+package minipool
+
+import "sync/atomic"
+
+// poolDequeue：固定大小的无锁环形队列，对应 runtime 的 sync/poolqueue.go
+//
+//	单生产者多消费者：只有持有分片的 goroutine 能 pushHead/popHead，
+//	其它分片的 goroutine 只能从尾部 popTail “窃取”。
+//	head 和 tail 打包在一个 uint64 中，一次 CAS 就能同时判断队列空/满。
+type poolDequeue struct {
+	headTail atomic.Uint64
+	vals     []atomic.Pointer[any] // nil 表示空槽；popTail 清空后生产者才能复用该槽
+}
+
+const (
+	dequeueBits  = 32
+	dequeueLimit = (1 << dequeueBits) / 4
+)
+
+func (d *poolDequeue) unpack(ptrs uint64) (head, tail uint32) {
+	const mask = 1<<dequeueBits - 1
+	head = uint32((ptrs >> dequeueBits) & mask)
+	tail = uint32(ptrs & mask)
+	return
+}
+
+func (d *poolDequeue) pack(head, tail uint32) uint64 {
+	const mask = 1<<dequeueBits - 1
+	return (uint64(head) << dequeueBits) | uint64(tail&mask)
+}
+
+// pushHead 在头部放入 val，队列满时返回 false。只能由生产者调用
+func (d *poolDequeue) pushHead(val any) bool {
+	head, tail := d.unpack(d.headTail.Load())
+	if (tail+uint32(len(d.vals)))&(1<<dequeueBits-1) == head {
+		return false // 满
+	}
+	slot := &d.vals[head&uint32(len(d.vals)-1)]
+	if slot.Load() != nil {
+		// 另一个 goroutine 正在 popTail 这个槽，还没清空，视为满
+		return false
+	}
+	slot.Store(&val)
+	// 先写槽再增加 head，消费者看到新的 head 时一定能看到槽里的值
+	d.headTail.Add(1 << dequeueBits)
+	return true
+}
+
+// popHead 从头部取出，只能由生产者调用
+func (d *poolDequeue) popHead() (any, bool) {
+	var slot *atomic.Pointer[any]
+	for {
+		ptrs := d.headTail.Load()
+		head, tail := d.unpack(ptrs)
+		if tail == head {
+			return nil, false // 空
+		}
+		// 先用 CAS 认领槽位，和 popTail 竞争同一个元素时只有一方能成功
+		head--
+		if d.headTail.CompareAndSwap(ptrs, d.pack(head, tail)) {
+			slot = &d.vals[head&uint32(len(d.vals)-1)]
+			break
+		}
+	}
+	val := slot.Swap(nil)
+	return *val, true
+}
+
+// popTail 从尾部取出，任意 goroutine 都可以调用
+func (d *poolDequeue) popTail() (any, bool) {
+	var slot *atomic.Pointer[any]
+	for {
+		ptrs := d.headTail.Load()
+		head, tail := d.unpack(ptrs)
+		if tail == head {
+			return nil, false
+		}
+		if d.headTail.CompareAndSwap(ptrs, d.pack(head, tail+1)) {
+			slot = &d.vals[tail&uint32(len(d.vals)-1)]
+			break
+		}
+	}
+	// 清空槽位即把它还给生产者
+	val := slot.Swap(nil)
+	return *val, true
+}
+
+// poolChain：由 poolDequeue 组成的双向链表，每个新节点容量翻倍
+//
+//	head 是最新（最大）的节点，只有生产者访问；tail 是最旧的节点，消费者会并发访问。
+type poolChain struct {
+	head *poolChainElt
+	tail atomic.Pointer[poolChainElt]
+}
+
+type poolChainElt struct {
+	poolDequeue
+	next, prev atomic.Pointer[poolChainElt]
+}
+
+func newChainElt(size int) *poolChainElt {
+	d := new(poolChainElt)
+	d.vals = make([]atomic.Pointer[any], size) // size 必须是 2 的幂
+	return d
+}
+
+func (c *poolChain) pushHead(val any) {
+	d := c.head
+	if d == nil {
+		const initSize = 8
+		d = newChainElt(initSize)
+		c.head = d
+		c.tail.Store(d)
+	}
+	if d.pushHead(val) {
+		return
+	}
+
+	// 当前节点满了，分配一个两倍大小的新节点
+	newSize := len(d.vals) * 2
+	if newSize >= dequeueLimit {
+		newSize = dequeueLimit
+	}
+	d2 := newChainElt(newSize)
+	d2.prev.Store(d)
+	d.next.Store(d2)
+	c.head = d2
+	d2.pushHead(val)
+}
+
+func (c *poolChain) popHead() (any, bool) {
+	for d := c.head; d != nil; d = d.prev.Load() {
+		if val, ok := d.popHead(); ok {
+			return val, ok
+		}
+		// 旧节点里可能还有没被窃取的元素，继续往前找
+	}
+	return nil, false
+}
+
+func (c *poolChain) popTail() (any, bool) {
+	d := c.tail.Load()
+	if d == nil {
+		return nil, false
+	}
+	for {
+		// 必须在 popTail 之前读 next：如果 d 为空且 next 为 nil，才能确定整个链为空
+		d2 := d.next.Load()
+		if val, ok := d.popTail(); ok {
+			return val, ok
+		}
+		if d2 == nil {
+			return nil, false
+		}
+		// d 已经空了并且后面还有节点，把它从链上摘掉，下次不必再检查
+		if c.tail.CompareAndSwap(d, d2) {
+			d2.prev.Store(nil)
+		}
+		d = d2
+	}
+}
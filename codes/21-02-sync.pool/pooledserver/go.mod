@@ -0,0 +1,7 @@
+module pooledserver
+
+go 1.21
+
+require httpdeadline v0.0.0
+
+replace httpdeadline => ../../21-01-context/httpdeadline
@@ -0,0 +1,77 @@
+// demo_01.go 的 f4：用 sync.Pool 复用 RequestHandler 的 HTTP 服务
+//
+// 原来的 f4 把处理函数直接写在 http.HandleFunc 里，这里把它提出来，f4 和测试用的是同一个服务：
+//
+//	每个请求从池里取一个 RequestHandler，处理完清理状态放回去
+//	处理过程（Work）会看请求的 ctx，客户端给的时间不够就放弃，返回 504
+//	放回池子用 defer，超时提前返回时 RequestHandler 也不会漏掉
+//
+// 请求的截止时间由外面包的 httpdeadline.Handler 设置，见 f4。
+package pooledserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 网络连接的对象复用
+// 处理高并发网络请求时，用 sync.Pool 来存储临时对象（如 HTTP 请求或响应的处理结构）。
+type RequestHandler struct {
+	RequestID int
+}
+
+// Server 的零值可以直接使用
+type Server struct {
+	// Work 模拟每个请求的处理耗时，0 表示不耗时
+	Work time.Duration
+	// OnNew 在池里没有对象、新建 RequestHandler 时调用，n 是新建的总数，可以为 nil
+	OnNew func(n int32)
+
+	pool    sync.Pool
+	created atomic.Int32
+}
+
+// Created 返回一共新建了多少个 RequestHandler
+func (s *Server) Created() int32 {
+	return s.created.Load()
+}
+
+func (s *Server) get() *RequestHandler {
+	if h, ok := s.pool.Get().(*RequestHandler); ok {
+		return h
+	}
+	n := s.created.Add(1)
+	if s.OnNew != nil {
+		s.OnNew(n)
+	}
+	return &RequestHandler{}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler := s.get()
+	defer func() {
+		// 清理状态并放回池
+		handler.RequestID = 0
+		s.pool.Put(handler)
+	}()
+	handler.RequestID = int(r.ContentLength) // 模拟处理 ID
+
+	body, _ := io.ReadAll(r.Body)
+	if s.Work > 0 {
+		t := time.NewTimer(s.Work)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			// 客户端给的时间不够，没必要再干下去
+			http.Error(w, r.Context().Err().Error(), http.StatusGatewayTimeout)
+			return
+		}
+	}
+	// 输出结果
+	fmt.Fprintf(w, "Handling request with handler: %p, body: %s", handler, body)
+}
@@ -0,0 +1,130 @@
+package pooledserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"httpdeadline"
+)
+
+/*
+shell:
+	cd pooledserver
+	go test -v -race .
+*/
+
+// newServer 和 f4 一样把 Server 包在 httpdeadline.Handler 里，客户端和 testHTTPReq 一样用 httpdeadline.Transport
+func newServer(t *testing.T, s *Server, margin time.Duration) (*httptest.Server, *http.Client) {
+	srv := httptest.NewServer(httpdeadline.Handler(s, httpdeadline.Options{Margin: margin}))
+	t.Cleanup(srv.Close)
+	return srv, &http.Client{Transport: &httpdeadline.Transport{}}
+}
+
+func post(ctx context.Context, client *http.Client, url, body string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(body))
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b), nil
+}
+
+func TestServer(t *testing.T) {
+	const margin = 20 * time.Millisecond
+	var news atomic.Int32
+	s := &Server{Work: 50 * time.Millisecond, OnNew: func(int32) { news.Add(1) }}
+	srv, client := newServer(t, s, margin)
+
+	withTimeout := func(d time.Duration) (int, string, time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+		start := time.Now()
+		status, body, err := post(ctx, client, srv.URL, "Request 1")
+		if err != nil {
+			t.Fatalf("timeout %v: %v", d, err)
+		}
+		return status, body, time.Since(start)
+	}
+
+	// 时间够：正常处理
+	status, body, _ := withTimeout(time.Second)
+	if status != 200 || !strings.HasPrefix(body, "Handling request with handler: ") || !strings.HasSuffix(body, "body: Request 1") {
+		t.Fatalf("status = %d, body = %q", status, body)
+	}
+
+	// 时间不够：服务端在客户端放弃之前（提前 margin）返回 504，客户端能收到响应而不是自己超时
+	status, _, elapsed := withTimeout(40 * time.Millisecond)
+	if status != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", status)
+	}
+	if elapsed >= 40*time.Millisecond {
+		t.Fatalf("response after %v, should come before the client's deadline", elapsed)
+	}
+
+	// 预算只够余量：中间件直接拒绝，Server 和池子都没被碰过
+	created := s.Created()
+	if status, _, _ := withTimeout(margin); status != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", status)
+	}
+	if s.Created() != created {
+		t.Fatal("handler ran although the budget was spent")
+	}
+	if news.Load() != s.Created() {
+		t.Fatalf("OnNew called %d times, created %d", news.Load(), s.Created())
+	}
+}
+
+// 并发请求里一半超时：超时的请求也会把 RequestHandler 放回池子，创建的对象数不会随请求数增长
+func TestServerReusesHandlersOnTimeout(t *testing.T) {
+	s := &Server{Work: 100 * time.Millisecond}
+	srv, client := newServer(t, s, 20*time.Millisecond)
+
+	const workers, perWorker = 4, 10
+	var ok, timedOut atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				d := time.Second
+				if (i+j)%2 == 0 {
+					d = 60 * time.Millisecond
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), d)
+				status, _, err := post(ctx, client, srv.URL, fmt.Sprintf("Request %d", i))
+				cancel()
+				switch {
+				case err != nil:
+					t.Errorf("request: %v", err)
+				case status == http.StatusOK:
+					ok.Add(1)
+				case status == http.StatusGatewayTimeout:
+					timedOut.Add(1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if ok.Load() != workers*perWorker/2 || timedOut.Load() != workers*perWorker/2 {
+		t.Fatalf("ok = %d, timed out = %d", ok.Load(), timedOut.Load())
+	}
+	// sync.Pool 在 GC 时会清空，所以只检查远少于请求数
+	if c := s.Created(); c > workers*perWorker/2 {
+		t.Fatalf("created %d handlers for %d requests", c, workers*perWorker)
+	}
+}
@@ -0,0 +1,3 @@
+module poolstats
+
+go 1.21
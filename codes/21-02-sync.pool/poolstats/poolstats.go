@@ -0,0 +1,214 @@
+// sync.Pool 复用效果统计
+//
+// demo_01.go 的 f4 用 atomic.AddInt32(&count, 1) 手动统计 New 的调用次数，
+// 这里把这类计数封装起来：记录 Get、Put、未命中时的 New，以及 GC 后被丢掉的对象，
+// 用命中率判断池化到底有没有用。
+//
+// sync.Pool 在每轮 GC 时把对象移到 victim，下一轮再清掉。Get 未命中的原因有两种：
+// 对象被 GC 清掉了，或者对象放在别的 P 的本地池里、正被并发的 Get 拿走。
+// 只有上一次 Put 之后 NumGC 变过，才把按计数推算仍在池里的对象算作丢失（lost）。
+package poolstats
+
+import (
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Pool 是带统计的 sync.Pool
+type Pool[T any] struct {
+	name  string
+	pool  sync.Pool
+	newFn func() T
+
+	gets atomic.Int64
+	puts atomic.Int64
+	news atomic.Int64 // 未命中，调用了 New
+	lost atomic.Int64 // 放回后没被取出、被 GC 清掉的对象
+
+	// 按计数推算的池内对象数，Get 未命中且上次 Put 之后发生过 GC 时，算作被 GC 清掉了
+	pooled atomic.Int64
+	putGC  atomic.Uint32 // 最近一次 Put 时的 NumGC
+
+	baseGC atomic.Uint32 // 创建或 Reset 时的 NumGC
+}
+
+// New 创建并注册一个名为 name 的池，newFn 的作用和 sync.Pool.New 相同。
+// 不再使用时调用 Close 从 Report 中移除。
+func New[T any](name string, newFn func() T) *Pool[T] {
+	p := &Pool[T]{name: name, newFn: newFn}
+	p.baseGC.Store(numGC())
+	registry.add(p)
+	return p
+}
+
+func (p *Pool[T]) Name() string { return p.name }
+
+func (p *Pool[T]) Get() T {
+	p.gets.Add(1)
+	if x := p.pool.Get(); x != nil {
+		p.take()
+		return x.(T)
+	}
+	p.news.Add(1)
+	// 池里应该还有对象却没取到，并且放回之后发生过 GC：这些对象已经连同 victim 一起被清掉。
+	// 没有发生 GC 的未命中是并发 Get 或对象在别的 P 上，不算丢失。
+	if p.pooled.Load() > 0 && numGC() != p.putGC.Load() {
+		p.lost.Add(p.pooled.Swap(0))
+	}
+	return p.newFn()
+}
+
+// take 在命中时把 pooled 减一。Reset 会把 pooled 清零，之后取出的是 Reset 前放回的对象，不能减成负数。
+func (p *Pool[T]) take() {
+	for {
+		n := p.pooled.Load()
+		if n <= 0 || p.pooled.CompareAndSwap(n, n-1) {
+			return
+		}
+	}
+}
+
+func (p *Pool[T]) Put(x T) {
+	p.puts.Add(1)
+	p.putGC.Store(numGC())
+	p.pooled.Add(1)
+	p.pool.Put(x)
+}
+
+// Close 把池从 Report 的汇总中移除，池本身仍然可以使用
+func (p *Pool[T]) Close() {
+	registry.remove(p)
+}
+
+// Reset 清零计数，基准测试中排除预热阶段时使用
+func (p *Pool[T]) Reset() {
+	p.gets.Store(0)
+	p.puts.Store(0)
+	p.news.Store(0)
+	p.lost.Store(0)
+	p.pooled.Store(0)
+	p.baseGC.Store(numGC())
+}
+
+// 统计快照
+type Stats struct {
+	Name     string
+	Gets     int64
+	Puts     int64
+	News     int64
+	Hits     int64
+	Lost     int64
+	GCCycles uint32 // 统计期间发生的 GC 次数
+	HitRatio float64
+}
+
+func (p *Pool[T]) Stats() Stats {
+	s := Stats{
+		Name:     p.name,
+		Gets:     p.gets.Load(),
+		Puts:     p.puts.Load(),
+		News:     p.news.Load(),
+		Lost:     p.lost.Load(),
+		GCCycles: numGC() - p.baseGC.Load(),
+	}
+	s.Hits = s.Gets - s.News
+	s.HitRatio = hitRatio(s.Hits, s.Gets)
+	return s
+}
+
+func hitRatio(hits, gets int64) float64 {
+	if gets == 0 {
+		return 0
+	}
+	return float64(hits) / float64(gets)
+}
+
+// numGC 读取已完成的 GC 轮数，和 runtime.MemStats.NumGC 是同一个计数。
+// runtime.ReadMemStats 会 STW，一次要 2µs 左右；runtime/metrics 不需要 STW，一次 100ns 左右，
+// 每次 Put 都要读，所以用后者。Sample 切片会逃逸到堆上，放在池里复用。
+func numGC() uint32 {
+	sample := samples.Get().(*[1]metrics.Sample)
+	metrics.Read(sample[:])
+	n := uint32(sample[0].Value.Uint64())
+	samples.Put(sample)
+	return n
+}
+
+var samples = sync.Pool{New: func() any {
+	return &[1]metrics.Sample{{Name: "/gc/cycles/total:gc-cycles"}}
+}}
+
+type statser interface {
+	Name() string
+	Stats() Stats
+}
+
+type poolRegistry struct {
+	mu    sync.Mutex
+	pools []statser
+}
+
+var registry poolRegistry
+
+func (r *poolRegistry) add(p statser) {
+	r.mu.Lock()
+	r.pools = append(r.pools, p)
+	r.mu.Unlock()
+}
+
+func (r *poolRegistry) remove(p statser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, q := range r.pools {
+		if q == p {
+			r.pools = append(r.pools[:i], r.pools[i+1:]...)
+			return
+		}
+	}
+}
+
+// Report 按池名汇总所有已注册（还没有 Close）的池的统计，同名的池合并计算
+func Report() []Stats {
+	registry.mu.Lock()
+	pools := append([]statser(nil), registry.pools...)
+	registry.mu.Unlock()
+
+	byName := make(map[string]*Stats)
+	for _, p := range pools {
+		s := p.Stats()
+		agg, ok := byName[s.Name]
+		if !ok {
+			byName[s.Name] = &s
+			continue
+		}
+		agg.Gets += s.Gets
+		agg.Puts += s.Puts
+		agg.News += s.News
+		agg.Hits += s.Hits
+		agg.Lost += s.Lost
+		if s.GCCycles > agg.GCCycles {
+			agg.GCCycles = s.GCCycles
+		}
+	}
+
+	out := make([]Stats, 0, len(byName))
+	for _, s := range byName {
+		s.HitRatio = hitRatio(s.Hits, s.Gets)
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// WriteReport 以表格形式输出 Report 的结果
+func WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "%-20s %10s %10s %10s %10s %8s %8s\n", "pool", "gets", "puts", "news", "lost", "gc", "hit%")
+	for _, s := range Report() {
+		fmt.Fprintf(w, "%-20s %10d %10d %10d %10d %8d %7.2f%%\n",
+			s.Name, s.Gets, s.Puts, s.News, s.Lost, s.GCCycles, s.HitRatio*100)
+	}
+}
@@ -0,0 +1,205 @@
+package poolstats
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+/*
+shell:
+	cd poolstats
+	go test -v .
+	go test -bench=. -run=^$ -benchmem
+*/
+
+func newBuffer() *bytes.Buffer {
+	return bytes.NewBuffer(make([]byte, 0, 1024))
+}
+
+// newPool 创建池，测试结束时从 Report 中移除，-count=N 重复运行时不会和上一轮的同名池合并
+func newPool(tb testing.TB, name string) *Pool[*bytes.Buffer] {
+	p := New(name, newBuffer)
+	tb.Cleanup(p.Close)
+	return p
+}
+
+func TestCounters(t *testing.T) {
+	p := newPool(t, "counters")
+
+	buf := p.Get() // 空池，未命中
+	p.Put(buf)
+	for i := 0; i < 100; i++ {
+		b := p.Get()
+		b.Reset()
+		p.Put(b)
+	}
+
+	s := p.Stats()
+	if s.Gets != 101 || s.Puts != 101 {
+		t.Fatalf("gets=%d puts=%d", s.Gets, s.Puts)
+	}
+	if s.News < 1 || s.Hits != s.Gets-s.News {
+		t.Fatalf("news=%d hits=%d", s.News, s.Hits)
+	}
+	// 开启 -race 时 sync.Pool 会随机丢弃 1/4 的 Put，命中率不会是 100%
+	if s.HitRatio < 0.5 {
+		t.Fatalf("hit ratio %.2f", s.HitRatio)
+	}
+}
+
+// 放回的对象经过两轮 GC（local -> victim -> 清除）后全部丢失
+func TestLostAcrossGC(t *testing.T) {
+	p := newPool(t, "gc")
+	for i := 0; i < 10; i++ {
+		p.Put(newBuffer())
+	}
+	runtime.GC()
+	runtime.GC()
+
+	p.Get()
+	s := p.Stats()
+	if s.News != 1 || s.Lost != 10 {
+		t.Fatalf("news=%d lost=%d, want 1 and 10", s.News, s.Lost)
+	}
+	if s.GCCycles < 2 {
+		t.Fatalf("gc cycles = %d, want >= 2", s.GCCycles)
+	}
+}
+
+// 没有发生 GC 的未命中不算丢失：这里用两个放回后立即被取走的对象模拟池里“应该还有”但取不到的情况
+func TestMissWithoutGCIsNotLost(t *testing.T) {
+	p := newPool(t, "no-gc")
+	p.Put(newBuffer())
+	p.pool.Get() // 绕过统计直接取走，相当于被别的 Get 拿走了
+	p.Get()
+	if s := p.Stats(); s.News != 1 || s.Lost != 0 {
+		t.Fatalf("news=%d lost=%d, want 1 and 0", s.News, s.Lost)
+	}
+}
+
+// Reset 之前放回的对象不会在 Reset 之后被算作丢失
+func TestResetClearsPooled(t *testing.T) {
+	p := newPool(t, "reset")
+	for i := 0; i < 10; i++ {
+		p.Put(newBuffer())
+	}
+	runtime.GC()
+	runtime.GC()
+	p.Reset()
+
+	p.Get()
+	p.Put(newBuffer())
+	p.Get()
+	s := p.Stats()
+	if s.Lost != 0 || s.Gets != 2 || s.Puts != 1 {
+		t.Fatalf("stats after Reset = %+v", s)
+	}
+}
+
+func TestReportAggregatesByName(t *testing.T) {
+	a := newPool(t, "report-shared")
+	b := newPool(t, "report-shared")
+	a.Get()
+	b.Get()
+	b.Get()
+
+	var found bool
+	for _, s := range Report() {
+		if s.Name != "report-shared" {
+			continue
+		}
+		found = true
+		if s.Gets != 3 || s.News != 3 || s.HitRatio != 0 {
+			t.Fatalf("aggregated stats = %+v", s)
+		}
+	}
+	if !found {
+		t.Fatal("pool missing from report")
+	}
+
+	var out strings.Builder
+	WriteReport(&out)
+	if !strings.Contains(out.String(), "report-shared") {
+		t.Fatalf("report:\n%s", out.String())
+	}
+
+	// Close 之后不再出现在 Report 里
+	a.Close()
+	b.Close()
+	for _, s := range Report() {
+		if s.Name == "report-shared" {
+			t.Fatalf("closed pools still reported: %+v", s)
+		}
+	}
+}
+
+// 用于验证 RequireHitRatio 的失败分支
+type fakeTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.failed = true
+	f.msg = fmt.Sprintf(format, args...)
+}
+
+func TestRequireHitRatio(t *testing.T) {
+	// 只 Get 不 Put，命中率为 0
+	p := newPool(t, "leaky")
+	for i := 0; i < 10; i++ {
+		p.Get()
+	}
+	tb := &fakeTB{TB: t}
+	RequireHitRatio(tb, p, 0.5)
+	if !tb.failed || !strings.Contains(tb.msg, "leaky") {
+		t.Fatalf("failed=%v msg=%q", tb.failed, tb.msg)
+	}
+
+	// 没有 Get 时不判定
+	tb = &fakeTB{TB: t}
+	RequireHitRatio(tb, newPool(t, "idle"), 0.5)
+	if tb.failed {
+		t.Fatal("idle pool should not fail")
+	}
+}
+
+/*
+命中率作为自定义指标输出。每次 Put 都要读一次 NumGC（runtime/metrics），比直接用 sync.Pool 慢 100 多 ns
+
+BenchmarkPooledBuffer                    6772424               211.7 ns/op               1.000 hit-ratio               0 B/op          0 allocs/op
+BenchmarkPooledBufferParallel            5927059               244.2 ns/op               1.000 hit-ratio               0 B/op          0 allocs/op
+*/
+func BenchmarkPooledBuffer(b *testing.B) {
+	p := newPool(b, "bench-buffer")
+	p.Put(newBuffer()) // 预热，避免 b.N=1 时唯一一次 Get 未命中
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := p.Get()
+		buf.Reset()
+		buf.WriteString("hello sync pool!")
+		p.Put(buf)
+	}
+	RequireHitRatio(b, p, 0.9)
+}
+
+func BenchmarkPooledBufferParallel(b *testing.B) {
+	p := newPool(b, "bench-buffer-parallel")
+	p.Put(newBuffer())
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			buf := p.Get()
+			buf.Reset()
+			buf.WriteString("hello sync pool!")
+			p.Put(buf)
+		}
+	})
+	RequireHitRatio(b, p, 0.9)
+}
@@ -0,0 +1,26 @@
+package poolstats
+
+import "testing"
+
+// RequireHitRatio 在命中率低于 min 时让测试/基准测试失败，
+// 对基准测试还会把命中率作为自定义指标输出（hit-ratio 列）。
+//
+//	func BenchmarkX(b *testing.B) {
+//		p := poolstats.New("buf", newBuf)
+//		for i := 0; i < b.N; i++ { ... }
+//		poolstats.RequireHitRatio(b, p, 0.9)
+//	}
+func RequireHitRatio(tb testing.TB, p interface{ Stats() Stats }, min float64) {
+	tb.Helper()
+	s := p.Stats()
+	if b, ok := tb.(*testing.B); ok {
+		b.ReportMetric(s.HitRatio, "hit-ratio")
+	}
+	if s.Gets == 0 {
+		return
+	}
+	if s.HitRatio < min {
+		tb.Fatalf("pool %q hit ratio %.3f below %.3f (gets=%d news=%d lost=%d gc=%d)",
+			s.Name, s.HitRatio, min, s.Gets, s.News, s.Lost, s.GCCycles)
+	}
+}
@@ -13,6 +13,7 @@ import (
 */
 func main() {
 	f1()
+	f2()
 }
 
 func worker(id int, wg *sync.WaitGroup, tasks <-chan int) {
@@ -58,18 +59,48 @@ func (t *Task) Reset() {
 	t.id = -1 // 标记可复用
 }
 
-func worker1(id int, taskPool *sync.Pool, wg *sync.WaitGroup) {
+// 方法2：在工作池的基础上复用 Task 对象。
+//
+// 原来的 worker1 循环执行 taskPool.Get().(*Task)，用 id < 0 表示“停止”。
+// 但 sync.Pool 为空时 New 会返回新对象，池里有没有对象、取到的是哪一个都不确定，
+// 循环什么时候结束无从保证（worker1 也从未被调用过）。
+// 正确的分工是：任务通过通道分发，关闭通道表示结束；空闲链表只负责回收 Task 对象。
+func worker1(id int, wg *sync.WaitGroup, tasks <-chan *Task, free chan<- *Task) {
 	defer wg.Done()
 
-	for {
-		task := taskPool.Get().(*Task)
-		if task == nil || task.id < 0 {
-			break
-		}
+	for task := range tasks {
 		fmt.Println("worker[", id, "] started deal task[", task.id, "]")
 		time.Sleep(time.Millisecond * 10)
 		fmt.Println("worker[", id, "] finished task[", task.id, "]")
 		task.Reset()
-		taskPool.Put(task)
+		free <- task // 处理完归还，供下一个任务复用
+	}
+}
+
+func f2() {
+	const workers = 10
+	var wg sync.WaitGroup
+	tasks := make(chan *Task, workers)
+
+	// 带缓冲的通道作为空闲链表：容量即 Task 对象的总数，
+	// 所有对象都在使用中时，发送方会阻塞等待归还，天然起到限流作用
+	free := make(chan *Task, workers*2)
+	for i := 0; i < cap(free); i++ {
+		free <- &Task{}
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker1(i, &wg, tasks, free)
 	}
+
+	for i := 0; i < 100; i++ {
+		task := <-free
+		task.id = i
+		tasks <- task
+	}
+	close(tasks) // 关闭通道，worker 处理完剩余任务后退出
+
+	wg.Wait()
+	fmt.Println("All tasks completed, task objects allocated:", cap(free))
 }
@@ -0,0 +1,82 @@
+// 任务对象复用与 demo_02.go f1 模式（每个任务新分配）的对比
+
+package taskpool
+
+import (
+	"sync"
+	"testing"
+)
+
+/*
+shell:
+	go test -bench=. -run=^$ -benchmem
+*/
+
+/*
+每个 op 分发 100 个任务给 10 个 worker
+
+BenchmarkNewPerTask                23355             51373 ns/op          106048 B/op        213 allocs/op
+BenchmarkRecycled                  35090             37797 ns/op           13768 B/op         50 allocs/op
+BenchmarkRecycledLongRunning     4712278               254.1 ns/op             0 B/op          0 allocs/op
+
+1. NewPerTask 就是 f1 的工作池，只是通道里传的是 *Task：每个任务都分配一个 Task 和 1KB 缓冲区。
+2. Recycled 每个 op 都新建 Pipeline，分配只发生在启动阶段（最多 workers*2+1 个 Task 以及通道、goroutine），
+   100 个任务之后全部复用，内存分配减少约 87%。
+3. RecycledLongRunning 中 Pipeline 常驻，单个任务的分发和处理不再分配内存。
+*/
+
+const (
+	benchWorkers = 10
+	benchTasks   = 100
+)
+
+func handle(task *Task) {
+	task.Buf = append(task.Buf, "payload"...)
+}
+
+func BenchmarkNewPerTask(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		taskChan := make(chan *Task, benchWorkers)
+		for w := 0; w < benchWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for task := range taskChan {
+					handle(task)
+				}
+			}()
+		}
+		for j := 0; j < benchTasks; j++ {
+			task := newTask()
+			task.ID = j
+			taskChan <- task
+		}
+		close(taskChan)
+		wg.Wait()
+	}
+}
+
+func BenchmarkRecycled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := NewPipeline(benchWorkers, newTask, func(_ int, task *Task) { handle(task) })
+		for j := 0; j < benchTasks; j++ {
+			p.Submit(func(task *Task) { task.ID = j })
+		}
+		p.Close()
+	}
+}
+
+// 长期运行的 Pipeline：启动成本摊薄后，每个任务 0 分配
+func BenchmarkRecycledLongRunning(b *testing.B) {
+	p := NewPipeline(benchWorkers, newTask, func(_ int, task *Task) { handle(task) })
+	fill := func(task *Task) { task.ID = 1 }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Submit(fill)
+	}
+	p.Close()
+}
@@ -0,0 +1,3 @@
+module taskpool
+
+go 1.21
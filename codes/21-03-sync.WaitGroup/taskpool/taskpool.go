@@ -0,0 +1,99 @@
+// 可复用任务对象的工作池
+//
+// demo_02.go 中 f1 的工作池每个任务只传一个 int；真实任务往往带着缓冲区等较大的字段，
+// 每个任务都 new 一次会给 GC 带来压力。这里把任务对象的生命周期拆成三步：
+//
+//	FreeList.Get   从空闲链表取出（为空才分配）
+//	Pipeline.Submit 填充后通过通道分发给 worker
+//	worker 处理完后 Reset 并放回空闲链表
+//
+// 结束由关闭任务通道表示，而不是依赖对象里的哨兵值。
+package taskpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Resetter 由任务类型实现，放回空闲链表前清理状态
+type Resetter interface {
+	Reset()
+}
+
+// FreeList 是固定容量的类型化空闲链表，底层是带缓冲的通道。
+// 与 sync.Pool 不同，放进去的对象不会被 GC 清掉，取出的一定是之前放回的对象。
+type FreeList[T Resetter] struct {
+	free   chan T
+	newFn  func() T
+	allocs atomic.Int64
+}
+
+// NewFreeList 创建容量为 size 的空闲链表，newFn 在链表为空时分配新对象，
+// 超过容量时放回的对象直接丢弃
+func NewFreeList[T Resetter](size int, newFn func() T) *FreeList[T] {
+	return &FreeList[T]{free: make(chan T, size), newFn: newFn}
+}
+
+// Get 取出一个对象，链表为空时分配新对象
+func (l *FreeList[T]) Get() T {
+	select {
+	case t := <-l.free:
+		return t
+	default:
+		l.allocs.Add(1)
+		return l.newFn()
+	}
+}
+
+// Put 清理对象并放回，链表已满时丢弃
+func (l *FreeList[T]) Put(t T) {
+	t.Reset()
+	select {
+	case l.free <- t:
+	default:
+	}
+}
+
+// Allocs 返回累计分配的对象数
+func (l *FreeList[T]) Allocs() int64 { return l.allocs.Load() }
+
+// Pipeline 固定数量的 worker 从通道接收任务，处理完后把任务对象还给空闲链表
+type Pipeline[T Resetter] struct {
+	Free  *FreeList[T]
+	tasks chan T
+	wg    sync.WaitGroup
+}
+
+// NewPipeline 启动 workers 个 worker，handle 在 worker 中执行，返回后任务对象即被回收，
+// handle 不能在返回后继续持有任务对象
+func NewPipeline[T Resetter](workers int, newFn func() T, handle func(worker int, task T)) *Pipeline[T] {
+	p := &Pipeline[T]{
+		// 空闲链表能容纳所有在途对象：通道中 workers 个 + 正在处理的 workers 个 + 提交方手里的 1 个
+		Free:  NewFreeList(workers*2+1, newFn),
+		tasks: make(chan T, workers),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func(id int) {
+			defer p.wg.Done()
+			for t := range p.tasks {
+				handle(id, t)
+				p.Free.Put(t)
+			}
+		}(i)
+	}
+	return p
+}
+
+// Submit 从空闲链表取出任务对象，由 fill 填充后分发给 worker
+func (p *Pipeline[T]) Submit(fill func(task T)) {
+	t := p.Free.Get()
+	fill(t)
+	p.tasks <- t
+}
+
+// Close 关闭任务通道并等待所有 worker 处理完剩余任务，之后不能再 Submit
+func (p *Pipeline[T]) Close() {
+	close(p.tasks)
+	p.wg.Wait()
+}
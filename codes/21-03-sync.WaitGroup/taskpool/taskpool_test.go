@@ -0,0 +1,98 @@
+package taskpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+/*
+shell:
+	cd taskpool
+	go test -v -race .
+	go test -bench=. -run=^$ -benchmem
+*/
+
+// 带 1KB 缓冲区的任务，模拟真实任务中较大的字段
+type Task struct {
+	ID  int
+	Buf []byte
+}
+
+func newTask() *Task { return &Task{Buf: make([]byte, 0, 1024)} }
+
+func (t *Task) Reset() {
+	t.ID = -1
+	t.Buf = t.Buf[:0]
+}
+
+func TestPipelineProcessesEveryTask(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[int]int)
+	)
+	p := NewPipeline(4, newTask, func(worker int, task *Task) {
+		mu.Lock()
+		seen[task.ID]++
+		mu.Unlock()
+	})
+	for i := 0; i < 1000; i++ {
+		p.Submit(func(task *Task) { task.ID = i })
+	}
+	p.Close()
+
+	if len(seen) != 1000 {
+		t.Fatalf("processed %d distinct tasks, want 1000", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Fatalf("task %d processed %d times", id, n)
+		}
+	}
+	// 在途对象最多 workers*2+1 个，之后全部复用
+	if allocs := p.Free.Allocs(); allocs > 9 {
+		t.Fatalf("allocated %d task objects, want <= 9", allocs)
+	}
+}
+
+// 任务对象在被处理时不能同时出现在别的 worker 手里
+func TestTaskNotSharedWhileInUse(t *testing.T) {
+	var inUse sync.Map
+	var dup atomic.Bool
+	p := NewPipeline(8, newTask, func(worker int, task *Task) {
+		if _, loaded := inUse.LoadOrStore(task, worker); loaded {
+			dup.Store(true)
+		}
+		task.Buf = append(task.Buf, "payload"...)
+		inUse.Delete(task)
+	})
+	for i := 0; i < 5000; i++ {
+		p.Submit(func(task *Task) {
+			if len(task.Buf) != 0 || task.ID != 0 && task.ID != -1 {
+				t.Errorf("task not reset: %+v", task)
+			}
+			task.ID = i
+		})
+	}
+	p.Close()
+	if dup.Load() {
+		t.Fatal("task object handed to two workers at once")
+	}
+}
+
+func TestFreeListDropsWhenFull(t *testing.T) {
+	l := NewFreeList(1, newTask)
+	a, b := l.Get(), l.Get()
+	l.Put(a)
+	l.Put(b) // 已满，丢弃
+	if got := l.Get(); got != a {
+		t.Fatal("expected the first returned task")
+	}
+	if l.Allocs() != 2 {
+		t.Fatalf("allocs = %d", l.Allocs())
+	}
+	l.Get()
+	if l.Allocs() != 3 {
+		t.Fatalf("allocs = %d after draining", l.Allocs())
+	}
+}
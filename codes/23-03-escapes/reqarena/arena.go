@@ -0,0 +1,86 @@
+// 请求级 arena：按块批量分配短生命周期的小对象，请求结束时 Reset 一次性释放
+//
+// 逃逸分析（知识点/23-性能优化/03-逃逸分析.md）告诉我们：返回指针的对象会逃逸到堆上，
+// 请求处理中 new 出来的大量小结构体都要由 GC 扫描、回收。
+// sync.Pool 一次只能复用一个对象；arena 则把对象放在预先分配的 slab（[]T）里：
+//
+//	Alloc 只是在当前 slab 中移动下标，不产生堆分配
+//	Reset 清零已用部分并把下标拨回 0，slab 留给下一个请求继续使用
+//
+// 泛型代码实例化后才做逃逸分析，需要通过测试查看：
+//
+//	go test -gcflags="-m -l" -run=^$ . 2>&1 | grep arena.go
+//	./arena.go:53:35: make([]go.shape.struct {...}, a.chunkSize) escapes to heap // 只有新 slab 分配在堆上
+//	./arena.go:53:20: append escapes to heap
+//
+// Alloc 返回的是 slab 内元素的地址，本身不产生新的堆对象。
+package reqarena
+
+// Arena 为类型 T 提供批量分配，不是并发安全的，一个请求一个 Arena
+type Arena[T any] struct {
+	chunks    [][]T
+	chunk     int // 当前使用的 slab
+	next      int // 当前 slab 中下一个空闲位置
+	chunkSize int
+}
+
+// New 创建每个 slab 容纳 chunkSize 个对象的 arena
+func New[T any](chunkSize int) *Arena[T] {
+	if chunkSize <= 0 {
+		chunkSize = 64
+	}
+	return &Arena[T]{chunkSize: chunkSize}
+}
+
+// Alloc 返回一个零值对象的指针。指针在下一次 Reset 之前有效，
+// Reset 之后该地址会被复用，不能再继续持有
+func (a *Arena[T]) Alloc() *T {
+	if a.chunk < len(a.chunks) && a.next < a.chunkSize {
+		p := &a.chunks[a.chunk][a.next]
+		a.next++
+		return p
+	}
+	return a.allocSlow()
+}
+
+// allocSlow 切换到下一个 slab，Reset 后优先复用已有的 slab，都用完才分配新的
+func (a *Arena[T]) allocSlow() *T {
+	if a.next >= a.chunkSize {
+		a.chunk++
+		a.next = 0
+	}
+	if a.chunk == len(a.chunks) {
+		a.chunks = append(a.chunks, make([]T, a.chunkSize))
+	}
+	p := &a.chunks[a.chunk][a.next]
+	a.next++
+	return p
+}
+
+// Reset 释放本轮分配的所有对象。
+// 已用部分会被清零：既保证下次 Alloc 拿到零值，也去掉对象里的指针，避免 slab 让它们引用的内存无法回收
+func (a *Arena[T]) Reset() {
+	for i := 0; i < a.chunk && i < len(a.chunks); i++ {
+		clear(a.chunks[i])
+	}
+	if a.chunk < len(a.chunks) {
+		clear(a.chunks[a.chunk][:a.next])
+	}
+	a.chunk, a.next = 0, 0
+}
+
+// Len 返回本轮已分配的对象数
+func (a *Arena[T]) Len() int {
+	return a.chunk*a.chunkSize + a.next
+}
+
+// Cap 返回已持有的 slab 总容量
+func (a *Arena[T]) Cap() int {
+	return len(a.chunks) * a.chunkSize
+}
+
+// Release 丢弃所有 slab，用在一次异常大的请求之后，避免 arena 长期占着大块内存
+func (a *Arena[T]) Release() {
+	a.chunks = nil
+	a.chunk, a.next = 0, 0
+}
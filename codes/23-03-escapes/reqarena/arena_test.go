@@ -0,0 +1,112 @@
+package reqarena
+
+import (
+	"strings"
+	"testing"
+)
+
+/*
+shell:
+	cd reqarena
+	go test -v .
+	go test -bench=. -run=^$ -benchmem
+	# 查看实例化后的逃逸情况
+	go test -gcflags="-m -l" -run=^$ . 2>&1 | grep arena.go
+*/
+
+// 请求中解析出的 key=value 字段，用链表串起来
+type field struct {
+	key, value string
+	next       *field
+}
+
+// parseQuery 把 "a=1&b=2" 解析成链表，字段都从 arena 中分配
+func parseQuery(q string, a *Arena[field]) *field {
+	var head, tail *field
+	for q != "" {
+		var kv string
+		kv, q, _ = strings.Cut(q, "&")
+		k, v, _ := strings.Cut(kv, "=")
+		f := a.Alloc()
+		f.key, f.value = k, v
+		if tail == nil {
+			head = f
+		} else {
+			tail.next = f
+		}
+		tail = f
+	}
+	return head
+}
+
+func TestAllocAcrossChunks(t *testing.T) {
+	a := New[int](4)
+	ptrs := make([]*int, 10)
+	for i := range ptrs {
+		ptrs[i] = a.Alloc()
+		if *ptrs[i] != 0 {
+			t.Fatalf("alloc %d not zeroed", i)
+		}
+		*ptrs[i] = i
+	}
+	for i, p := range ptrs {
+		if *p != i {
+			t.Fatalf("ptr %d = %d, objects overlap", i, *p)
+		}
+	}
+	if a.Len() != 10 || a.Cap() != 12 {
+		t.Fatalf("len=%d cap=%d, want 10 and 12", a.Len(), a.Cap())
+	}
+}
+
+func TestResetReusesAndZeroes(t *testing.T) {
+	a := New[field](4)
+	head := parseQuery("a=1&b=2&c=3&d=4&e=5", a)
+	if head.key != "a" || head.next.next.next.next.value != "5" {
+		t.Fatalf("unexpected parse result")
+	}
+	first := head
+
+	a.Reset()
+	if a.Len() != 0 || a.Cap() != 8 {
+		t.Fatalf("after reset len=%d cap=%d", a.Len(), a.Cap())
+	}
+	// Reset 后复用同一块内存，且已经被清零（next 指针也断开了）
+	f := a.Alloc()
+	if f != first {
+		t.Fatal("Reset did not reuse the first slot")
+	}
+	if *f != (field{}) {
+		t.Fatalf("reused slot not zeroed: %+v", *f)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	a := New[int](2)
+	for i := 0; i < 5; i++ {
+		a.Alloc()
+	}
+	a.Release()
+	if a.Len() != 0 || a.Cap() != 0 {
+		t.Fatalf("len=%d cap=%d after release", a.Len(), a.Cap())
+	}
+	*a.Alloc() = 1
+}
+
+// slab 分配好之后，每个请求的 Alloc + Reset 不应产生任何堆分配
+func TestSteadyStateNoAllocs(t *testing.T) {
+	a := New[field](64)
+	const q = "user=alice&id=42&lang=go&page=1&size=20&sort=desc"
+	parseQuery(q, a)
+	a.Reset()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if parseQuery(q, a) == nil {
+			t.Fatal("empty parse")
+		}
+		a.Reset()
+	})
+	if allocs != 0 {
+		t.Fatalf("allocs per request = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,3 @@
+module reqarena
+
+go 1.21